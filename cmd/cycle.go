@@ -6,98 +6,268 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/gauthierbraillon/ensemble/internal/agent"
+	"github.com/gauthierbraillon/ensemble/internal/config"
+	"github.com/gauthierbraillon/ensemble/internal/diff"
+	"github.com/gauthierbraillon/ensemble/internal/git"
 	"github.com/gauthierbraillon/ensemble/internal/runner"
+	"github.com/gauthierbraillon/ensemble/internal/sarif"
+	"github.com/gauthierbraillon/ensemble/internal/state"
 )
 
 var cycleCmd = &cobra.Command{
-	Use:   "cycle",
+	Use:   "cycle [<rev> | <rev>..<rev> | <rev>...<rev>]",
 	Short: "Enforce RED→GREEN→REFACTOR→DEPLOY on a diff",
-	Long: `Reads a unified diff from stdin and runs TDD, software engineering, security, and UX/design agents against it.
+	Long: `Runs TDD, software engineering, security, and UX/design agents against a change.
 
-Each finding prints as one JSON line. Exits 1 if any verdict is "block".`,
-	Example: `  git diff HEAD~1 | ensemble cycle
-  git diff HEAD   | ensemble cycle
-  ensemble cycle  < my.patch`,
+The change comes from git when given a revision range (A..B; A...B diffs from
+their merge base; a single revision is compared with HEAD), --base (the
+branch's changes since it forked from base), --staged (the index) or
+--worktree (uncommitted changes, untracked files included). Model agents then
+also see the full post-change contents of the changed files.
+
+Without any of these, a unified diff is read from stdin. Any unified diff
+works: git diff (including renames, binary files and --no-prefix) or plain
+diff -u output. A malformed hunk header is an error.
+
+--deterministic runs only the agents that need no model (testing-quality),
+fast and offline enough for a pre-commit hook.
+
+Agents run concurrently, at most --concurrency at a time. Findings print in a
+fixed agent order, one JSON line each, or as a SARIF 2.1.0 log with
+--format sarif. Exits 1 if any finding meets the block threshold (by default,
+any "block" verdict). The blocking findings are recorded in .ensemble/ so the
+prompt hook can remind the model of them.
+
+Agents, models, timeouts, the block threshold and file globs come from
+.ensemble.yaml at the repository root when present.
+
+Model agents run on ENSEMBLE_TIER (opus, sonnet or haiku; default sonnet).
+Override a single agent with ENSEMBLE_TIER_<AGENT>, e.g. ENSEMBLE_TIER_SECURITY=opus.
+Each model finding records the model that produced it.`,
+	Example: `  ensemble cycle --base main
+  ensemble cycle HEAD~3..HEAD
+  ensemble cycle --staged
+  ensemble cycle --staged --deterministic
+  ensemble cycle --worktree
+  git diff HEAD~1 | ensemble cycle
+  ensemble cycle  < my.patch
+  git diff HEAD~1 | ensemble cycle --concurrency 1
+  git diff HEAD~1 | ensemble cycle --format sarif > ensemble.sarif`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: runCycle,
 }
 
-func runCycle(_ *cobra.Command, _ []string) error {
-	diff, err := io.ReadAll(os.Stdin)
+var (
+	cycleConcurrency   int
+	cycleFormat        string
+	cycleBase          string
+	cycleStaged        bool
+	cycleWorktree      bool
+	cycleDeterministic bool
+)
+
+func runCycle(cmd *cobra.Command, args []string) error {
+	if cycleFormat != "json" && cycleFormat != "sarif" {
+		return fmt.Errorf("--format must be json or sarif, got %q", cycleFormat)
+	}
+	cfg, err := config.Load(".")
+	if err != nil {
+		return err
+	}
+	if cmd.Flags().Changed("concurrency") {
+		cfg.Runner.Concurrency = cycleConcurrency
+	}
+	if cfg.Runner.Concurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1, got %d", cfg.Runner.Concurrency)
+	}
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	change, err := cycleChange(ctx, args)
+	if err != nil {
+		return err
+	}
+	reviews, err := cycleReviews(cfg, change, !cycleDeterministic)
 	if err != nil {
 		return err
 	}
-	var findings []agent.Finding
-	findings = append(findings, agent.ReviewDiff(string(diff))...)
-	findings = append(findings, agent.ReviewCode(context.Background(), string(diff), sweRunner())...)
-	findings = append(findings, agent.ReviewSecurity(context.Background(), string(diff), securityRunner())...)
-	findings = append(findings, agent.ReviewUX(context.Background(), string(diff), uxRunner())...)
-	blocked := false
+	findings := agent.RunReviews(ctx, reviews, cfg.Runner.Concurrency)
+	if err := writeFindings(os.Stdout, cycleFormat, findings); err != nil {
+		return err
+	}
+	var blocking []agent.Finding
 	for _, f := range findings {
-		line, _ := json.Marshal(f)
-		fmt.Println(string(line))
-		if f.Verdict == agent.Block {
-			blocked = true
+		if cfg.Fails(f) {
+			blocking = append(blocking, f)
 		}
 	}
-	if blocked {
+	if ctx.Err() != nil {
+		return fmt.Errorf("cycle interrupted: %w", ctx.Err())
+	}
+	if err := state.SaveCycle(cfg.Root, state.Cycle{Time: time.Now().UTC(), Blocking: blocking}); err != nil {
+		fmt.Fprintln(os.Stderr, "WARNING: could not record the cycle:", err)
+	}
+	if len(blocking) > 0 {
 		os.Exit(1)
 	}
 	return nil
 }
 
-func sweRunner() runner.Runner {
-	if os.Getenv("ANTHROPIC_API_KEY") == "" {
-		return nil
+func writeFindings(w io.Writer, format string, findings []agent.Finding) error {
+	if format == "sarif" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(sarif.FromFindings(findings, Version))
 	}
-	r, err := runner.New(runner.Config{
-		Binary:   "claude",
-		Model:    "claude-haiku-4-5-20251001",
-		Timeout:  30 * time.Second,
-		MaxBytes: 32 * 1024,
-	})
+	enc := json.NewEncoder(w)
+	for _, f := range findings {
+		if err := enc.Encode(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cycleChange asks git for the change when a revision or mode is given and
+// reads a diff from stdin otherwise.
+func cycleChange(ctx context.Context, args []string) (agent.Change, error) {
+	modes := len(args)
+	for _, set := range []bool{cycleBase != "", cycleStaged, cycleWorktree} {
+		if set {
+			modes++
+		}
+	}
+	if modes > 1 {
+		return agent.Change{}, fmt.Errorf("choose one of a revision range, --base, --staged or --worktree")
+	}
+	if modes == 0 {
+		raw, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return agent.Change{}, err
+		}
+		patch, err := diff.Parse(string(raw))
+		return agent.Change{Patch: patch}, err
+	}
+	repo, err := git.Open(ctx, ".")
 	if err != nil {
-		return nil
+		return agent.Change{}, err
 	}
-	return r
+	var raw string
+	var tree git.Tree
+	switch {
+	case cycleBase != "":
+		raw, tree, err = repo.Base(ctx, cycleBase)
+	case cycleStaged:
+		raw, tree, err = repo.Staged(ctx)
+	case cycleWorktree:
+		raw, tree, err = repo.Worktree(ctx)
+	default:
+		raw, tree, err = repo.Range(ctx, args[0])
+	}
+	if err != nil {
+		return agent.Change{}, err
+	}
+	patch, err := diff.Parse(raw)
+	return agent.Change{Patch: patch, Tree: tree}, err
 }
 
-func securityRunner() runner.Runner {
-	if os.Getenv("ANTHROPIC_API_KEY") == "" {
-		return nil
+type llmReview func(ctx context.Context, c agent.Change, r runner.Runner) []agent.Finding
+
+// cycleReviews builds one review per enabled agent, leaving out the agents
+// that need a model unless models is set. Files outside the configured paths
+// are dropped before any agent sees the patch.
+func cycleReviews(cfg config.Config, change agent.Change, models bool) ([]agent.Review, error) {
+	policy := cfg.Policy()
+	change.Patch = change.Patch.Filter(func(f diff.File) bool { return policy.Covers(f.Path()) })
+	var reviews []agent.Review
+	if cfg.Enabled("testing-quality") {
+		reviews = append(reviews, agent.Review{Name: "testing-quality", Run: func(context.Context) []agent.Finding {
+			return agent.ReviewDiff(change, policy)
+		}})
 	}
-	r, err := runner.New(runner.Config{
-		Binary:   "claude",
-		Model:    "claude-haiku-4-5-20251001",
-		Timeout:  30 * time.Second,
-		MaxBytes: 32 * 1024,
-	})
+	for _, a := range []struct {
+		name   string
+		review llmReview
+	}{
+		{"software-engineering", agent.ReviewCode},
+		{"security", agent.ReviewSecurity},
+		{"ux-design", agent.ReviewUX},
+	} {
+		if !models || !cfg.Enabled(a.name) {
+			continue
+		}
+		r, err := modelReview(cfg, a.name, change, a.review)
+		if err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, r)
+	}
+	return reviews, nil
+}
+
+func modelReview(cfg config.Config, name string, change agent.Change, review llmReview) (agent.Review, error) {
+	model, err := cfg.Model(name, os.Getenv)
+	if err != nil {
+		return agent.Review{}, err
+	}
+	r, err := reviewRunner(cfg.Runner, model)
 	if err != nil {
-		return nil
+		return agent.Review{}, err
 	}
-	return r
+	if r == nil {
+		model = ""
+	}
+	return agent.Review{Name: name, Model: model, Run: func(ctx context.Context) []agent.Finding {
+		return review(ctx, change, r)
+	}}, nil
 }
 
-func uxRunner() runner.Runner {
-	if os.Getenv("ANTHROPIC_API_KEY") == "" {
-		return nil
+// reviewRunner returns the runner for model, nil when ANTHROPIC_API_KEY is
+// not set. A runner that is set up wrong is an error, not a skipped review.
+func reviewRunner(rc config.Runner, model string) (runner.Runner, error) {
+	backend, err := rc.SelectBackend(os.Getenv)
+	if err != nil {
+		return nil, err
+	}
+	apiKey := os.Getenv("ANTHROPIC_API_KEY")
+	if apiKey == "" {
+		return nil, nil
 	}
-	r, err := runner.New(runner.Config{
-		Binary:   "claude",
-		Model:    "claude-haiku-4-5-20251001",
-		Timeout:  30 * time.Second,
-		MaxBytes: 32 * 1024,
+	r, err := runner.FromConfig(runner.Config{
+		Backend:  backend,
+		Binary:   rc.Binary,
+		Model:    model,
+		Timeout:  rc.Timeout,
+		MaxBytes: rc.MaxBytes,
+		BaseURL:  envOr("ANTHROPIC_BASE_URL", rc.BaseURL),
+		APIKey:   apiKey,
 	})
 	if err != nil {
-		return nil
+		return nil, fmt.Errorf("runner: %w", err)
+	}
+	return r, nil
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
 	}
-	return r
+	return fallback
 }
 
 func init() {
+	cycleCmd.Flags().StringVar(&cycleFormat, "format", "json", "output format: json (one finding per line) or sarif")
+	cycleCmd.Flags().StringVar(&cycleBase, "base", "", "review the current branch's changes since it forked from this revision")
+	cycleCmd.Flags().BoolVar(&cycleStaged, "staged", false, "review the changes staged in the index")
+	cycleCmd.Flags().BoolVar(&cycleWorktree, "worktree", false, "review uncommitted changes, untracked files included")
+	cycleCmd.Flags().BoolVar(&cycleDeterministic, "deterministic", false, "run only the agents that need no model, as the pre-commit hook does")
+	cycleCmd.Flags().IntVar(&cycleConcurrency, "concurrency", 4, "maximum number of agents reviewing at once")
 	rootCmd.AddCommand(cycleCmd)
 }
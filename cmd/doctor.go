@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/gauthierbraillon/ensemble/internal/doctor"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose the ensemble installation",
+	Long: `Checks that ensemble can do its job in this project: .ensemble.yaml parses,
+the hooks are registered once across the project, local and user settings,
+the ensemble on PATH is this version, the runner for model reviews resolves,
+ANTHROPIC_API_KEY is set (a failure only for the api runner; otherwise the
+model reviews are skipped without it), and the tools each quality gate's make
+target runs are installed.
+
+Prints a table, or one JSON object per check with --format json. Exits 1 when
+any check fails.`,
+	Example: `  ensemble doctor
+  ensemble doctor --format json | jq 'select(.status == "fail")'`,
+	RunE: runDoctor,
+}
+
+var doctorFormat string
+
+func runDoctor(cmd *cobra.Command, _ []string) error {
+	if doctorFormat != "table" && doctorFormat != "json" {
+		return fmt.Errorf("--format must be table or json, got %q", doctorFormat)
+	}
+	env := doctor.Env{Dir: ".", Version: Version}
+	if home, err := os.UserHomeDir(); err == nil {
+		env.Home = home
+	}
+	checks := doctor.Run(cmd.Context(), env)
+	if err := writeChecks(os.Stdout, doctorFormat, checks); err != nil {
+		return err
+	}
+	if doctor.Failed(checks) {
+		os.Exit(1)
+	}
+	return nil
+}
+
+func writeChecks(w io.Writer, format string, checks []doctor.Check) error {
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		for _, c := range checks {
+			if err := enc.Encode(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHECK\tSTATUS\tDETAIL")
+	for _, c := range checks {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", c.Name, c.Status, c.Detail)
+	}
+	return tw.Flush()
+}
+
+func init() {
+	doctorCmd.Flags().StringVar(&doctorFormat, "format", "table", "output format: table or json (one check per line)")
+	rootCmd.AddCommand(doctorCmd)
+}
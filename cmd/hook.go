@@ -1,53 +1,324 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"slices"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/gauthierbraillon/ensemble/internal/agent"
+	"github.com/gauthierbraillon/ensemble/internal/config"
+	"github.com/gauthierbraillon/ensemble/internal/diff"
+	"github.com/gauthierbraillon/ensemble/internal/git"
+	"github.com/gauthierbraillon/ensemble/internal/hook"
+	"github.com/gauthierbraillon/ensemble/internal/pipeline"
+	"github.com/gauthierbraillon/ensemble/internal/state"
+	"github.com/gauthierbraillon/ensemble/internal/workflow"
 )
 
-type hookEvent struct {
-	ToolName  string    `json:"tool_name"`
-	ToolInput toolInput `json:"tool_input"`
-}
-
-type toolInput struct {
-	FilePath string `json:"file_path"`
-}
-
 var hookCmd = &cobra.Command{
 	Use:   "hook",
-	Short: "Claude Code PreToolUse hook — blocks writes that violate TDD",
-	Long: `Called automatically by Claude Code before every Write or Edit. Not for manual use.
+	Short: "Claude Code hooks — block writes that violate TDD, run tests after edits",
+	Long: `Called automatically by Claude Code before every Write, Edit, MultiEdit,
+NotebookEdit or Bash call. Not for manual use. Bash commands are parsed for the
+files they write: redirections, here-documents, tee, sed -i, cp and mv.
+
+Wire it up with ensemble init, which registers it in .claude/settings.json.
+The hook answers with a structured permission decision on stdout: writing
+foo.go without foo_test.go on disk is denied, with the fix handed back to the
+model as the reason; warnings ask the user; passes print nothing.
+TypeScript, Python and Rust files follow their own test conventions
+(foo.test.ts, test_foo.py, an inline #[cfg(test)] module, ...).
+
+The proposed content is checked too: hard-coded secrets and disabled tests
+are denied; deleted assertions and new lint suppressions ask first.
+
+With tests.verify_red set in .ensemble.yaml, writing Go implementation also
+runs the tests added or edited since HEAD, before the write lands, and
+denies it unless one of them fails.
+
+With --phase post the hook answers PostToolUse instead: after each edit it
+runs the tests of the edited Go package, within tests.budget (30s by
+default), and hands any failure back to the model to fix before moving on.
 
-Wire it up by copying .claude/settings.json into your project's .claude/ directory.
-Writing foo.go without foo_test.go on disk exits 2 and hard-blocks Claude Code.`,
+Hooks follow each Claude Code session (by session_id) through the TDD loop in
+.ensemble/tdd/: writing implementation moves RED to GREEN, passing tests move
+GREEN to REFACTOR, and a new test starts over in RED. During REFACTOR,
+implementation may only change while the tests are green.
+
+With --phase stop the hook answers Stop: before the model ends its turn, the
+uncommitted changes get the same review as cycle --worktree (TDD plus the
+model agents) and the changed packages' tests run. While any finding meets
+the block threshold, the model is sent back to work with the findings as the
+reason. Once it has been sent back (stop_hook_active), only the checks that
+need no model can send it back again, so findings that vary from one model
+run to the next cannot keep it working forever.
+
+With --phase prompt the hook answers UserPromptSubmit: every prompt reaches
+the model with the working agreement attached, namely the TDD phase read off
+the uncommitted changes, the blocking findings of the last cycle, and the
+quality gates every commit must pass.
+
+With --exit-code the hook instead prints the finding as JSON and exits 2 on a
+block, for Claude Code versions that predate structured decisions.`,
 	RunE: runHook,
 }
 
-func runHook(_ *cobra.Command, _ []string) error {
+var (
+	hookExitCode bool
+	hookPhase    string
+)
+
+func runHook(cmd *cobra.Command, _ []string) error {
 	raw, err := io.ReadAll(os.Stdin)
 	if err != nil {
 		return err
 	}
-	var event hookEvent
+	var event hook.Event
 	if err := json.Unmarshal(raw, &event); err != nil {
 		return err
 	}
-	finding := agent.CheckFileWrite(event.ToolInput.FilePath)
-	out, _ := json.Marshal(finding)
-	fmt.Println(string(out))
-	if finding.Verdict == agent.Block {
-		os.Exit(2)
+	cfg, err := config.Load(".")
+	if err != nil {
+		return err
+	}
+	var finding agent.Finding
+	switch hookPhase {
+	case "pre":
+		finding = checkWrites(cmd.Context(), event, cfg)
+	case "post":
+		finding = runTests(cmd.Context(), event, cfg)
+	case "stop":
+		return runStop(cmd.Context(), event, cfg)
+	case "prompt":
+		return runPrompt(cmd.Context(), event, cfg)
+	default:
+		return fmt.Errorf("unknown phase %q (want pre, post, stop or prompt)", hookPhase)
+	}
+	if hookExitCode {
+		out, _ := json.Marshal(finding)
+		fmt.Println(string(out))
+		if finding.Verdict == agent.Block {
+			os.Exit(2)
+		}
+		return nil
+	}
+	decision, ok := hook.Decide(finding)
+	if hookPhase == "post" {
+		decision, ok = hook.Feedback(hook.PostToolUse, finding)
+	}
+	if !ok {
+		return nil
+	}
+	return json.NewEncoder(os.Stdout).Encode(decision)
+}
+
+// checkWrites judges every file the tool call writes, with the checks of the
+// agents that are enabled, and returns the most severe finding.
+func checkWrites(ctx context.Context, event hook.Event, cfg config.Config) agent.Finding {
+	p := cfg.Policy()
+	session, tracked := loadSession(cfg, event)
+	worst := agent.Finding{Agent: "testing-quality", Verdict: agent.Pass, Severity: agent.Low, Finding: "no file written"}
+	first := true
+	for _, w := range event.Writes() {
+		var findings []agent.Finding
+		if cfg.Enabled("testing-quality") {
+			f := agent.CheckFileWrite(w.Path, w.Content, p)
+			if f.Verdict == agent.Pass && p.VerifyRed {
+				f = verifyRed(ctx, w.Path, p)
+			}
+			findings = append(findings, f)
+			if tracked {
+				findings = append(findings, session.Check(w.Path, p))
+			}
+		}
+		for _, f := range agent.CheckContent(w.Path, w.Replaced, w.Content, p) {
+			if cfg.Enabled(f.Agent) {
+				findings = append(findings, f)
+			}
+		}
+		for _, f := range findings {
+			if first || worse(f, worst) {
+				worst, first = f, false
+			}
+		}
+	}
+	return worst
+}
+
+// runTests runs the tests of the Go packages the tool call wrote to, within
+// tests.budget, so a red bar reaches the model before its next step.
+func runTests(ctx context.Context, event hook.Event, cfg config.Config) agent.Finding {
+	if !cfg.Enabled("testing-quality") {
+		return agent.Finding{Agent: "testing-quality", Verdict: agent.Pass, Severity: agent.Low, Finding: "testing-quality disabled"}
+	}
+	p := cfg.Policy()
+	var paths []string
+	for _, w := range event.Writes() {
+		paths = append(paths, w.Path)
+	}
+	dirs := agent.GoPackages(paths, p)
+	session, tracked := loadSession(cfg, event)
+	if tracked {
+		for _, path := range paths {
+			session.Wrote(path, p)
+		}
+		// Re-run the package that failed last, so undoing a breaking step
+		// (which writes nothing the hook can see) turns the session green.
+		if session.Failing != "" && !slices.Contains(dirs, session.Failing) {
+			if info, err := os.Stat(session.Failing); err == nil && info.IsDir() {
+				dirs = append(dirs, session.Failing)
+			}
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, cfg.Tests.Budget)
+	defer cancel()
+	f := agent.VerifyGreen(ctx, dirs)
+	if tracked {
+		switch f.Category {
+		case "tests-pass":
+			session.Ran(true, "")
+		case "failing-test":
+			session.Ran(false, f.File)
+		}
+		if err := session.Save(cfg.Root); err != nil {
+			fmt.Fprintln(os.Stderr, "WARNING: could not record the session:", err)
+		}
+	}
+	return f
+}
+
+// loadSession returns the TDD state of the event's session, or false when the
+// payload names none or its state cannot be read.
+func loadSession(cfg config.Config, event hook.Event) (workflow.Session, bool) {
+	s, ok, err := workflow.LoadSession(cfg.Root, event.SessionID)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "WARNING: could not read the session:", err)
+		return workflow.Session{}, false
+	}
+	return s, ok
+}
+
+// runStop reviews the uncommitted changes as cycle --worktree would, runs the
+// tests of the Go packages they touch, and keeps the model working while any
+// finding meets the block threshold. A model already sent back is reviewed
+// by the deterministic checks alone: TDD and the tests.
+func runStop(ctx context.Context, event hook.Event, cfg config.Config) error {
+	findings, err := reviewWorktree(ctx, cfg, !event.StopHookActive)
+	if err != nil {
+		return err
+	}
+	var failing []agent.Finding
+	for _, f := range findings {
+		if cfg.Fails(f) {
+			failing = append(failing, f)
+		}
+	}
+	if hookExitCode {
+		enc := json.NewEncoder(os.Stdout)
+		for _, f := range failing {
+			if err := enc.Encode(f); err != nil {
+				return err
+			}
+		}
+		if len(failing) > 0 {
+			os.Exit(2)
+		}
+		return nil
+	}
+	out, ok := hook.Continue(failing)
+	if !ok {
+		return nil
+	}
+	return json.NewEncoder(os.Stdout).Encode(out)
+}
+
+// runPrompt restates the working agreement with every prompt: the session's
+// TDD phase (read off the uncommitted changes until the hooks have tracked
+// it), the last cycle's blocking findings, and the quality gates.
+func runPrompt(ctx context.Context, event hook.Event, cfg config.Config) error {
+	phase := workflow.Red
+	if session, ok := loadSession(cfg, event); ok && !session.Updated.IsZero() {
+		phase = session.Phase
+	} else if repo, err := git.Open(ctx, "."); err == nil {
+		if raw, _, err := repo.Worktree(ctx); err == nil {
+			if patch, err := diff.Parse(raw); err == nil {
+				phase = workflow.Infer(patch, cfg.Policy())
+			}
+		}
+	}
+	var last *state.Cycle
+	if c, err := state.LoadCycle(cfg.Root); err == nil {
+		last = &c
+	}
+	text := workflow.Agreement(phase, last, pipeline.Gates())
+	if hookExitCode {
+		fmt.Print(text)
+		return nil
+	}
+	return json.NewEncoder(os.Stdout).Encode(hook.Inject(hook.UserPromptSubmit, text))
+}
+
+// reviewWorktree runs the enabled agents on the uncommitted changes, the
+// model agents only if models is set, plus the changed packages' tests.
+// Outside a git repository there is nothing to review.
+func reviewWorktree(ctx context.Context, cfg config.Config, models bool) ([]agent.Finding, error) {
+	repo, err := git.Open(ctx, ".")
+	if err != nil {
+		return nil, nil
+	}
+	raw, tree, err := repo.Worktree(ctx)
+	if err != nil {
+		return nil, err
+	}
+	patch, err := diff.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if len(patch.Files) == 0 {
+		return nil, nil
+	}
+	reviews, err := cycleReviews(cfg, agent.Change{Patch: patch, Tree: tree}, models)
+	if err != nil {
+		return nil, err
+	}
+	findings := agent.RunReviews(ctx, reviews, cfg.Runner.Concurrency)
+	if cfg.Enabled("testing-quality") {
+		var paths []string
+		for _, f := range patch.Files {
+			paths = append(paths, filepath.Join(repo.Root, filepath.FromSlash(f.Path())))
+		}
+		testCtx, cancel := context.WithTimeout(ctx, cfg.Tests.Budget)
+		defer cancel()
+		findings = append(findings, agent.VerifyGreen(testCtx, agent.GoPackages(paths, cfg.Policy())))
+	}
+	return findings, nil
+}
+
+// redTimeout keeps the RED check inside Claude Code's default hook timeout.
+const redTimeout = 45 * time.Second
+
+func verifyRed(ctx context.Context, path string, p agent.Policy) agent.Finding {
+	ctx, cancel := context.WithTimeout(ctx, redTimeout)
+	defer cancel()
+	return agent.VerifyRed(ctx, path, p)
+}
+
+func worse(a, b agent.Finding) bool {
+	if a.Verdict != b.Verdict {
+		return a.Verdict.AtLeast(b.Verdict)
 	}
-	return nil
+	return a.Severity != b.Severity && a.Severity.AtLeast(b.Severity)
 }
 
 func init() {
+	hookCmd.Flags().StringVar(&hookPhase, "phase", "pre", "hook event to answer: pre (PreToolUse), post (PostToolUse), stop (Stop) or prompt (UserPromptSubmit)")
+	hookCmd.Flags().BoolVar(&hookExitCode, "exit-code", false, "print the finding and exit 2 on a block instead of a structured decision")
 	rootCmd.AddCommand(hookCmd)
 }
@@ -1,43 +1,168 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 
+	"github.com/gauthierbraillon/ensemble/internal/git"
 	"github.com/gauthierbraillon/ensemble/internal/initcmd"
 )
 
 var initCmd = &cobra.Command{
-	Use:     "init",
-	Short:   "Set up ensemble hook in .claude/settings.json",
-	Long:    `Creates or updates .claude/settings.json to add the ensemble PreToolUse hook.`,
-	Example: `  ensemble init`,
-	RunE:    runInit,
+	Use:   "init",
+	Short: "Set up ensemble hooks in Claude Code's settings",
+	Long: `Creates or updates .claude/settings.json to add the ensemble hooks: PreToolUse
+checks every write, PostToolUse runs the edited package's tests, Stop reviews
+the uncommitted changes before the model ends its turn, and UserPromptSubmit
+restates the working agreement with every prompt.
+
+--scope picks the settings file: project (.claude/settings.json, committed
+and shared), local (.claude/settings.local.json, to opt in without
+committing) or user (~/.claude/settings.json, every project on the machine).
+Claude Code runs the hooks of every scope, so a hook already registered in
+another scope is left out rather than registered twice; one registered there
+by an older version, for fewer tools, is pointed out so that init --scope
+can upgrade it where it is.
+
+--git-hooks also installs git hooks in the repository's hooks directory
+(core.hooksPath when set): pre-commit runs ensemble cycle --staged
+--deterministic, and pre-push runs the full cycle on each pushed range. A
+hook already there is kept as <name>.pre-ensemble and runs first.
+
+Only what changes is rewritten: the rest of the file keeps its key order and
+formatting, and a settings.json that is not valid JSON is left untouched with
+an error pointing at the problem. --dry-run prints the change as a diff
+instead of writing it.
+
+With --remove it takes the hooks out again, as ensemble uninstall does.`,
+	Example: `  ensemble init
+  ensemble init --scope local
+  ensemble init --git-hooks
+  ensemble init --dry-run
+  ensemble init --remove`,
+	RunE: runInit,
 }
 
-func runInit(_ *cobra.Command, _ []string) error {
-	dir, err := os.Getwd()
+var (
+	initRemove   bool
+	initDryRun   bool
+	initScope    string
+	initGitHooks bool
+)
+
+func runInit(cmd *cobra.Command, args []string) error {
+	if initRemove {
+		return runUninstall(cmd, args)
+	}
+	scope, locations, err := settingsScope()
 	if err != nil {
 		return err
 	}
-	changed, err := initcmd.WriteSettings(dir)
+	change, elsewhere, err := initcmd.PlanInstall(locations, scope)
 	if err != nil {
 		return err
 	}
-	if changed {
-		fmt.Println("Initialised .claude/settings.json — ensemble hook active.")
+	for _, r := range elsewhere {
+		fmt.Printf("%s hook already registered in %s — left out.\n", r.Hook.Event, r.Scope.File())
+		if r.Hook.Narrow() {
+			fmt.Printf("  It fires only for %s; run ensemble init --scope %s to upgrade it.\n", r.Hook.Matcher, r.Scope)
+		}
+	}
+	if initDryRun {
+		printDryRun(change)
+		if initGitHooks {
+			return gitHooks(cmd.Context(), false)
+		}
+		return nil
+	}
+	if err := change.Apply(); err != nil {
+		return err
+	}
+	if !change.Empty() {
+		fmt.Printf("Initialised %s — ensemble hook active.\n", scope.File())
 		fmt.Println("Run: git diff HEAD~1 | ensemble cycle")
 	} else {
 		fmt.Println("Already configured — nothing changed.")
 	}
+	if initGitHooks {
+		if err := gitHooks(cmd.Context(), false); err != nil {
+			return err
+		}
+	}
 	if !initcmd.EnsembleOnPath() {
 		fmt.Fprintln(os.Stderr, "WARNING: ensemble not found on PATH — hook will not fire until it is installed.")
 	}
 	return nil
 }
 
+// settingsScope resolves --scope to the scope and where its files live.
+func settingsScope() (initcmd.Scope, initcmd.Locations, error) {
+	scope, err := initcmd.ParseScope(initScope)
+	if err != nil {
+		return "", initcmd.Locations{}, err
+	}
+	locations := initcmd.Locations{Project: "."}
+	if home, err := os.UserHomeDir(); err == nil {
+		locations.Home = home
+	} else if scope == initcmd.User {
+		return "", initcmd.Locations{}, fmt.Errorf("user scope: %w", err)
+	}
+	return scope, locations, nil
+}
+
+// gitHooks installs ensemble's git hooks in the current repository, or
+// removes them, honouring --dry-run.
+func gitHooks(ctx context.Context, remove bool) error {
+	repo, err := git.Open(ctx, ".")
+	if err != nil {
+		return fmt.Errorf("--git-hooks: %w", err)
+	}
+	dir, err := repo.HooksDir(ctx)
+	if err != nil {
+		return err
+	}
+	plan, verb := initcmd.PlanGitHooks, "Installed"
+	if remove {
+		plan, verb = initcmd.PlanRemoveGitHooks, "Removed"
+	}
+	changes, err := plan(dir)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		fmt.Println("Git hooks already as wanted — nothing changed.")
+		return nil
+	}
+	for _, c := range changes {
+		if initDryRun {
+			fmt.Printf("Would have %s %s.\n", strings.ToLower(verb), c)
+			continue
+		}
+		if err := c.Apply(); err != nil {
+			return err
+		}
+		fmt.Printf("%s %s.\n", verb, c)
+	}
+	return nil
+}
+
+// printDryRun shows the change a command would make.
+func printDryRun(change initcmd.Change) {
+	if change.Empty() {
+		fmt.Println("Nothing to change.")
+		return
+	}
+	fmt.Print(change.Diff())
+}
+
 func init() {
+	initCmd.Flags().BoolVar(&initRemove, "remove", false, "remove the ensemble hooks instead of adding them")
+	initCmd.Flags().BoolVar(&initDryRun, "dry-run", false, "print the change to settings.json instead of writing it")
+	initCmd.Flags().StringVar(&initScope, "scope", "project", "settings to write: project, local or user")
+	initCmd.Flags().BoolVar(&initGitHooks, "git-hooks", false, "also install git pre-commit and pre-push hooks that run ensemble cycle")
 	rootCmd.AddCommand(initCmd)
 }
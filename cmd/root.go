@@ -1,11 +1,17 @@
 package cmd
 
 import (
-	"bufio"
+	"context"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
+
+	"github.com/gauthierbraillon/ensemble/internal/agent"
+	"github.com/gauthierbraillon/ensemble/internal/config"
+	"github.com/gauthierbraillon/ensemble/internal/orchestrator"
+	"github.com/gauthierbraillon/ensemble/internal/runner"
 )
 
 var rootCmd = &cobra.Command{
@@ -13,21 +19,85 @@ var rootCmd = &cobra.Command{
 	Short: "AI agent team — quality-enforced TDD mob programming",
 	Long: `ensemble runs an AI agent team that enforces XP/CD discipline on every code change.
 
-You direct. Agents enforce. Quality gates are non-negotiable.`,
+You direct. Agents enforce. Quality gates are non-negotiable.
+
+Run without a command, it opens an interactive session with the orchestrator.
+Its replies stream as they are written; each change it proposes is reviewed
+by the same agents as ensemble cycle before it is applied. Warnings are
+shown, and a blocked change is applied only if you answer yes. Ctrl-C
+abandons a reply; Ctrl-D ends the session.
+
+Lines starting with / are commands to the session: /help lists them, and Tab
+completes them at a terminal.
+
+Each session is recorded to .ensemble/sessions/<id>.jsonl; --resume <id>
+carries one on with its conversation, outstanding findings, model and
+agents, and ensemble sessions list shows them.
+
+The orchestrator runs on ENSEMBLE_TIER_ORCHESTRATOR, then agents.orchestrator
+in .ensemble.yaml (tier or model), then ENSEMBLE_TIER or tier.`,
+	Example: `  ensemble
+  ensemble --resume 20261018-091500-3fa2`,
 	RunE: runSession,
 }
 
-func runSession(_ *cobra.Command, _ []string) error {
-	scanner := bufio.NewScanner(os.Stdin)
-	fmt.Print("ensemble> ")
-	for scanner.Scan() {
-		fmt.Print("ensemble> ")
+var resumeSession string
+
+// The orchestrator writes whole files, so it gets more time and room than a
+// review.
+const (
+	sessionTimeout  = 5 * time.Minute
+	sessionMaxBytes = 1 << 20
+)
+
+func runSession(cmd *cobra.Command, _ []string) error {
+	cfg, err := config.Load(".")
+	if err != nil {
+		return err
+	}
+	model, err := cfg.Model(config.Orchestrator, os.Getenv)
+	if err != nil {
+		return err
+	}
+	rc := cfg.Runner
+	rc.Timeout = max(rc.Timeout, sessionTimeout)
+	rc.MaxBytes = max(rc.MaxBytes, sessionMaxBytes)
+
+	s := orchestrator.New(os.Stdin, os.Stdout)
+	s.Config = cfg
+	s.Model = model
+	s.Connect = func(model string) (runner.Runner, error) { return reviewRunner(rc, model) }
+	if s.Runner, err = s.Connect(model); err != nil {
+		return err
+	}
+	s.Review = func(ctx context.Context, cfg config.Config, change agent.Change) ([]agent.Finding, error) {
+		reviews, err := cycleReviews(cfg, change, true)
+		if err != nil {
+			return nil, err
+		}
+		return agent.RunReviews(ctx, reviews, cfg.Runner.Concurrency), nil
+	}
+	if resumeSession == "" {
+		if s.Transcript, err = orchestrator.NewTranscript(cfg.Root, time.Now()); err != nil {
+			return err
+		}
+		return s.Run(cmd.Context())
+	}
+	t, entries, err := orchestrator.OpenTranscript(cfg.Root, resumeSession)
+	if err != nil {
+		return err
+	}
+	s.Transcript = t
+	if err := s.Resume(entries); err != nil {
+		return err
 	}
-	return nil
+	fmt.Fprintf(os.Stdout, "Resuming session %s on %s: %d turn(s), %d outstanding finding(s).\n", t.ID, s.Model, len(s.History), len(s.Findings))
+	return s.Run(cmd.Context())
 }
 
 func init() {
 	rootCmd.CompletionOptions.DisableDefaultCmd = true
+	rootCmd.Flags().StringVar(&resumeSession, "resume", "", "carry on the recorded session with this ID (see ensemble sessions list)")
 }
 
 func Execute() {
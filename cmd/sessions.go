@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/gauthierbraillon/ensemble/internal/config"
+	"github.com/gauthierbraillon/ensemble/internal/orchestrator"
+)
+
+var sessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "Inspect the recorded interactive sessions",
+	Long: `Every interactive session is recorded to .ensemble/sessions/<id>.jsonl: the
+prompts, the orchestrator's replies, the review findings and what the user
+decided about them, including the reason given for each override.
+
+ensemble --resume <id> carries a session on.`,
+}
+
+var sessionsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the recorded sessions, latest first",
+	Long: `Lists the sessions recorded in this project, latest first, with how many
+prompts each had and how many blocking findings were overridden in it.
+
+Prints a table, or one JSON object per session with --format json.`,
+	Example: `  ensemble sessions list
+  ensemble sessions list --format json | jq 'select(.overrides > 0) | .id'`,
+	Args: cobra.NoArgs,
+	RunE: runSessionsList,
+}
+
+var sessionsShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show what happened in a recorded session",
+	Long: `Shows a recorded session entry by entry: prompts, replies, findings, and
+each decision with its reason.
+
+Prints the transcript for reading, or its JSON lines as recorded with
+--format json.`,
+	Example: `  ensemble sessions show 20261018-091500-3fa2
+  ensemble sessions show 20261018-091500-3fa2 --format json | jq 'select(.kind == "decision")'`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSessionsShow,
+}
+
+var sessionsFormat string
+
+func sessionsRoot() (string, error) {
+	if sessionsFormat != "table" && sessionsFormat != "json" {
+		return "", fmt.Errorf("--format must be table or json, got %q", sessionsFormat)
+	}
+	cfg, err := config.Load(".")
+	if err != nil {
+		return "", err
+	}
+	return cfg.Root, nil
+}
+
+func runSessionsList(_ *cobra.Command, _ []string) error {
+	root, err := sessionsRoot()
+	if err != nil {
+		return err
+	}
+	summaries, err := orchestrator.Transcripts(root)
+	if err != nil {
+		return err
+	}
+	return writeSessions(os.Stdout, sessionsFormat, summaries)
+}
+
+// firstPromptWidth is how much of a session's first prompt the table shows.
+const firstPromptWidth = 60
+
+func writeSessions(w io.Writer, format string, summaries []orchestrator.Summary) error {
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		for _, s := range summaries {
+			if err := enc.Encode(s); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if len(summaries) == 0 {
+		_, err := fmt.Fprintln(w, "No sessions recorded yet.")
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSTARTED\tPROMPTS\tOVERRIDES\tFIRST PROMPT")
+	for _, s := range summaries {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%s\n", s.ID, s.Started.Local().Format(time.DateTime), s.Prompts, s.Overrides, truncate(s.First, firstPromptWidth))
+	}
+	return tw.Flush()
+}
+
+func runSessionsShow(_ *cobra.Command, args []string) error {
+	root, err := sessionsRoot()
+	if err != nil {
+		return err
+	}
+	_, entries, err := orchestrator.OpenTranscript(root, args[0])
+	if err != nil {
+		return err
+	}
+	return writeTranscript(os.Stdout, sessionsFormat, entries)
+}
+
+func writeTranscript(w io.Writer, format string, entries []orchestrator.Entry) error {
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		for _, e := range entries {
+			if err := enc.Encode(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s %s", e.Time.Local().Format(time.TimeOnly), e.Kind)
+		switch e.Kind {
+		case orchestrator.KindReview:
+			if len(e.Findings) == 0 {
+				fmt.Fprint(w, ": no findings")
+			}
+			fmt.Fprintln(w)
+			for _, o := range e.Findings {
+				fmt.Fprintln(w, o)
+			}
+		case orchestrator.KindDecision:
+			switch {
+			case e.Finding != 0:
+				fmt.Fprintf(w, ": %s #%d because: %s\n", e.Decision, e.Finding, e.Text)
+			default:
+				fmt.Fprintf(w, ": %s %s\n", e.Decision, strings.Join(e.Files, ", "))
+			}
+		case orchestrator.Orchestrator:
+			fmt.Fprintf(w, " (%s): %s\n", e.Model, e.Text)
+		case orchestrator.KindModel:
+			fmt.Fprintf(w, ": %s\n", e.Model)
+		case orchestrator.KindAgent:
+			state := "disabled"
+			if e.Enabled != nil && *e.Enabled {
+				state = "enabled"
+			}
+			fmt.Fprintf(w, ": %s %s\n", e.Agent, state)
+		default:
+			fmt.Fprintf(w, ": %s\n", e.Text)
+		}
+	}
+	return nil
+}
+
+// truncate shortens s to n runes on one line.
+func truncate(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
+
+func init() {
+	sessionsCmd.PersistentFlags().StringVar(&sessionsFormat, "format", "table", "output format: table or json (one object per line)")
+	sessionsCmd.AddCommand(sessionsListCmd, sessionsShowCmd)
+	rootCmd.AddCommand(sessionsCmd)
+}
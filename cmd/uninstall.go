@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/gauthierbraillon/ensemble/internal/initcmd"
+)
+
+var uninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Remove the ensemble hooks from Claude Code's settings",
+	Long: `Removes every ensemble hook from .claude/settings.json, or from the settings
+--scope names (project, local or user), whatever the phase or the version
+that registered it, and lists what it removed. Other hooks and settings are
+kept in their order; entries, events and a hooks map left empty are removed.
+Same as ensemble init --remove; --dry-run prints the change as a diff instead
+of writing it. --git-hooks also removes the git hooks init --git-hooks
+installed, restoring any hook they chained.`,
+	Example: `  ensemble uninstall
+  ensemble uninstall --scope user
+  ensemble uninstall --dry-run`,
+	RunE: runUninstall,
+}
+
+func runUninstall(cmd *cobra.Command, _ []string) error {
+	scope, locations, err := settingsScope()
+	if err != nil {
+		return err
+	}
+	change, removed, err := initcmd.PlanRemove(locations, scope)
+	if err != nil {
+		return err
+	}
+	if initDryRun {
+		printDryRun(change)
+	} else if err := change.Apply(); err != nil {
+		return err
+	} else if len(removed) == 0 {
+		fmt.Println("No ensemble hook configured — nothing changed.")
+	} else {
+		fmt.Printf("Removed from %s:\n", scope.File())
+		for _, h := range removed {
+			fmt.Printf("  %s: %s\n", h.Event, h.Command)
+		}
+	}
+	if initGitHooks {
+		return gitHooks(cmd.Context(), true)
+	}
+	return nil
+}
+
+func init() {
+	uninstallCmd.Flags().BoolVar(&initDryRun, "dry-run", false, "print the change to settings.json instead of writing it")
+	uninstallCmd.Flags().BoolVar(&initGitHooks, "git-hooks", false, "also remove the git hooks init --git-hooks installed")
+	uninstallCmd.Flags().StringVar(&initScope, "scope", "project", "settings to remove the hooks from: project, local or user")
+	rootCmd.AddCommand(uninstallCmd)
+}
@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/gauthierbraillon/ensemble/internal/diff"
+)
+
+// maxFileContext caps the post-change file contents added to a prompt, so
+// a large change cannot crowd the diff out of the model's context.
+const maxFileContext = 64 * 1024
+
+// Tree reads files, by repository-relative path, as they stand after a change.
+type Tree interface {
+	ReadFile(path string) ([]byte, error)
+}
+
+// Change is what a cycle reviews: a patch and, when known, the tree it
+// produces. Tree is nil when only the patch is available, e.g. on stdin.
+type Change struct {
+	Patch diff.Patch
+	Tree  Tree
+}
+
+// Files renders the full post-change contents of the added and modified
+// text files, in patch order, until maxFileContext is reached. Files that
+// cannot be read or would exceed the cap are listed as omitted.
+func (c Change) Files() string {
+	if c.Tree == nil {
+		return ""
+	}
+	var b strings.Builder
+	var omitted []string
+	for _, f := range c.Patch.Files {
+		if f.Status == diff.Deleted || f.Binary {
+			continue
+		}
+		content, err := c.Tree.ReadFile(f.Path())
+		if err != nil || bytes.IndexByte(content, 0) >= 0 || b.Len()+len(content) > maxFileContext {
+			omitted = append(omitted, f.Path())
+			continue
+		}
+		fmt.Fprintf(&b, "=== %s ===\n%s", f.Path(), content)
+		if len(content) > 0 && content[len(content)-1] != '\n' {
+			b.WriteByte('\n')
+		}
+	}
+	if len(omitted) > 0 {
+		fmt.Fprintf(&b, "(omitted: %s)\n", strings.Join(omitted, ", "))
+	}
+	return b.String()
+}
+
+// reviewInput is the tail every model prompt shares: the diff, then the
+// changed files in full so reviewers see context beyond the hunks.
+func reviewInput(c Change) string {
+	input := "Diff:\n" + c.Patch.String()
+	if files := c.Files(); files != "" {
+		input += "\nFull contents of the changed files after the change:\n" + files
+	}
+	return input
+}
@@ -0,0 +1,82 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gauthierbraillon/ensemble/internal/diff"
+)
+
+type mapTree map[string]string
+
+func (m mapTree) ReadFile(path string) ([]byte, error) {
+	content, ok := m[path]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return []byte(content), nil
+}
+
+type promptRecorder struct{ prompt *string }
+
+func (r promptRecorder) Run(_ context.Context, prompt string) (string, error) {
+	*r.prompt = prompt
+	return "[]", nil
+}
+
+func TestChangeFiles(t *testing.T) {
+	patch, err := diff.Parse(`diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -10 +10 @@
+-var x = 1
++var x = 2
+diff --git a/gone.go b/gone.go
+deleted file mode 100644
+--- a/gone.go
++++ /dev/null
+@@ -1 +0,0 @@
+-package gone
+diff --git a/missing.go b/missing.go
+--- /dev/null
++++ b/missing.go
+@@ -0,0 +1 @@
++package missing
+`)
+	require.NoError(t, err)
+
+	t.Run("renders post-change contents of changed files", func(t *testing.T) {
+		c := Change{Patch: patch, Tree: mapTree{"a.go": "package a\n\nfunc far() {}\n\nvar x = 2"}}
+		files := c.Files()
+		assert.Contains(t, files, "=== a.go ===\npackage a\n\nfunc far() {}\n\nvar x = 2\n")
+		assert.NotContains(t, files, "gone.go", "deleted files have no post-change content")
+		assert.Contains(t, files, "(omitted: missing.go)")
+	})
+
+	t.Run("is empty without a tree", func(t *testing.T) {
+		assert.Empty(t, Change{Patch: patch}.Files())
+	})
+
+	t.Run("omits files past the size cap", func(t *testing.T) {
+		c := Change{Patch: patch, Tree: mapTree{"a.go": strings.Repeat("x", maxFileContext+1), "missing.go": "package missing\n"}}
+		files := c.Files()
+		assert.NotContains(t, files, "=== a.go ===")
+		assert.Contains(t, files, "=== missing.go ===")
+		assert.Contains(t, files, "(omitted: a.go)")
+	})
+
+	t.Run("reaches the model prompt after the diff", func(t *testing.T) {
+		var prompt string
+		c := Change{Patch: patch, Tree: mapTree{"a.go": "package a\n\nfunc far() {}\n"}}
+		ReviewCode(context.Background(), c, promptRecorder{&prompt})
+		diffAt := strings.Index(prompt, "Diff:\n")
+		filesAt := strings.Index(prompt, "func far() {}")
+		require.NotEqual(t, -1, diffAt)
+		assert.Greater(t, filesAt, diffAt)
+	})
+}
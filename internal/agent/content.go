@@ -0,0 +1,123 @@
+package agent
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// secretPatterns match credentials that should never be committed. They are
+// deliberately narrow: a hook that cries wolf gets switched off.
+var secretPatterns = []struct {
+	kind string
+	re   *regexp.Regexp
+}{
+	{"private key", regexp.MustCompile(`-----BEGIN (?:RSA |EC |DSA |OPENSSH |PGP |ENCRYPTED )?PRIVATE KEY`)},
+	{"AWS access key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"GitHub token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{60,})\b`)},
+	{"Anthropic API key", regexp.MustCompile(`\bsk-ant-[A-Za-z0-9_-]{20,}`)},
+	{"OpenAI API key", regexp.MustCompile(`\bsk-(?:proj-)?[A-Za-z0-9]{32,}`)},
+	{"Slack token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+	{"Stripe secret key", regexp.MustCompile(`\b[sr]k_live_[A-Za-z0-9]{20,}`)},
+	{"credential", regexp.MustCompile(`(?i)\b[a-z_]*(?:password|passwd|secret|api_?key|access_?token|auth_?token)\s*(?::=|=|:)\s*["'][^"'\s$]{12,}["']`)},
+}
+
+// CheckContent runs fast, deterministic checks on the text a write puts
+// into filePath: hard-coded secrets anywhere, and in tests, disabled tests,
+// deleted assertions and new lint suppressions. before is the text the
+// write replaces, empty when it only adds; only what changes between the two
+// is judged, so a write never answers for code already on disk. Files the
+// policy's path globs leave out are not checked.
+func CheckContent(filePath, before, after string, p Policy) []Finding {
+	if !p.Covers(filePath) {
+		return nil
+	}
+	var findings []Finding
+	added := addedLines(before, after)
+	for _, s := range secretPatterns {
+		if s.re.MatchString(added) {
+			findings = append(findings, Finding{
+				Agent:    "security",
+				Verdict:  Block,
+				Severity: Critical,
+				Category: "hardcoded-secret",
+				Finding:  "possible " + s.kind + " written to " + filePath,
+				File:     filePath,
+				Fix:      "read it from the environment or a secret store instead",
+			})
+			break
+		}
+	}
+	conv, ok := p.Convention(filePath)
+	if !ok {
+		return findings
+	}
+	if marker, n := grown(before, after, conv.LintSuppressions); n > 0 {
+		findings = append(findings, Finding{
+			Agent:    "software-engineering",
+			Verdict:  Warn,
+			Severity: Medium,
+			Category: "lint-suppression",
+			Finding:  marker + " added to " + filePath,
+			File:     filePath,
+			Fix:      "fix what the linter reports instead of silencing it",
+		})
+	}
+	if !p.IsTest(filePath) && conv.InlineTestMarker == "" {
+		return findings
+	}
+	if marker, n := grown(before, after, conv.SkipMarkers); n > 0 {
+		findings = append(findings, Finding{
+			Agent:    "testing-quality",
+			Verdict:  Block,
+			Severity: High,
+			Category: "disabled-test",
+			Finding:  "test disabled with " + marker + " in " + filePath,
+			File:     filePath,
+			Fix:      "make the test pass instead of skipping it",
+		})
+	}
+	if marker, n := grown(after, before, conv.AssertionMarkers); n > 0 {
+		findings = append(findings, Finding{
+			Agent:    "testing-quality",
+			Verdict:  Warn,
+			Severity: Medium,
+			Category: "deleted-assertion",
+			Finding:  fmt.Sprintf("%d assertion(s) removed from %s, such as %s", n, filePath, marker),
+			File:     filePath,
+			Fix:      "keep the assertion, or replace it with one at least as strict",
+		})
+	}
+	return findings
+}
+
+// addedLines returns the lines of after that do not appear in before.
+func addedLines(before, after string) string {
+	old := map[string]bool{}
+	for _, line := range strings.Split(before, "\n") {
+		old[line] = true
+	}
+	var b strings.Builder
+	for _, line := range strings.Split(after, "\n") {
+		if !old[line] {
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+// grown reports the first marker that occurs more often in after than in
+// before, and by how many occurrences altogether.
+func grown(before, after string, markers []string) (string, int) {
+	first, total := "", 0
+	for _, m := range markers {
+		if n := strings.Count(after, m) - strings.Count(before, m); n > 0 {
+			if first == "" {
+				first = m
+			}
+			total += n
+		}
+	}
+	return strings.TrimSuffix(first, "("), total
+}
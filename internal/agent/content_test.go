@@ -0,0 +1,129 @@
+package agent_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gauthierbraillon/ensemble/internal/agent"
+)
+
+func categories(findings []agent.Finding) []string {
+	var out []string
+	for _, f := range findings {
+		out = append(out, f.Category)
+	}
+	return out
+}
+
+func TestCheckContentSecrets(t *testing.T) {
+	// Assembled at run time so the test file itself carries no secret.
+	awsKey := "AKIA" + "IOSFODNN7EXAMPLE"
+	cases := map[string]string{
+		"AWS access key": `const key = "` + awsKey + `"`,
+		"private key":    "-----BEGIN RSA " + "PRIVATE KEY-----\nMIIE...",
+		"credential":     `password := "hunter2hunter2hunter2"`,
+		"Anthropic key":  `ANTHROPIC_API_KEY=` + "sk-ant-" + "api03-abcdefghijklmnopqrstuv",
+	}
+	for name, content := range cases {
+		t.Run(name, func(t *testing.T) {
+			findings := agent.CheckContent("/p/config.go", "", content, agent.DefaultPolicy())
+			require.Len(t, findings, 1)
+			assert.Equal(t, "security", findings[0].Agent)
+			assert.Equal(t, agent.Block, findings[0].Verdict)
+			assert.Equal(t, "hardcoded-secret", findings[0].Category)
+			assert.NotContains(t, findings[0].Finding, content, "the secret is not echoed back")
+		})
+	}
+
+	t.Run("secrets in any file, not just code", func(t *testing.T) {
+		findings := agent.CheckContent("/p/.env", "", "AWS_ACCESS_KEY_ID="+awsKey, agent.DefaultPolicy())
+		assert.Equal(t, []string{"hardcoded-secret"}, categories(findings))
+	})
+
+	t.Run("reading from the environment is fine", func(t *testing.T) {
+		findings := agent.CheckContent("/p/config.go", "", `password := os.Getenv("DB_PASSWORD")`, agent.DefaultPolicy())
+		assert.Empty(t, findings)
+	})
+
+	t.Run("files outside the configured paths are not checked", func(t *testing.T) {
+		p := agent.Policy{Root: "/p", Exclude: []string{"testdata/**"}}
+		assert.Empty(t, agent.CheckContent("/p/testdata/fixture.go", "", `const key = "`+awsKey+`"`, p))
+		assert.Empty(t, agent.CheckContent("/p/testdata/fixture_test.go", "", "t.Skip(\"flaky\") //nolint", p))
+		assert.NotEmpty(t, agent.CheckContent("/p/config.go", "", `const key = "`+awsKey+`"`, p))
+	})
+
+	t.Run("a secret already on disk is not blamed on the edit", func(t *testing.T) {
+		line := `const key = "` + awsKey + `"`
+		findings := agent.CheckContent("/p/config.go", line+"\n", line+"\nconst x = 1\n", agent.DefaultPolicy())
+		assert.Empty(t, findings)
+	})
+}
+
+func TestCheckContentTests(t *testing.T) {
+	p := agent.DefaultPolicy()
+
+	t.Run("t.Skip added to a Go test blocks", func(t *testing.T) {
+		findings := agent.CheckContent("/p/foo_test.go",
+			"func TestFoo(t *testing.T) {\n\tassert.Equal(t, 1, Foo())\n}",
+			"func TestFoo(t *testing.T) {\n\tt.Skip(\"flaky\")\n\tassert.Equal(t, 1, Foo())\n}", p)
+		require.Len(t, findings, 1)
+		assert.Equal(t, agent.Block, findings[0].Verdict)
+		assert.Equal(t, "disabled-test", findings[0].Category)
+		assert.Contains(t, findings[0].Finding, "t.Skip")
+	})
+
+	t.Run("skips in other languages", func(t *testing.T) {
+		for path, content := range map[string]string{
+			"/p/app.test.ts":   `it.skip("works", () => {})`,
+			"/p/test_app.py":   "@pytest.mark.skip\ndef test_app(): pass",
+			"/p/src/lib.rs":    "#[cfg(test)]\nmod tests {\n    #[ignore]\n    #[test]\n    fn t() {}\n}",
+			"/p/tests/main.rs": "#[ignore]\n#[test]\nfn t() {}",
+		} {
+			assert.Contains(t, categories(agent.CheckContent(path, "", content, p)), "disabled-test", path)
+		}
+	})
+
+	t.Run("deleted assertions warn", func(t *testing.T) {
+		findings := agent.CheckContent("/p/foo_test.go",
+			"\trequire.NoError(t, err)\n\tassert.Equal(t, 2, n)\n",
+			"\trequire.NoError(t, err)\n", p)
+		require.Len(t, findings, 1)
+		assert.Equal(t, agent.Warn, findings[0].Verdict)
+		assert.Equal(t, "deleted-assertion", findings[0].Category)
+		assert.Contains(t, findings[0].Finding, "1 assertion(s)")
+	})
+
+	t.Run("rewording an assertion is not deleting it", func(t *testing.T) {
+		findings := agent.CheckContent("/p/foo_test.go", "\tassert.Equal(t, 2, n)\n", "\tassert.Equal(t, 3, n)\n", p)
+		assert.Empty(t, findings)
+	})
+
+	t.Run("skip markers in implementation code are not tests", func(t *testing.T) {
+		findings := agent.CheckContent("/p/runner.go", "", "func (r *R) Skip(n int) { r.t.Skip(n) }", p)
+		assert.Empty(t, findings)
+	})
+}
+
+func TestCheckContentLintSuppressions(t *testing.T) {
+	p := agent.DefaultPolicy()
+
+	t.Run("//nolint added warns", func(t *testing.T) {
+		findings := agent.CheckContent("/p/foo.go", "x := f()", "x := f() //nolint:errcheck", p)
+		require.Len(t, findings, 1)
+		assert.Equal(t, "software-engineering", findings[0].Agent)
+		assert.Equal(t, agent.Warn, findings[0].Verdict)
+		assert.Equal(t, "lint-suppression", findings[0].Category)
+	})
+
+	t.Run("an existing suppression kept as is passes", func(t *testing.T) {
+		findings := agent.CheckContent("/p/foo.go", "x := f() //nolint:errcheck", "y := f() //nolint:errcheck", p)
+		assert.Empty(t, findings)
+	})
+
+	t.Run("eslint-disable in TypeScript", func(t *testing.T) {
+		findings := agent.CheckContent("/p/app.ts", "", "// eslint-disable-next-line\nlet x: any", p)
+		assert.Equal(t, []string{"lint-suppression"}, categories(findings))
+	})
+}
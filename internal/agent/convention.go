@@ -0,0 +1,214 @@
+package agent
+
+import (
+	"bytes"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Convention describes how one language lays out its tests. Paths are
+// slash-separated and relative to the repository root.
+type Convention struct {
+	Name string
+	// Extensions are the implementation file extensions this convention
+	// claims, dot included.
+	Extensions []string
+	// IsTest reports whether a file is a test.
+	IsTest func(rel string) bool
+	// Ignore, when set, marks files that are neither implementation nor
+	// test, such as TypeScript declarations.
+	Ignore func(rel string) bool
+	// TestFiles lists where the tests for an implementation file may live,
+	// the preferred location first.
+	TestFiles func(rel string) []string
+	// InlineTestMarker, when set, is a line that opens tests kept inside the
+	// implementation file itself.
+	InlineTestMarker string
+	// LineComments are the prefixes that start a comment line.
+	LineComments []string
+	// BlockComments reports whether the language has /* */ comments.
+	BlockComments bool
+	// SkipMarkers disable a test, such as t.Skip( or @pytest.mark.skip.
+	SkipMarkers []string
+	// AssertionMarkers start an assertion in a test.
+	AssertionMarkers []string
+	// LintSuppressions silence a linter, such as //nolint.
+	LintSuppressions []string
+}
+
+func (c Convention) claims(rel string) bool {
+	for _, ext := range c.Extensions {
+		if strings.HasSuffix(rel, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// InlineTests reports whether content carries its own tests, and on which
+// 1-based line they start.
+func (c Convention) InlineTests(content []byte) (int, bool) {
+	if c.InlineTestMarker == "" {
+		return 0, false
+	}
+	for i, line := range bytes.Split(content, []byte("\n")) {
+		if strings.TrimSpace(string(line)) == c.InlineTestMarker {
+			return i + 1, true
+		}
+	}
+	return 0, false
+}
+
+var (
+	conventionsMu sync.RWMutex
+	conventions   = map[string]Convention{}
+)
+
+// RegisterConvention adds or replaces a convention by name.
+func RegisterConvention(c Convention) {
+	conventionsMu.Lock()
+	defer conventionsMu.Unlock()
+	conventions[c.Name] = c
+}
+
+// LookupConvention returns the registered convention with the given name.
+func LookupConvention(name string) (Convention, bool) {
+	conventionsMu.RLock()
+	defer conventionsMu.RUnlock()
+	c, ok := conventions[name]
+	return c, ok
+}
+
+// ConventionNames lists the registered conventions in name order.
+func ConventionNames() []string {
+	conventionsMu.RLock()
+	defer conventionsMu.RUnlock()
+	names := make([]string, 0, len(conventions))
+	for name := range conventions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	RegisterConvention(GoConvention("_test.go"))
+	RegisterConvention(typeScriptConvention())
+	RegisterConvention(pythonConvention())
+	RegisterConvention(rustConvention())
+}
+
+// GoConvention pairs foo.go with foo<suffix>, foo_test.go by default.
+func GoConvention(suffix string) Convention {
+	return Convention{
+		Name:       "go",
+		Extensions: []string{".go"},
+		IsTest:     func(rel string) bool { return strings.HasSuffix(rel, suffix) },
+		TestFiles: func(rel string) []string {
+			return []string{strings.TrimSuffix(rel, ".go") + suffix}
+		},
+		LineComments:     []string{"//"},
+		BlockComments:    true,
+		SkipMarkers:      []string{"t.Skip(", "t.Skipf(", "t.SkipNow(", "b.Skip(", "b.SkipNow("},
+		AssertionMarkers: []string{"assert.", "require.", "t.Error", "t.Fatal", "b.Error", "b.Fatal"},
+		LintSuppressions: []string{"//nolint", "// nolint", "#nosec"},
+	}
+}
+
+// typeScriptConvention pairs foo.ts with foo.test.ts, foo.spec.ts or
+// __tests__/foo.ts, and likewise for .tsx, .js and .jsx. Declarations and
+// tool configuration, such as jest.config.js or .eslintrc.js, need no tests.
+func typeScriptConvention() Convention {
+	return Convention{
+		Name:       "typescript",
+		Extensions: []string{".ts", ".tsx", ".js", ".jsx"},
+		IsTest: func(rel string) bool {
+			stem := strings.TrimSuffix(rel, path.Ext(rel))
+			return strings.HasSuffix(stem, ".test") || strings.HasSuffix(stem, ".spec") ||
+				strings.Contains("/"+rel, "/__tests__/")
+		},
+		Ignore: func(rel string) bool {
+			base := path.Base(rel)
+			return strings.HasSuffix(base, ".d.ts") || strings.HasPrefix(base, ".") ||
+				strings.HasSuffix(strings.TrimSuffix(base, path.Ext(base)), ".config")
+		},
+		TestFiles: func(rel string) []string {
+			dir, base := path.Split(rel)
+			ext := path.Ext(base)
+			stem := strings.TrimSuffix(base, ext)
+			return []string{
+				dir + stem + ".test" + ext,
+				dir + stem + ".spec" + ext,
+				dir + "__tests__/" + base,
+				dir + "__tests__/" + stem + ".test" + ext,
+			}
+		},
+		LineComments:     []string{"//"},
+		BlockComments:    true,
+		SkipMarkers:      []string{".skip(", "xit(", "xdescribe(", "xtest("},
+		AssertionMarkers: []string{"expect(", "assert"},
+		LintSuppressions: []string{"eslint-disable", "@ts-ignore", "@ts-nocheck"},
+	}
+}
+
+// pythonConvention pairs pkg/foo.py with pkg/test_foo.py, pkg/foo_test.py,
+// pkg/tests/test_foo.py or tests/pkg/test_foo.py.
+func pythonConvention() Convention {
+	return Convention{
+		Name:       "python",
+		Extensions: []string{".py"},
+		IsTest: func(rel string) bool {
+			base := path.Base(rel)
+			return strings.HasPrefix(base, "test_") || strings.HasSuffix(base, "_test.py") ||
+				strings.HasPrefix(rel, "tests/") || strings.Contains(rel, "/tests/")
+		},
+		Ignore: func(rel string) bool {
+			base := path.Base(rel)
+			return base == "__init__.py" || base == "conftest.py" || base == "setup.py"
+		},
+		TestFiles: func(rel string) []string {
+			dir, base := path.Split(rel)
+			stem := strings.TrimSuffix(base, ".py")
+			return []string{
+				dir + "test_" + base,
+				dir + stem + "_test.py",
+				dir + "tests/test_" + base,
+				"tests/" + dir + "test_" + base,
+			}
+		},
+		LineComments:     []string{"#"},
+		SkipMarkers:      []string{"@pytest.mark.skip", "@unittest.skip", "pytest.skip(", "self.skipTest("},
+		AssertionMarkers: []string{"assert", "pytest.raises("},
+		LintSuppressions: []string{"# noqa", "# type: ignore", "# pylint: disable"},
+	}
+}
+
+// rustConvention accepts tests inline in a #[cfg(test)] module, or an
+// integration test named after the file in the crate's tests/ directory.
+func rustConvention() Convention {
+	return Convention{
+		Name:       "rust",
+		Extensions: []string{".rs"},
+		IsTest: func(rel string) bool {
+			return strings.HasPrefix(rel, "tests/") || strings.Contains(rel, "/tests/") ||
+				strings.HasSuffix(rel, "_test.rs") || strings.HasSuffix(rel, "/tests.rs")
+		},
+		Ignore: func(rel string) bool { return path.Base(rel) == "build.rs" },
+		TestFiles: func(rel string) []string {
+			crate, file := "", rel
+			if i := strings.LastIndex(rel, "src/"); i >= 0 && (i == 0 || rel[i-1] == '/') {
+				crate, file = rel[:i], rel[i+len("src/"):]
+			}
+			stem := strings.TrimSuffix(path.Base(file), ".rs")
+			return []string{crate + "tests/" + stem + ".rs"}
+		},
+		InlineTestMarker: "#[cfg(test)]",
+		LineComments:     []string{"//"},
+		BlockComments:    true,
+		SkipMarkers:      []string{"#[ignore"},
+		AssertionMarkers: []string{"assert!(", "assert_eq!(", "assert_ne!("},
+		LintSuppressions: []string{"#[allow(", "#![allow("},
+	}
+}
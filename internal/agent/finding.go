@@ -18,7 +18,21 @@ type Finding struct {
 	Agent    string   `json:"agent"`
 	Verdict  Verdict  `json:"verdict"`
 	Severity Severity `json:"severity"`
+	Category string   `json:"category,omitempty"`
 	Finding  string   `json:"finding"`
 	File     string   `json:"file"`
 	Fix      string   `json:"fix"`
+	Model    string   `json:"model,omitempty"`
+}
+
+var verdictRank = map[Verdict]int{Pass: 0, Warn: 1, Block: 2}
+
+var severityRank = map[Severity]int{Low: 0, Medium: 1, High: 2, Critical: 3}
+
+func (v Verdict) AtLeast(min Verdict) bool {
+	return verdictRank[v] >= verdictRank[min]
+}
+
+func (s Severity) AtLeast(min Severity) bool {
+	return severityRank[s] >= severityRank[min]
 }
@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/gauthierbraillon/ensemble/internal/gotest"
+)
+
+// VerifyGreen runs the tests of the Go packages in dirs, one after another,
+// and reports the first that fails, with the package directory as its file.
+// ctx carries the time budget; packages it leaves no time for are reported as
+// unverified.
+func VerifyGreen(ctx context.Context, dirs []string) Finding {
+	if len(dirs) == 0 {
+		return Finding{Agent: "testing-quality", Verdict: Pass, Severity: Low, Finding: "no go package changed"}
+	}
+	for _, dir := range dirs {
+		res, err := gotest.Run(ctx, dir, "", ".")
+		switch {
+		case errors.Is(err, context.DeadlineExceeded):
+			return Finding{
+				Agent:    "testing-quality",
+				Verdict:  Warn,
+				Severity: Low,
+				Category: "test-budget",
+				Finding:  "tests of " + dir + " did not finish within the time budget",
+				File:     dir,
+				Fix:      "run go test in " + dir + " before moving on, or raise tests.budget",
+			}
+		case err != nil:
+			return Finding{
+				Agent:    "testing-quality",
+				Verdict:  Warn,
+				Severity: Low,
+				Category: "tests-not-run",
+				Finding:  "could not run the tests of " + dir + ": " + err.Error(),
+				File:     dir,
+				Fix:      "run go test in " + dir + " before moving on",
+			}
+		case !res.Passed:
+			return Finding{
+				Agent:    "testing-quality",
+				Verdict:  Block,
+				Severity: High,
+				Category: "failing-test",
+				Finding:  "go test failed in " + dir + ":\n" + res.Failures(),
+				File:     dir,
+				Fix:      "make the failing tests pass before moving on",
+			}
+		}
+	}
+	return Finding{Agent: "testing-quality", Verdict: Pass, Severity: Low, Category: "tests-pass", Finding: "tests pass"}
+}
+
+// GoPackages returns the directories of the Go files among paths, in order
+// and without repeats, skipping directories that no longer exist.
+func GoPackages(paths []string, p Policy) []string {
+	seen := map[string]bool{}
+	var dirs []string
+	for _, path := range paths {
+		conv, ok := p.Convention(path)
+		if !ok || conv.Name != "go" || !p.Covers(path) {
+			continue
+		}
+		dir := filepath.Dir(path)
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
@@ -0,0 +1,67 @@
+package agent_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gauthierbraillon/ensemble/internal/agent"
+)
+
+func TestVerifyGreen(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("passing package", func(t *testing.T) {
+		dir := redModule(t, map[string]string{"foo_test.go": passingFooTest})
+		f := agent.VerifyGreen(ctx, []string{dir})
+		assert.Equal(t, agent.Pass, f.Verdict, f.Finding)
+		assert.Equal(t, "tests-pass", f.Category)
+	})
+
+	t.Run("failing package blocks with the failure", func(t *testing.T) {
+		dir := redModule(t, map[string]string{"foo_test.go": passingFooTest})
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "foo.go"), []byte("package foo\n\nfunc Foo() int { return 2 }\n"), 0600))
+
+		f := agent.VerifyGreen(ctx, []string{dir})
+		assert.Equal(t, agent.Block, f.Verdict)
+		assert.Equal(t, "failing-test", f.Category)
+		assert.Contains(t, f.Finding, "want 1")
+		assert.Equal(t, dir, f.File)
+	})
+
+	t.Run("an exhausted budget warns", func(t *testing.T) {
+		dir := redModule(t, map[string]string{"foo_test.go": passingFooTest})
+		expired, cancel := context.WithTimeout(ctx, time.Nanosecond)
+		defer cancel()
+		<-expired.Done()
+
+		f := agent.VerifyGreen(expired, []string{dir})
+		assert.Equal(t, agent.Warn, f.Verdict)
+		assert.Equal(t, "test-budget", f.Category)
+	})
+
+	t.Run("no package, nothing to run", func(t *testing.T) {
+		f := agent.VerifyGreen(ctx, nil)
+		assert.Equal(t, agent.Pass, f.Verdict)
+		assert.Empty(t, f.Category, "nothing ran")
+	})
+}
+
+func TestGoPackages(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0750))
+	paths := []string{
+		filepath.Join(dir, "foo.go"),
+		filepath.Join(dir, "foo_test.go"),
+		filepath.Join(dir, "sub", "bar.go"),
+		filepath.Join(dir, "README.md"),
+		filepath.Join(dir, "app.ts"),
+		filepath.Join(dir, "gone", "baz.go"),
+	}
+	assert.Equal(t, []string{dir, filepath.Join(dir, "sub")}, agent.GoPackages(paths, agent.DefaultPolicy()))
+}
@@ -2,23 +2,52 @@ package agent
 
 import (
 	"os"
-	"strings"
 )
 
-func CheckFileWrite(filePath string) Finding {
-	if !strings.HasSuffix(filePath, ".go") || strings.HasSuffix(filePath, "_test.go") {
+// CheckFileWrite decides whether an implementation file may be written:
+// one of its test files must exist on disk, or, for conventions that keep
+// tests inline, the file must carry them. content is the text being written,
+// when known.
+func CheckFileWrite(filePath, content string, p Policy) Finding {
+	if !p.IsImpl(filePath) {
 		return Finding{Agent: "testing-quality", Verdict: Pass, Severity: Low, Finding: "not an implementation file"}
 	}
-	testFile := testFileName(filePath)
-	if _, err := os.Stat(testFile); err == nil {
-		return Finding{Agent: "testing-quality", Verdict: Pass, Severity: Low, Finding: "test file exists"}
+	testFiles := p.TestFiles(filePath)
+	for _, testFile := range testFiles {
+		if _, err := os.Stat(testFile); err == nil {
+			return Finding{Agent: "testing-quality", Verdict: Pass, Severity: Low, Finding: "test file exists"}
+		}
+	}
+	conv, _ := p.Convention(filePath)
+	if hasInlineTests(filePath, content, conv) {
+		return Finding{Agent: "testing-quality", Verdict: Pass, Severity: Low, Finding: "inline tests exist"}
+	}
+	fix := "write " + testFiles[0] + " with a failing test first"
+	if conv.InlineTestMarker != "" {
+		fix = "add a " + conv.InlineTestMarker + " module with a failing test first, or write " + testFiles[0]
 	}
 	return Finding{
 		Agent:    "testing-quality",
 		Verdict:  Block,
 		Severity: Critical,
+		Category: "missing-test",
 		Finding:  "no test file for " + filePath,
 		File:     filePath,
-		Fix:      "write " + testFile + " with a failing test first",
+		Fix:      fix,
+	}
+}
+
+func hasInlineTests(filePath, content string, conv Convention) bool {
+	if conv.InlineTestMarker == "" {
+		return false
+	}
+	if _, ok := conv.InlineTests([]byte(content)); ok {
+		return true
+	}
+	onDisk, err := os.ReadFile(filePath) // #nosec G304
+	if err != nil {
+		return false
 	}
+	_, ok := conv.InlineTests(onDisk)
+	return ok
 }
@@ -13,12 +13,12 @@ import (
 
 func TestTDDHookEnforcement(t *testing.T) {
 	t.Run("passes for non-Go files", func(t *testing.T) {
-		f := agent.CheckFileWrite("/project/README.md")
+		f := agent.CheckFileWrite("/project/README.md", "", agent.DefaultPolicy())
 		assert.Equal(t, agent.Pass, f.Verdict)
 	})
 
 	t.Run("passes for test files — writing tests is always allowed", func(t *testing.T) {
-		f := agent.CheckFileWrite("/project/internal/foo/foo_test.go")
+		f := agent.CheckFileWrite("/project/internal/foo/foo_test.go", "", agent.DefaultPolicy())
 		assert.Equal(t, agent.Pass, f.Verdict)
 	})
 
@@ -27,14 +27,59 @@ func TestTDDHookEnforcement(t *testing.T) {
 		testFile := filepath.Join(dir, "foo_test.go")
 		require.NoError(t, os.WriteFile(testFile, []byte("package foo_test"), 0600))
 
-		f := agent.CheckFileWrite(filepath.Join(dir, "foo.go"))
+		f := agent.CheckFileWrite(filepath.Join(dir, "foo.go"), "", agent.DefaultPolicy())
 		assert.Equal(t, agent.Pass, f.Verdict)
 	})
 
 	t.Run("blocks with critical severity when no test file exists on disk", func(t *testing.T) {
 		dir := t.TempDir()
-		f := agent.CheckFileWrite(filepath.Join(dir, "foo.go"))
+		f := agent.CheckFileWrite(filepath.Join(dir, "foo.go"), "", agent.DefaultPolicy())
 		assert.Equal(t, agent.Block, f.Verdict)
 		assert.Equal(t, agent.Critical, f.Severity)
 	})
 }
+
+func TestTDDHookConventions(t *testing.T) {
+	write := func(t *testing.T, dir, path string) {
+		t.Helper()
+		full := filepath.Join(dir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(full), 0750))
+		require.NoError(t, os.WriteFile(full, nil, 0600))
+	}
+
+	t.Run("TypeScript accepts foo.test.ts or __tests__/foo.ts", func(t *testing.T) {
+		dir := t.TempDir()
+		p := agent.Policy{Root: dir}
+		assert.Equal(t, agent.Block, agent.CheckFileWrite(filepath.Join(dir, "src/foo.ts"), "", p).Verdict)
+		write(t, dir, "src/__tests__/foo.ts")
+		assert.Equal(t, agent.Pass, agent.CheckFileWrite(filepath.Join(dir, "src/foo.ts"), "", p).Verdict)
+		write(t, dir, "src/bar.test.tsx")
+		assert.Equal(t, agent.Pass, agent.CheckFileWrite(filepath.Join(dir, "src/bar.tsx"), "", p).Verdict)
+	})
+
+	t.Run("Python accepts test_foo.py beside it or under tests/", func(t *testing.T) {
+		dir := t.TempDir()
+		p := agent.Policy{Root: dir}
+		impl := filepath.Join(dir, "app/foo.py")
+		block := agent.CheckFileWrite(impl, "", p)
+		assert.Equal(t, agent.Block, block.Verdict)
+		assert.Contains(t, block.Fix, filepath.Join("app", "test_foo.py"))
+		write(t, dir, "tests/app/test_foo.py")
+		assert.Equal(t, agent.Pass, agent.CheckFileWrite(impl, "", p).Verdict)
+	})
+
+	t.Run("Rust accepts an inline test module", func(t *testing.T) {
+		dir := t.TempDir()
+		p := agent.Policy{Root: dir}
+		impl := filepath.Join(dir, "src/lib.rs")
+		assert.Equal(t, agent.Block, agent.CheckFileWrite(impl, "pub fn f() {}\n", p).Verdict)
+		assert.Equal(t, agent.Pass, agent.CheckFileWrite(impl, "pub fn f() {}\n\n#[cfg(test)]\nmod tests {}\n", p).Verdict)
+	})
+
+	t.Run("a directory rule limits which conventions apply", func(t *testing.T) {
+		dir := t.TempDir()
+		p := agent.Policy{Root: dir, Conventions: []agent.ConventionRule{{Paths: []string{"scripts/**"}, Use: []string{"go"}}}}
+		assert.Equal(t, agent.Pass, agent.CheckFileWrite(filepath.Join(dir, "scripts/deploy.py"), "", p).Verdict)
+		assert.Equal(t, agent.Block, agent.CheckFileWrite(filepath.Join(dir, "app/deploy.py"), "", p).Verdict)
+	})
+}
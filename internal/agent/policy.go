@@ -0,0 +1,128 @@
+package agent
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/gauthierbraillon/ensemble/internal/glob"
+)
+
+type Policy struct {
+	Root       string
+	TestSuffix string
+	Include    []string
+	Exclude    []string
+	// Conventions choose test conventions by directory. The first rule
+	// matching a file decides; files no rule matches may use any registered
+	// convention.
+	Conventions []ConventionRule
+	// VerifyRed asks for a failing test, not just a test file, before Go
+	// implementation is written. See VerifyRed.
+	VerifyRed bool
+}
+
+// ConventionRule applies the named conventions to files matching Paths.
+type ConventionRule struct {
+	Paths []string
+	Use   []string
+}
+
+func DefaultPolicy() Policy {
+	return Policy{TestSuffix: "_test.go"}
+}
+
+// Convention returns the test convention that governs path, if any.
+func (p Policy) Convention(path string) (Convention, bool) {
+	rel := filepath.ToSlash(p.relative(path))
+	for _, name := range p.conventionNames(rel) {
+		c, ok := LookupConvention(name)
+		if !ok {
+			continue
+		}
+		if name == "go" {
+			c = GoConvention(p.testSuffix())
+		}
+		if c.claims(rel) {
+			return c, true
+		}
+	}
+	return Convention{}, false
+}
+
+func (p Policy) conventionNames(rel string) []string {
+	for _, r := range p.Conventions {
+		if glob.Any(r.Paths, rel) {
+			return r.Use
+		}
+	}
+	return ConventionNames()
+}
+
+func (p Policy) IsTest(path string) bool {
+	c, ok := p.Convention(path)
+	return ok && c.IsTest(filepath.ToSlash(p.relative(path)))
+}
+
+func (p Policy) IsImpl(path string) bool {
+	c, ok := p.Convention(path)
+	if !ok {
+		return false
+	}
+	rel := filepath.ToSlash(p.relative(path))
+	if c.IsTest(rel) || (c.Ignore != nil && c.Ignore(rel)) {
+		return false
+	}
+	return p.Covers(path)
+}
+
+// TestFile is the preferred location for implFile's test.
+func (p Policy) TestFile(implFile string) string {
+	files := p.TestFiles(implFile)
+	if len(files) == 0 {
+		return ""
+	}
+	return files[0]
+}
+
+// TestFiles lists every location where implFile's test may live, preferred
+// first. Absolute paths under Root come back absolute.
+func (p Policy) TestFiles(implFile string) []string {
+	c, ok := p.Convention(implFile)
+	if !ok {
+		return nil
+	}
+	rel := p.relative(implFile)
+	files := c.TestFiles(filepath.ToSlash(rel))
+	if rel != implFile {
+		for i, f := range files {
+			files[i] = filepath.Join(p.Root, filepath.FromSlash(f))
+		}
+	}
+	return files
+}
+
+func (p Policy) Covers(path string) bool {
+	rel := filepath.ToSlash(p.relative(path))
+	if len(p.Include) > 0 && !glob.Any(p.Include, rel) {
+		return false
+	}
+	return !glob.Any(p.Exclude, rel)
+}
+
+func (p Policy) relative(path string) string {
+	if p.Root == "" || !filepath.IsAbs(path) {
+		return path
+	}
+	rel, err := filepath.Rel(p.Root, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return rel
+}
+
+func (p Policy) testSuffix() string {
+	if p.TestSuffix == "" {
+		return "_test.go"
+	}
+	return p.TestSuffix
+}
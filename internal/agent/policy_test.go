@@ -0,0 +1,152 @@
+package agent_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gauthierbraillon/ensemble/internal/agent"
+	"github.com/gauthierbraillon/ensemble/internal/diff"
+)
+
+func TestPolicy(t *testing.T) {
+	t.Run("default policy pairs foo.go with foo_test.go", func(t *testing.T) {
+		p := agent.DefaultPolicy()
+		assert.True(t, p.IsImpl("internal/foo/foo.go"))
+		assert.False(t, p.IsImpl("internal/foo/foo_test.go"))
+		assert.False(t, p.IsImpl("README.md"))
+		assert.Equal(t, "internal/foo/foo_test.go", p.TestFile("internal/foo/foo.go"))
+	})
+
+	t.Run("custom test suffix changes the expected test file", func(t *testing.T) {
+		p := agent.Policy{TestSuffix: "_spec.go"}
+		assert.True(t, p.IsTest("foo_spec.go"))
+		assert.True(t, p.IsImpl("foo_test.go"))
+		assert.Equal(t, "foo_spec.go", p.TestFile("foo.go"))
+	})
+
+	t.Run("exclude globs take files out of scope", func(t *testing.T) {
+		p := agent.Policy{Exclude: []string{"vendor/**", "**/*.pb.go"}}
+		assert.False(t, p.IsImpl("vendor/x/y.go"))
+		assert.False(t, p.IsImpl("api/user.pb.go"))
+		assert.True(t, p.IsImpl("api/user.go"))
+	})
+
+	t.Run("include globs restrict scope", func(t *testing.T) {
+		p := agent.Policy{Include: []string{"internal/**"}}
+		assert.True(t, p.IsImpl("internal/agent/tdd.go"))
+		assert.False(t, p.IsImpl("cmd/root.go"))
+	})
+
+	t.Run("absolute paths are matched relative to the root", func(t *testing.T) {
+		p := agent.Policy{Root: "/repo", Exclude: []string{"gen/**"}}
+		assert.False(t, p.IsImpl("/repo/gen/models.go"))
+		assert.True(t, p.IsImpl("/repo/internal/models.go"))
+	})
+}
+
+func TestPolicyConventions(t *testing.T) {
+	p := agent.DefaultPolicy()
+	cases := []struct {
+		path         string
+		impl, test   bool
+		firstTestFor string
+	}{
+		{"web/src/app.ts", true, false, "web/src/app.test.ts"},
+		{"web/src/app.spec.ts", false, true, ""},
+		{"web/src/__tests__/app.ts", false, true, ""},
+		{"web/src/types.d.ts", false, false, ""},
+		{"jest.config.js", false, false, ""},
+		{"web/vite.config.ts", false, false, ""},
+		{"eslint.config.mjs", false, false, ""},
+		{"web/tailwind.config.cjs", false, false, ""},
+		{".prettierrc.js", false, false, ""},
+		{"web/.eslintrc.cjs", false, false, ""},
+		{"web/src/config.ts", true, false, "web/src/config.test.ts"},
+		{"svc/handler.py", true, false, "svc/test_handler.py"},
+		{"svc/test_handler.py", false, true, ""},
+		{"svc/__init__.py", false, false, ""},
+		{"crates/core/src/parse.rs", true, false, "crates/core/tests/parse.rs"},
+		{"crates/core/tests/parse.rs", false, true, ""},
+		{"docs/index.md", false, false, ""},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.impl, p.IsImpl(c.path), "IsImpl(%s)", c.path)
+		assert.Equal(t, c.test, p.IsTest(c.path), "IsTest(%s)", c.path)
+		if c.impl {
+			assert.Equal(t, c.firstTestFor, p.TestFile(c.path))
+		}
+	}
+
+	t.Run("every built-in convention is registered", func(t *testing.T) {
+		assert.Equal(t, []string{"go", "python", "rust", "typescript"}, agent.ConventionNames())
+	})
+
+	t.Run("the first matching directory rule wins", func(t *testing.T) {
+		p := agent.Policy{Conventions: []agent.ConventionRule{
+			{Paths: []string{"legacy/**"}, Use: []string{}},
+			{Paths: []string{"**"}, Use: []string{"typescript"}},
+		}}
+		assert.False(t, p.IsImpl("legacy/old.ts"))
+		assert.True(t, p.IsImpl("web/new.ts"))
+		assert.False(t, p.IsImpl("web/main.go"))
+	})
+}
+
+func TestReviewDiffHonoursPolicy(t *testing.T) {
+	patch, err := diff.Parse(`diff --git a/vendor/x/x.go b/vendor/x/x.go
+--- /dev/null
++++ b/vendor/x/x.go
+@@ -0,0 +1 @@
++package x
+`)
+	require.NoError(t, err)
+
+	t.Run("blocks under the default policy", func(t *testing.T) {
+		findings := agent.ReviewDiff(agent.Change{Patch: patch}, agent.DefaultPolicy())
+		assert.Equal(t, agent.Block, findings[0].Verdict)
+	})
+
+	t.Run("passes when the file is excluded", func(t *testing.T) {
+		findings := agent.ReviewDiff(agent.Change{Patch: patch}, agent.Policy{Exclude: []string{"vendor/**"}})
+		assert.Equal(t, agent.Pass, findings[0].Verdict)
+	})
+}
+
+func TestReviewDiffReadsFileSections(t *testing.T) {
+	t.Run("a deleted test does not cover its implementation", func(t *testing.T) {
+		patch, err := diff.Parse(`diff --git a/foo.go b/foo.go
+--- a/foo.go
++++ b/foo.go
+@@ -1 +1,2 @@
+ package foo
++func bar() {}
+diff --git a/foo_test.go b/foo_test.go
+deleted file mode 100644
+--- a/foo_test.go
++++ /dev/null
+@@ -1 +0,0 @@
+-package foo
+`)
+		require.NoError(t, err)
+		findings := agent.ReviewDiff(agent.Change{Patch: patch}, agent.DefaultPolicy())
+		require.Len(t, findings, 1)
+		assert.Equal(t, agent.Block, findings[0].Verdict)
+		assert.Equal(t, "foo.go", findings[0].File)
+	})
+
+	t.Run("a pure rename carries no content to test", func(t *testing.T) {
+		patch, err := diff.Parse("diff --git a/old.go b/new.go\nsimilarity index 100%\nrename from old.go\nrename to new.go\n")
+		require.NoError(t, err)
+		findings := agent.ReviewDiff(agent.Change{Patch: patch}, agent.DefaultPolicy())
+		assert.Equal(t, agent.Pass, findings[0].Verdict)
+	})
+}
+
+func TestSeverityAndVerdictOrdering(t *testing.T) {
+	assert.True(t, agent.Critical.AtLeast(agent.High))
+	assert.False(t, agent.Low.AtLeast(agent.Medium))
+	assert.True(t, agent.Block.AtLeast(agent.Warn))
+	assert.False(t, agent.Pass.AtLeast(agent.Warn))
+}
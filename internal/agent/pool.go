@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"context"
+	"sync"
+)
+
+type Review struct {
+	Name  string
+	Model string
+	Run   func(ctx context.Context) []Finding
+}
+
+func RunReviews(ctx context.Context, reviews []Review, concurrency int) []Finding {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	results := make([][]Finding, len(reviews))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, r := range reviews {
+		if !acquire(ctx, sem) {
+			results[i] = []Finding{skippedReview(r.Name, ctx.Err())}
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = withModel(r.Run(ctx), r.Model)
+		}()
+	}
+	wg.Wait()
+	var findings []Finding
+	for _, r := range results {
+		findings = append(findings, r...)
+	}
+	return findings
+}
+
+func withModel(findings []Finding, model string) []Finding {
+	for i := range findings {
+		if findings[i].Model == "" {
+			findings[i].Model = model
+		}
+	}
+	return findings
+}
+
+func acquire(ctx context.Context, sem chan struct{}) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	select {
+	case sem <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func skippedReview(name string, err error) Finding {
+	return Finding{
+		Agent:    name,
+		Verdict:  Warn,
+		Severity: Low,
+		Finding:  "skipped: " + err.Error(),
+	}
+}
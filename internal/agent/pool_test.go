@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func reviewReturning(name string, delay time.Duration) Review {
+	return Review{Name: name, Run: func(context.Context) []Finding {
+		time.Sleep(delay)
+		return []Finding{{Agent: name, Verdict: Pass, Severity: Low}}
+	}}
+}
+
+func TestRunReviews(t *testing.T) {
+	t.Run("returns findings in review order regardless of completion order", func(t *testing.T) {
+		reviews := []Review{
+			reviewReturning("slow", 30*time.Millisecond),
+			reviewReturning("medium", 15*time.Millisecond),
+			reviewReturning("fast", 0),
+		}
+		findings := RunReviews(context.Background(), reviews, 3)
+		require.Len(t, findings, 3)
+		assert.Equal(t, "slow", findings[0].Agent)
+		assert.Equal(t, "medium", findings[1].Agent)
+		assert.Equal(t, "fast", findings[2].Agent)
+	})
+
+	t.Run("runs reviews concurrently", func(t *testing.T) {
+		reviews := []Review{
+			reviewReturning("a", 50*time.Millisecond),
+			reviewReturning("b", 50*time.Millisecond),
+			reviewReturning("c", 50*time.Millisecond),
+		}
+		start := time.Now()
+		RunReviews(context.Background(), reviews, 3)
+		assert.Less(t, time.Since(start), 140*time.Millisecond)
+	})
+
+	t.Run("never exceeds the concurrency limit", func(t *testing.T) {
+		var inFlight, peak int32
+		tracked := func(context.Context) []Finding {
+			n := atomic.AddInt32(&inFlight, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&inFlight, -1)
+			return nil
+		}
+		reviews := make([]Review, 8)
+		for i := range reviews {
+			reviews[i] = Review{Name: "tracked", Run: tracked}
+		}
+		RunReviews(context.Background(), reviews, 2)
+		assert.LessOrEqual(t, atomic.LoadInt32(&peak), int32(2))
+	})
+
+	t.Run("treats a non-positive limit as sequential", func(t *testing.T) {
+		findings := RunReviews(context.Background(), []Review{reviewReturning("a", 0)}, 0)
+		require.Len(t, findings, 1)
+		assert.Equal(t, Pass, findings[0].Verdict)
+	})
+
+	t.Run("skips reviews not yet started when the context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		blocking := Review{Name: "blocking", Run: func(ctx context.Context) []Finding {
+			cancel()
+			<-ctx.Done()
+			return []Finding{skippedReview("blocking", ctx.Err())}
+		}}
+		findings := RunReviews(ctx, []Review{blocking, reviewReturning("never", 0)}, 1)
+		require.Len(t, findings, 2)
+		assert.Equal(t, "never", findings[1].Agent)
+		assert.Equal(t, Warn, findings[1].Verdict)
+		assert.Contains(t, findings[1].Finding, "skipped")
+	})
+
+	t.Run("records the model that produced each finding", func(t *testing.T) {
+		review := reviewReturning("security", 0)
+		review.Model = "claude-opus-4-6"
+		findings := RunReviews(context.Background(), []Review{review, reviewReturning("testing-quality", 0)}, 2)
+		require.Len(t, findings, 2)
+		assert.Equal(t, "claude-opus-4-6", findings[0].Model)
+		assert.Empty(t, findings[1].Model)
+	})
+}
@@ -0,0 +1,106 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gauthierbraillon/ensemble/internal/git"
+	"github.com/gauthierbraillon/ensemble/internal/gotest"
+)
+
+// VerifyRed checks that a failing test exists before the Go implementation
+// file filePath is written. It runs the test functions added or edited in
+// the file's test since HEAD, against the code as it stands before the
+// write, and blocks when every one of them already passes. A test that does
+// not compile counts as failing: it names code not written yet.
+func VerifyRed(ctx context.Context, filePath string, p Policy) Finding {
+	conv, ok := p.Convention(filePath)
+	if !ok || conv.Name != "go" || !p.IsImpl(filePath) {
+		return Finding{Agent: "testing-quality", Verdict: Pass, Severity: Low, Finding: "no go test to run"}
+	}
+	testFile := p.TestFile(filePath)
+	after, err := os.ReadFile(testFile) // #nosec G304
+	if err != nil {
+		return Finding{Agent: "testing-quality", Verdict: Pass, Severity: Low, Finding: "no go test to run"}
+	}
+	dir := filepath.Dir(testFile)
+	names, err := gotest.ChangedTests(committed(ctx, testFile), after)
+	if err != nil {
+		return unverified(filePath, err)
+	}
+	if len(names) == 0 {
+		return Finding{
+			Agent:    "testing-quality",
+			Verdict:  Warn,
+			Severity: Medium,
+			Category: "no-new-test",
+			Finding:  "no test in " + filepath.Base(testFile) + " changed since HEAD, so nothing shows " + filepath.Base(filePath) + " needs changing",
+			File:     filePath,
+			Fix:      "add a failing test to " + testFile + " first, or approve if this is a refactor",
+		}
+	}
+	res, err := gotest.Run(ctx, dir, gotest.RunPattern(names), ".")
+	if err != nil {
+		return unverified(filePath, err)
+	}
+	if res.Passed {
+		return Finding{
+			Agent:    "testing-quality",
+			Verdict:  Block,
+			Severity: High,
+			Category: "green-test",
+			Finding:  "no failing test for " + filepath.Base(filePath) + ": " + strings.Join(names, ", ") + " already pass",
+			File:     filePath,
+			Fix:      "make " + names[0] + " fail against the current code before writing the implementation",
+		}
+	}
+	return Finding{Agent: "testing-quality", Verdict: Pass, Severity: Low, Finding: "a new test fails before the change"}
+}
+
+// committed returns path as of HEAD, or nothing when it is untracked or
+// outside a repository.
+func committed(ctx context.Context, path string) []byte {
+	repo, err := git.Open(ctx, filepath.Dir(path))
+	if err != nil {
+		return nil
+	}
+	root, err := filepath.EvalSymlinks(repo.Root)
+	if err != nil {
+		root = repo.Root
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil {
+		return nil
+	}
+	data, err := repo.At("HEAD").ReadFile(filepath.ToSlash(rel))
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+func unverified(filePath string, err error) Finding {
+	reason := err.Error()
+	if errors.Is(err, context.DeadlineExceeded) {
+		reason = "go test ran out of time"
+	}
+	return Finding{
+		Agent:    "testing-quality",
+		Verdict:  Warn,
+		Severity: Low,
+		Category: "red-unverified",
+		Finding:  "could not verify a failing test for " + filepath.Base(filePath) + ": " + reason,
+		File:     filePath,
+		Fix:      "run go test on the new test and check that it fails",
+	}
+}
@@ -0,0 +1,99 @@
+package agent_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gauthierbraillon/ensemble/internal/agent"
+)
+
+// redModule returns a git repository holding a Go module with foo.go, where
+// Foo returns 1, committed alongside any extra files.
+func redModule(t *testing.T, committed map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/foo\n\ngo 1.21\n",
+		"foo.go": "package foo\n\nfunc Foo() int { return 1 }\n",
+	}
+	for name, content := range committed {
+		files[name] = content
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+	}
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "-A"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "commit.gpgsign=false", "commit", "-q", "-m", "initial"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, "git %v: %s", args, out)
+	}
+	return dir
+}
+
+const passingFooTest = "package foo\n\nimport \"testing\"\n\nfunc TestFoo(t *testing.T) {\n\tif Foo() != 1 {\n\t\tt.Fatal(\"want 1\")\n\t}\n}\n"
+
+func TestVerifyRed(t *testing.T) {
+	ctx := context.Background()
+	p := agent.DefaultPolicy()
+
+	t.Run("a new test that already passes blocks", func(t *testing.T) {
+		dir := redModule(t, nil)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "foo_test.go"), []byte(passingFooTest), 0600))
+
+		f := agent.VerifyRed(ctx, filepath.Join(dir, "foo.go"), p)
+		assert.Equal(t, agent.Block, f.Verdict)
+		assert.Equal(t, "green-test", f.Category)
+		assert.Contains(t, f.Finding, "TestFoo")
+	})
+
+	t.Run("a new test that fails passes", func(t *testing.T) {
+		dir := redModule(t, map[string]string{"foo_test.go": passingFooTest})
+		failing := passingFooTest + "\nfunc TestFooTwo(t *testing.T) {\n\tif Foo() != 2 {\n\t\tt.Fatal(\"want 2\")\n\t}\n}\n"
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "foo_test.go"), []byte(failing), 0600))
+
+		f := agent.VerifyRed(ctx, filepath.Join(dir, "foo.go"), p)
+		assert.Equal(t, agent.Pass, f.Verdict, f.Finding)
+	})
+
+	t.Run("a new test for code not written yet passes", func(t *testing.T) {
+		dir := redModule(t, nil)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "foo_test.go"),
+			[]byte("package foo\n\nimport \"testing\"\n\nfunc TestBar(t *testing.T) { Bar() }\n"), 0600))
+
+		f := agent.VerifyRed(ctx, filepath.Join(dir, "foo.go"), p)
+		assert.Equal(t, agent.Pass, f.Verdict, f.Finding)
+	})
+
+	t.Run("no test changed since HEAD asks", func(t *testing.T) {
+		dir := redModule(t, map[string]string{"foo_test.go": passingFooTest})
+
+		f := agent.VerifyRed(ctx, filepath.Join(dir, "foo.go"), p)
+		assert.Equal(t, agent.Warn, f.Verdict)
+		assert.Equal(t, "no-new-test", f.Category)
+	})
+
+	t.Run("go test that cannot run asks", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "foo_test.go"), []byte(passingFooTest), 0600))
+
+		f := agent.VerifyRed(ctx, filepath.Join(dir, "foo.go"), p)
+		assert.Equal(t, agent.Warn, f.Verdict)
+		assert.Equal(t, "red-unverified", f.Category)
+	})
+
+	t.Run("other languages are left to CheckFileWrite", func(t *testing.T) {
+		f := agent.VerifyRed(ctx, "/p/app.ts", p)
+		assert.Equal(t, agent.Pass, f.Verdict)
+	})
+}
@@ -7,11 +7,11 @@ import (
 	"github.com/gauthierbraillon/ensemble/internal/runner"
 )
 
-func ReviewSecurity(ctx context.Context, diff string, r runner.Runner) []Finding {
+func ReviewSecurity(ctx context.Context, c Change, r runner.Runner) []Finding {
 	if r == nil {
 		return []Finding{skippedSecurity("no runner configured")}
 	}
-	raw, err := r.Run(ctx, securityPrompt(diff))
+	raw, err := r.Run(ctx, securityPrompt(c))
 	if err != nil {
 		return []Finding{skippedSecurity(err.Error())}
 	}
@@ -28,18 +28,17 @@ func ReviewSecurity(ctx context.Context, diff string, r runner.Runner) []Finding
 	return findings
 }
 
-func securityPrompt(diff string) string {
+func securityPrompt(c Change) string {
 	return `You are a security reviewer. Review the following git diff for security issues only: OWASP Top 10, hardcoded secrets, injection patterns (SQL, command, path traversal), insecure deserialization, authentication and authorisation flaws.
 
 Do NOT comment on code quality, naming, or style — security issues only.
 
 Respond with a JSON array of findings. Each finding must be:
-{"agent":"security","verdict":"pass"|"warn"|"block","severity":"low"|"medium"|"high"|"critical","finding":"<one line>","file":"<path:line or empty>","fix":"<one line or empty>"}
+{"agent":"security","verdict":"pass"|"warn"|"block","severity":"low"|"medium"|"high"|"critical","category":"<kebab-case issue type, e.g. sql-injection or hardcoded-secret>","finding":"<one line>","file":"<path:line or empty>","fix":"<one line or empty>"}
 
 If no issues found, respond with exactly: []
 
-Diff:
-` + diff
+` + reviewInput(c)
 }
 
 func skippedSecurity(reason string) Finding {
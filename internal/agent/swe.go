@@ -9,11 +9,11 @@ import (
 	"github.com/gauthierbraillon/ensemble/internal/runner"
 )
 
-func ReviewCode(ctx context.Context, diff string, r runner.Runner) []Finding {
+func ReviewCode(ctx context.Context, c Change, r runner.Runner) []Finding {
 	if r == nil {
 		return []Finding{skippedSWE("no runner configured")}
 	}
-	raw, err := r.Run(ctx, swePrompt(diff))
+	raw, err := r.Run(ctx, swePrompt(c))
 	if err != nil {
 		return []Finding{skippedSWE(err.Error())}
 	}
@@ -30,16 +30,15 @@ func ReviewCode(ctx context.Context, diff string, r runner.Runner) []Finding {
 	return findings
 }
 
-func swePrompt(diff string) string {
+func swePrompt(c Change) string {
 	return `You are a software engineering reviewer. Review the following git diff for code quality issues only: naming, SOLID principles, duplication, dead code, error handling.
 
 Respond with a JSON array of findings. Each finding must be:
-{"agent":"software-engineering","verdict":"pass"|"warn"|"block","severity":"low"|"medium"|"high"|"critical","finding":"<one line>","file":"<path:line or empty>","fix":"<one line or empty>"}
+{"agent":"software-engineering","verdict":"pass"|"warn"|"block","severity":"low"|"medium"|"high"|"critical","category":"<kebab-case issue type, e.g. naming or duplication>","finding":"<one line>","file":"<path:line or empty>","fix":"<one line or empty>"}
 
 If no issues found, respond with exactly: []
 
-Diff:
-` + diff
+` + reviewInput(c)
 }
 
 func parseSWEResponse(raw string) ([]Finding, error) {
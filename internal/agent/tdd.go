@@ -2,57 +2,165 @@ package agent
 
 import (
 	"strings"
+
+	"github.com/gauthierbraillon/ensemble/internal/diff"
 )
 
-func ReviewDiff(diff string) []Finding {
-	implFiles := diffedGoFiles(diff)
-	if len(implFiles) == 0 {
-		return []Finding{passAll()}
+// ReviewDiff checks that every implementation file the change adds or
+// modifies comes with a test, as its language's convention defines one.
+// Deletions, pure renames and comment-only edits need none. When the change
+// carries a tree, a test that already exists there counts; a new file under
+// an existing test passes, while a modified file whose test was left
+// untouched only warns.
+func ReviewDiff(c Change, p Policy) []Finding {
+	inPatch := map[string]bool{}
+	for _, f := range c.Patch.Files {
+		if f.Status != diff.Deleted {
+			inPatch[f.Path()] = true
+		}
 	}
-	missing := missingTests(implFiles, diff)
-	if len(missing) == 0 {
-		return []Finding{passAll()}
+	var findings []Finding
+	for _, f := range c.Patch.Files {
+		if f.Status == diff.Deleted || len(f.Hunks) == 0 || !p.IsImpl(f.Path()) {
+			continue
+		}
+		conv, _ := p.Convention(f.Path())
+		if commentOnly(f, conv) {
+			continue
+		}
+		testFiles := p.TestFiles(f.Path())
+		if anyOf(testFiles, func(t string) bool { return inPatch[t] }) {
+			continue
+		}
+		added := f.Status == diff.Added || f.Status == diff.Copied
+		if line, ok := c.inlineTests(f, conv); ok {
+			if !added && !changedFrom(f, line) {
+				findings = append(findings, untouchedTest(f.Path(), "the "+conv.InlineTestMarker+" module of "+f.Path()))
+			}
+			continue
+		}
+		existing := anyOf(testFiles, c.exists)
+		switch {
+		case existing && added:
+			continue
+		case existing:
+			findings = append(findings, untouchedTest(f.Path(), testFiles[0]))
+		case added:
+			findings = append(findings, missingTest(f.Path(), testFiles[0], Critical, "implementation without test"))
+		default:
+			findings = append(findings, missingTest(f.Path(), testFiles[0], High, "modified implementation has no test"))
+		}
 	}
-	findings := make([]Finding, 0, len(missing))
-	for _, f := range missing {
-		findings = append(findings, Finding{
-			Agent:    "testing-quality",
-			Verdict:  Block,
-			Severity: Critical,
-			Finding:  "implementation without test",
-			File:     f,
-			Fix:      "add " + testFileName(f) + " with a failing test first",
-		})
+	if len(findings) == 0 {
+		return []Finding{passAll()}
 	}
 	return findings
 }
 
-func diffedGoFiles(diff string) []string {
-	var files []string
-	for _, line := range strings.Split(diff, "\n") {
-		if !strings.HasPrefix(line, "+++ b/") {
-			continue
+func (c Change) exists(path string) bool {
+	if c.Tree == nil {
+		return false
+	}
+	_, err := c.Tree.ReadFile(path)
+	return err == nil
+}
+
+// inlineTests finds where f's inline tests start: in the tree when there is
+// one, otherwise among the lines the patch adds.
+func (c Change) inlineTests(f diff.File, conv Convention) (int, bool) {
+	if conv.InlineTestMarker == "" {
+		return 0, false
+	}
+	if c.Tree != nil {
+		content, err := c.Tree.ReadFile(f.Path())
+		if err != nil {
+			return 0, false
 		}
-		path := strings.TrimPrefix(line, "+++ b/")
-		if strings.HasSuffix(path, ".go") && !strings.HasSuffix(path, "_test.go") {
-			files = append(files, path)
+		return conv.InlineTests(content)
+	}
+	for _, l := range f.AddedLines() {
+		if strings.TrimSpace(l.Text) == conv.InlineTestMarker {
+			return l.NewLine, true
 		}
 	}
-	return files
+	return 0, false
 }
 
-func missingTests(implFiles []string, diff string) []string {
-	var missing []string
-	for _, f := range implFiles {
-		if !strings.Contains(diff, "+++ b/"+testFileName(f)) {
-			missing = append(missing, f)
+// changedFrom reports whether the patch adds any line at or below line.
+func changedFrom(f diff.File, line int) bool {
+	for _, l := range f.AddedLines() {
+		if l.NewLine >= line {
+			return true
 		}
 	}
-	return missing
+	return false
 }
 
-func testFileName(implFile string) string {
-	return strings.TrimSuffix(implFile, ".go") + "_test.go"
+func anyOf(paths []string, pred func(string) bool) bool {
+	for _, path := range paths {
+		if pred(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// commentOnly reports whether every added or removed line is blank or a
+// comment. Block comments are followed from the start of each hunk, so one
+// opened above the hunk is not seen and the change counts as code.
+func commentOnly(f diff.File, conv Convention) bool {
+	for _, h := range f.Hunks {
+		inBlock := false
+		for _, l := range h.Lines {
+			text := strings.TrimSpace(l.Text)
+			opens := conv.BlockComments && strings.HasPrefix(text, "/*")
+			comment := inBlock || opens || text == "" || hasAnyPrefix(text, conv.LineComments)
+			if opens {
+				inBlock = true
+			}
+			if inBlock && strings.Contains(text, "*/") {
+				inBlock = false
+				comment = comment && strings.HasSuffix(text, "*/")
+			}
+			if l.Kind != diff.Context && !comment {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func missingTest(file, testFile string, severity Severity, finding string) Finding {
+	return Finding{
+		Agent:    "testing-quality",
+		Verdict:  Block,
+		Severity: severity,
+		Category: "missing-test",
+		Finding:  finding,
+		File:     file,
+		Fix:      "add " + testFile + " with a failing test first",
+	}
+}
+
+func untouchedTest(file, testFile string) Finding {
+	return Finding{
+		Agent:    "testing-quality",
+		Verdict:  Warn,
+		Severity: Medium,
+		Category: "untouched-test",
+		Finding:  "implementation changed but its test did not",
+		File:     file,
+		Fix:      "cover the change in " + testFile,
+	}
 }
 
 func passAll() Finding {
@@ -0,0 +1,172 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gauthierbraillon/ensemble/internal/diff"
+)
+
+func parsePatch(t *testing.T, raw string) diff.Patch {
+	t.Helper()
+	p, err := diff.Parse(raw)
+	require.NoError(t, err)
+	return p
+}
+
+const newFooDiff = `diff --git a/foo.go b/foo.go
+new file mode 100644
+--- /dev/null
++++ b/foo.go
+@@ -0,0 +1,2 @@
++package foo
++func Foo() {}
+`
+
+const modifiedFooDiff = `diff --git a/foo.go b/foo.go
+--- a/foo.go
++++ b/foo.go
+@@ -1,2 +1,2 @@
+ package foo
+-func Foo() {}
++func Foo() int { return 1 }
+`
+
+func TestReviewDiffSeverities(t *testing.T) {
+	withTest := mapTree{"foo_test.go": "package foo\n"}
+
+	t.Run("new file without any test blocks as critical", func(t *testing.T) {
+		findings := ReviewDiff(Change{Patch: parsePatch(t, newFooDiff), Tree: mapTree{}}, DefaultPolicy())
+		require.Len(t, findings, 1)
+		assert.Equal(t, Block, findings[0].Verdict)
+		assert.Equal(t, Critical, findings[0].Severity)
+		assert.Equal(t, "implementation without test", findings[0].Finding)
+	})
+
+	t.Run("new file under a test committed earlier passes", func(t *testing.T) {
+		findings := ReviewDiff(Change{Patch: parsePatch(t, newFooDiff), Tree: withTest}, DefaultPolicy())
+		assert.Equal(t, Pass, findings[0].Verdict)
+	})
+
+	t.Run("modified file without any test blocks as high", func(t *testing.T) {
+		findings := ReviewDiff(Change{Patch: parsePatch(t, modifiedFooDiff), Tree: mapTree{}}, DefaultPolicy())
+		require.Len(t, findings, 1)
+		assert.Equal(t, Block, findings[0].Verdict)
+		assert.Equal(t, High, findings[0].Severity)
+	})
+
+	t.Run("modified file whose existing test was not touched warns", func(t *testing.T) {
+		findings := ReviewDiff(Change{Patch: parsePatch(t, modifiedFooDiff), Tree: withTest}, DefaultPolicy())
+		require.Len(t, findings, 1)
+		assert.Equal(t, Warn, findings[0].Verdict)
+		assert.Equal(t, Medium, findings[0].Severity)
+		assert.Equal(t, "untouched-test", findings[0].Category)
+		assert.Contains(t, findings[0].Fix, "foo_test.go")
+	})
+
+	t.Run("without a tree an untested modification still blocks", func(t *testing.T) {
+		findings := ReviewDiff(Change{Patch: parsePatch(t, modifiedFooDiff)}, DefaultPolicy())
+		assert.Equal(t, Block, findings[0].Verdict)
+	})
+}
+
+func TestReviewDiffSkips(t *testing.T) {
+	cases := map[string]string{
+		"deleted implementation": `diff --git a/foo.go b/foo.go
+deleted file mode 100644
+--- a/foo.go
++++ /dev/null
+@@ -1,2 +0,0 @@
+-package foo
+-func Foo() {}
+`,
+		"line comment edit": `diff --git a/foo.go b/foo.go
+--- a/foo.go
++++ b/foo.go
+@@ -1,3 +1,4 @@
+ package foo
+-// Foo does things.
++// Foo does things well.
++
+ func Foo() {}
+`,
+		"block comment edit": `diff --git a/foo.go b/foo.go
+--- a/foo.go
++++ b/foo.go
+@@ -1,4 +1,5 @@
+ package foo
+ /*
++ * more detail
+ */
+ func Foo() {}
+`,
+	}
+	for name, raw := range cases {
+		t.Run(name, func(t *testing.T) {
+			findings := ReviewDiff(Change{Patch: parsePatch(t, raw), Tree: mapTree{}}, DefaultPolicy())
+			assert.Equal(t, Pass, findings[0].Verdict)
+		})
+	}
+
+	t.Run("code after a closing comment marker is still code", func(t *testing.T) {
+		raw := `diff --git a/foo.go b/foo.go
+--- a/foo.go
++++ b/foo.go
+@@ -1,2 +1,2 @@
+ package foo
+-/* old */ var x = 1
++/* new */ var x = 2
+`
+		findings := ReviewDiff(Change{Patch: parsePatch(t, raw), Tree: mapTree{}}, DefaultPolicy())
+		assert.Equal(t, Block, findings[0].Verdict)
+	})
+}
+
+func TestReviewDiffConventions(t *testing.T) {
+	t.Run("a TypeScript file is covered by its spec in the patch", func(t *testing.T) {
+		raw := `diff --git a/src/app.ts b/src/app.ts
+--- /dev/null
++++ b/src/app.ts
+@@ -0,0 +1 @@
++export const x = 1
+diff --git a/src/app.spec.ts b/src/app.spec.ts
+--- /dev/null
++++ b/src/app.spec.ts
+@@ -0,0 +1 @@
++test("x", () => {})
+`
+		findings := ReviewDiff(Change{Patch: parsePatch(t, raw)}, DefaultPolicy())
+		assert.Equal(t, Pass, findings[0].Verdict)
+	})
+
+	t.Run("a Python comment edit needs no test", func(t *testing.T) {
+		raw := "--- a/app.py\n+++ b/app.py\n@@ -1 +1 @@\n-# old\n+# new\n"
+		findings := ReviewDiff(Change{Patch: parsePatch(t, raw), Tree: mapTree{}}, DefaultPolicy())
+		assert.Equal(t, Pass, findings[0].Verdict)
+	})
+
+	rust := "pub fn f() -> i32 { 2 }\n\n#[cfg(test)]\nmod tests {\n    #[test]\n    fn f_is_two() { assert_eq!(super::f(), 2); }\n}\n"
+
+	t.Run("a Rust file with inline tests passes when the tests change too", func(t *testing.T) {
+		raw := "--- a/src/lib.rs\n+++ b/src/lib.rs\n@@ -1,6 +1,6 @@\n-pub fn f() -> i32 { 1 }\n+pub fn f() -> i32 { 2 }\n \n #[cfg(test)]\n mod tests {\n     #[test]\n-    fn f_is_two() { assert_eq!(super::f(), 1); }\n+    fn f_is_two() { assert_eq!(super::f(), 2); }\n"
+		findings := ReviewDiff(Change{Patch: parsePatch(t, raw), Tree: mapTree{"src/lib.rs": rust}}, DefaultPolicy())
+		assert.Equal(t, Pass, findings[0].Verdict)
+	})
+
+	t.Run("a Rust file whose inline tests were not touched warns", func(t *testing.T) {
+		raw := "--- a/src/lib.rs\n+++ b/src/lib.rs\n@@ -1,3 +1,3 @@\n-pub fn f() -> i32 { 1 }\n+pub fn f() -> i32 { 2 }\n \n #[cfg(test)]\n"
+		findings := ReviewDiff(Change{Patch: parsePatch(t, raw), Tree: mapTree{"src/lib.rs": rust}}, DefaultPolicy())
+		require.Len(t, findings, 1)
+		assert.Equal(t, Warn, findings[0].Verdict)
+		assert.Contains(t, findings[0].Fix, "#[cfg(test)]")
+	})
+
+	t.Run("a new Rust file without inline or integration tests blocks", func(t *testing.T) {
+		raw := "--- /dev/null\n+++ b/src/lib.rs\n@@ -0,0 +1 @@\n+pub fn f() {}\n"
+		findings := ReviewDiff(Change{Patch: parsePatch(t, raw), Tree: mapTree{"src/lib.rs": "pub fn f() {}\n"}}, DefaultPolicy())
+		assert.Equal(t, Block, findings[0].Verdict)
+		assert.Contains(t, findings[0].Fix, "tests/lib.rs")
+	})
+}
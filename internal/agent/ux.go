@@ -6,19 +6,20 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/gauthierbraillon/ensemble/internal/diff"
 	"github.com/gauthierbraillon/ensemble/internal/runner"
 )
 
 var exportedSymbol = regexp.MustCompile(`\b(func|type|var|const)\s+[A-Z]\w*`)
 
-func ReviewUX(ctx context.Context, diff string, r runner.Runner) []Finding {
-	if !hasExportedAPIChange(diff) {
+func ReviewUX(ctx context.Context, c Change, r runner.Runner) []Finding {
+	if !hasExportedAPIChange(c.Patch) {
 		return nil
 	}
 	if r == nil {
 		return []Finding{skippedUX("no runner configured")}
 	}
-	raw, err := r.Run(ctx, uxPrompt(diff))
+	raw, err := r.Run(ctx, uxPrompt(c))
 	if err != nil {
 		return []Finding{skippedUX(err.Error())}
 	}
@@ -35,18 +36,13 @@ func ReviewUX(ctx context.Context, diff string, r runner.Runner) []Finding {
 	return findings
 }
 
-func hasExportedAPIChange(diff string) bool {
-	inTestFile := false
-	for _, line := range strings.Split(diff, "\n") {
-		if strings.HasPrefix(line, "+++ b/") {
-			inTestFile = strings.HasSuffix(line, "_test.go")
+func hasExportedAPIChange(patch diff.Patch) bool {
+	for _, f := range patch.Files {
+		if f.Status == diff.Deleted || strings.HasSuffix(f.Path(), "_test.go") {
 			continue
 		}
-		if inTestFile {
-			continue
-		}
-		if strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "++") {
-			if exportedSymbol.MatchString(line) {
+		for _, l := range f.AddedLines() {
+			if exportedSymbol.MatchString(l.Text) {
 				return true
 			}
 		}
@@ -54,18 +50,17 @@ func hasExportedAPIChange(diff string) bool {
 	return false
 }
 
-func uxPrompt(diff string) string {
+func uxPrompt(c Change) string {
 	return `You are a UX/API design reviewer. Review the following git diff for exported API surface issues only: naming conventions, Go idiomatic naming, API clarity, consistency with existing patterns.
 
 Do NOT comment on code quality, security, or implementation details — exported API naming and consistency only.
 
 Respond with a JSON array of findings. Each finding must be:
-{"agent":"ux-design","verdict":"pass"|"warn"|"block","severity":"low"|"medium"|"high"|"critical","finding":"<one line>","file":"<path:line or empty>","fix":"<one line or empty>"}
+{"agent":"ux-design","verdict":"pass"|"warn"|"block","severity":"low"|"medium"|"high"|"critical","category":"<kebab-case issue type, e.g. naming or consistency>","finding":"<one line>","file":"<path:line or empty>","fix":"<one line or empty>"}
 
 If no issues found, respond with exactly: []
 
-Diff:
-` + diff
+` + reviewInput(c)
 }
 
 func passedUX() Finding {
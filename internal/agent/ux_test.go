@@ -3,50 +3,51 @@ package agent
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/gauthierbraillon/ensemble/internal/diff"
 )
 
 func TestUXDesignAgentReview(t *testing.T) {
 	t.Run("silent when diff contains no exported API change", func(t *testing.T) {
-		diff := `diff --git a/internal/foo/foo.go b/internal/foo/foo.go
-+++ b/internal/foo/foo.go
-+func add(a, b int) int { return a + b }`
-		findings := ReviewUX(context.Background(), diff, stubRunner{out: "[]"})
+		patch := patchAdding(t, "internal/foo/foo.go", "func add(a, b int) int { return a + b }")
+		findings := ReviewUX(context.Background(), Change{Patch: patch}, stubRunner{out: "[]"})
 		assert.Empty(t, findings)
 	})
 
 	t.Run("passes when no API design issues found", func(t *testing.T) {
-		findings := ReviewUX(context.Background(), diffWithExportedFuncUnit(), stubRunner{out: "[]"})
+		findings := ReviewUX(context.Background(), diffWithExportedFuncUnit(t), stubRunner{out: "[]"})
 		require.Len(t, findings, 1)
 		assert.Equal(t, Pass, findings[0].Verdict)
 	})
 
 	t.Run("returns warn finding for unclear exported name", func(t *testing.T) {
 		raw := `[{"agent":"ux-design","verdict":"warn","severity":"low","finding":"unclear name","file":"api.go:1","fix":"rename to GetUser"}]`
-		findings := ReviewUX(context.Background(), diffWithExportedFuncUnit(), stubRunner{out: raw})
+		findings := ReviewUX(context.Background(), diffWithExportedFuncUnit(t), stubRunner{out: raw})
 		require.Len(t, findings, 1)
 		assert.Equal(t, Warn, findings[0].Verdict)
 	})
 
 	t.Run("returns block finding for API convention violation", func(t *testing.T) {
 		raw := `[{"agent":"ux-design","verdict":"block","severity":"high","finding":"API breaks convention","file":"api.go:5","fix":"follow REST naming"}]`
-		findings := ReviewUX(context.Background(), diffWithExportedFuncUnit(), stubRunner{out: raw})
+		findings := ReviewUX(context.Background(), diffWithExportedFuncUnit(t), stubRunner{out: raw})
 		require.Len(t, findings, 1)
 		assert.Equal(t, Block, findings[0].Verdict)
 	})
 
 	t.Run("skips gracefully when no runner is configured", func(t *testing.T) {
-		findings := ReviewUX(context.Background(), diffWithExportedFuncUnit(), nil)
+		findings := ReviewUX(context.Background(), diffWithExportedFuncUnit(t), nil)
 		require.Len(t, findings, 1)
 		assert.Equal(t, Warn, findings[0].Verdict)
 		assert.Contains(t, findings[0].Finding, "skipped")
 	})
 
 	t.Run("skips gracefully on runner error", func(t *testing.T) {
-		findings := ReviewUX(context.Background(), diffWithExportedFuncUnit(), stubRunner{err: errors.New("timeout")})
+		findings := ReviewUX(context.Background(), diffWithExportedFuncUnit(t), stubRunner{err: errors.New("timeout")})
 		require.Len(t, findings, 1)
 		assert.Equal(t, Warn, findings[0].Verdict)
 		assert.Contains(t, findings[0].Finding, "skipped")
@@ -54,14 +55,14 @@ func TestUXDesignAgentReview(t *testing.T) {
 
 	t.Run("enforces agent name regardless of model response", func(t *testing.T) {
 		raw := `[{"agent":"software-engineering","verdict":"warn","severity":"low","finding":"issue","file":"","fix":""}]`
-		findings := ReviewUX(context.Background(), diffWithExportedFuncUnit(), stubRunner{out: raw})
+		findings := ReviewUX(context.Background(), diffWithExportedFuncUnit(t), stubRunner{out: raw})
 		require.Len(t, findings, 1)
 		assert.Equal(t, "ux-design", findings[0].Agent)
 	})
 
 	t.Run("handles markdown-fenced JSON in model response", func(t *testing.T) {
 		raw := "```json\n[]\n```"
-		findings := ReviewUX(context.Background(), diffWithExportedFuncUnit(), stubRunner{out: raw})
+		findings := ReviewUX(context.Background(), diffWithExportedFuncUnit(t), stubRunner{out: raw})
 		require.Len(t, findings, 1)
 		assert.Equal(t, Pass, findings[0].Verdict)
 	})
@@ -69,35 +70,59 @@ func TestUXDesignAgentReview(t *testing.T) {
 
 func TestExportedAPIChangeDetection(t *testing.T) {
 	t.Run("detects added exported function", func(t *testing.T) {
-		assert.True(t, hasExportedAPIChange("+func Add(a, b int) int { return a + b }"))
+		assert.True(t, hasExportedAPIChange(patchAdding(t, "foo.go", "func Add(a, b int) int { return a + b }")))
 	})
 
 	t.Run("ignores added unexported function", func(t *testing.T) {
-		assert.False(t, hasExportedAPIChange("+func add(a, b int) int { return a + b }"))
+		assert.False(t, hasExportedAPIChange(patchAdding(t, "foo.go", "func add(a, b int) int { return a + b }")))
 	})
 
 	t.Run("ignores exported symbols in test files", func(t *testing.T) {
-		diff := `+++ b/foo_test.go
-+func TestAdd(t *testing.T) {}
-+func Add(a, b int) int { return a + b }`
-		assert.False(t, hasExportedAPIChange(diff))
+		patch := patchAdding(t, "foo_test.go", "func TestAdd(t *testing.T) {}", "func Add(a, b int) int { return a + b }")
+		assert.False(t, hasExportedAPIChange(patch))
 	})
 
 	t.Run("detects added exported type", func(t *testing.T) {
-		assert.True(t, hasExportedAPIChange("+type Config struct { Host string }"))
+		assert.True(t, hasExportedAPIChange(patchAdding(t, "foo.go", "type Config struct { Host string }")))
 	})
 
 	t.Run("detects added exported constant", func(t *testing.T) {
-		assert.True(t, hasExportedAPIChange("+const MaxRetries = 3"))
+		assert.True(t, hasExportedAPIChange(patchAdding(t, "foo.go", "const MaxRetries = 3")))
 	})
 
 	t.Run("ignores removed exported symbols — deletions do not trigger review", func(t *testing.T) {
-		assert.False(t, hasExportedAPIChange("-func Remove() {}"))
+		patch, err := diff.Parse("--- a/foo.go\n+++ b/foo.go\n@@ -1 +0,0 @@\n-func Remove() {}\n")
+		require.NoError(t, err)
+		assert.False(t, hasExportedAPIChange(patch))
+	})
+
+	t.Run("ignores exported symbols in a context line", func(t *testing.T) {
+		patch, err := diff.Parse("--- a/foo.go\n+++ b/foo.go\n@@ -1,2 +1,2 @@\n func Keep() {}\n-var x = 1\n+var x = 2\n")
+		require.NoError(t, err)
+		assert.False(t, hasExportedAPIChange(patch))
 	})
+
+	t.Run("attributes added lines to their own file", func(t *testing.T) {
+		raw := "diff --git a/foo_test.go b/foo_test.go\n--- a/foo_test.go\n+++ b/foo_test.go\n@@ -0,0 +1 @@\n+func Helper() {}\n" +
+			"diff --git a/foo.go b/foo.go\n--- a/foo.go\n+++ b/foo.go\n@@ -0,0 +1 @@\n+func add() {}\n"
+		patch, err := diff.Parse(raw)
+		require.NoError(t, err)
+		assert.False(t, hasExportedAPIChange(patch))
+	})
+}
+
+// patchAdding builds a patch that creates path with the given lines.
+func patchAdding(t *testing.T, path string, lines ...string) diff.Patch {
+	t.Helper()
+	raw := fmt.Sprintf("diff --git a/%[1]s b/%[1]s\n--- /dev/null\n+++ b/%[1]s\n@@ -0,0 +1,%[2]d @@\n", path, len(lines))
+	for _, l := range lines {
+		raw += "+" + l + "\n"
+	}
+	patch, err := diff.Parse(raw)
+	require.NoError(t, err)
+	return patch
 }
 
-func diffWithExportedFuncUnit() string {
-	return `diff --git a/internal/foo/foo.go b/internal/foo/foo.go
-+++ b/internal/foo/foo.go
-+func Add(a, b int) int { return a + b }`
+func diffWithExportedFuncUnit(t *testing.T) Change {
+	return Change{Patch: patchAdding(t, "internal/foo/foo.go", "func Add(a, b int) int { return a + b }")}
 }
@@ -0,0 +1,325 @@
+// Package config loads the project's .ensemble.yaml.
+// A missing file is not an error: every field has a default that matches
+// ensemble's built-in behaviour.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/gauthierbraillon/ensemble/internal/agent"
+	"github.com/gauthierbraillon/ensemble/internal/glob"
+	"github.com/gauthierbraillon/ensemble/internal/runner"
+)
+
+// FileName is the configuration file looked up at the repository root.
+const FileName = ".ensemble.yaml"
+
+// Agents lists every agent that can be configured, in review order.
+var Agents = []string{"testing-quality", "software-engineering", "security", "ux-design"}
+
+// Orchestrator names the interactive session's model under agents. It takes
+// a tier or a model like a review agent but is not one, so it cannot be
+// disabled.
+const Orchestrator = "orchestrator"
+
+type Config struct {
+	Tier   string           `yaml:"tier"`
+	Runner Runner           `yaml:"runner"`
+	Agents map[string]Agent `yaml:"agents"`
+	Block  Threshold        `yaml:"block"`
+	Paths  Paths            `yaml:"paths"`
+	Tests  Tests            `yaml:"tests"`
+
+	// Path is the file the configuration was read from, empty when defaulted.
+	Path string `yaml:"-"`
+	// Root is the directory paths are matched against.
+	Root string `yaml:"-"`
+}
+
+type Runner struct {
+	Backend     string        `yaml:"backend"`
+	Binary      string        `yaml:"binary"`
+	BaseURL     string        `yaml:"base_url"`
+	Timeout     time.Duration `yaml:"timeout"`
+	MaxBytes    int64         `yaml:"max_bytes"`
+	Concurrency int           `yaml:"concurrency"`
+}
+
+type Agent struct {
+	Enabled *bool  `yaml:"enabled"`
+	Tier    string `yaml:"tier"`
+	Model   string `yaml:"model"`
+}
+
+// Threshold is the lowest verdict and severity that fails a cycle.
+type Threshold struct {
+	Verdict  agent.Verdict  `yaml:"verdict"`
+	Severity agent.Severity `yaml:"severity"`
+}
+
+type Paths struct {
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+}
+
+type Tests struct {
+	Suffix      string           `yaml:"suffix"`
+	Conventions []ConventionRule `yaml:"conventions"`
+	// VerifyRed makes the hook run the new Go tests before an
+	// implementation write and deny it unless one of them fails.
+	VerifyRed bool `yaml:"verify_red"`
+	// Budget bounds how long the hook may spend running tests after an edit.
+	Budget time.Duration `yaml:"budget"`
+}
+
+// ConventionRule picks the test conventions for files matching Paths. The
+// first matching rule wins; unmatched files may use any convention.
+type ConventionRule struct {
+	Paths []string `yaml:"paths"`
+	Use   []string `yaml:"use"`
+}
+
+// Default returns the configuration used when no .ensemble.yaml exists.
+func Default() Config {
+	return Config{
+		Runner: Runner{
+			Backend:     runner.BackendCLI,
+			Binary:      "claude",
+			Timeout:     30 * time.Second,
+			MaxBytes:    32 * 1024,
+			Concurrency: 4,
+		},
+		Block: Threshold{Verdict: agent.Block, Severity: agent.Low},
+		Tests: Tests{Suffix: "_test.go", Budget: 30 * time.Second},
+	}
+}
+
+// Load finds .ensemble.yaml in dir or its parents, stopping at the repository root,
+// and returns it merged over the defaults.
+func Load(dir string) (Config, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return Config{}, err
+	}
+	path, root := find(abs)
+	if path == "" {
+		c := Default()
+		c.Root = root
+		return c, nil
+	}
+	data, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return Config{}, err
+	}
+	c, err := Parse(data)
+	if err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+	c.Path = path
+	c.Root = root
+	return c, nil
+}
+
+// Parse decodes and validates a configuration document.
+func Parse(data []byte) (Config, error) {
+	c := Default()
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&c); err != nil && !errors.Is(err, io.EOF) {
+		return Config{}, fmt.Errorf("malformed config: %w", err)
+	}
+	if err := c.Validate(); err != nil {
+		return Config{}, err
+	}
+	return c, nil
+}
+
+func find(dir string) (path, root string) {
+	for d := dir; ; d = filepath.Dir(d) {
+		candidate := filepath.Join(d, FileName)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, d
+		}
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil {
+			return "", d
+		}
+		if filepath.Dir(d) == d {
+			return "", dir
+		}
+	}
+}
+
+// Validate reports every problem in the configuration at once.
+func (c Config) Validate() error {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	if c.Tier != "" {
+		if _, err := runner.ModelForTier(c.Tier); err != nil {
+			add("tier: %s", err)
+		}
+	}
+	if err := checkBackend(c.Runner.Backend); err != nil {
+		add("runner.backend: %s", err)
+	}
+	if c.Runner.Timeout <= 0 {
+		add("runner.timeout: must be positive")
+	}
+	if c.Runner.MaxBytes <= 0 {
+		add("runner.max_bytes: must be positive")
+	}
+	if c.Runner.Concurrency < 1 {
+		add("runner.concurrency: must be at least 1")
+	}
+	names := make([]string, 0, len(c.Agents))
+	for name := range c.Agents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		a := c.Agents[name]
+		switch {
+		case name == Orchestrator:
+			if a.Enabled != nil {
+				add("agents.%s.enabled: the orchestrator is not a review agent and cannot be disabled", name)
+			}
+		case !KnownAgent(name):
+			add("agents.%s: unknown agent (want one of %s or %s)", name, strings.Join(Agents, ", "), Orchestrator)
+			continue
+		}
+		if a.Tier != "" {
+			if _, err := runner.ModelForTier(a.Tier); err != nil {
+				add("agents.%s.tier: %s", name, err)
+			}
+		}
+		if a.Model != "" && !runner.ValidModels[a.Model] {
+			add("agents.%s.model: invalid model %q", name, a.Model)
+		}
+	}
+	switch c.Block.Verdict {
+	case agent.Warn, agent.Block:
+	default:
+		add("block.verdict: must be %q or %q, got %q", agent.Warn, agent.Block, c.Block.Verdict)
+	}
+	switch c.Block.Severity {
+	case agent.Low, agent.Medium, agent.High, agent.Critical:
+	default:
+		add("block.severity: must be low, medium, high or critical, got %q", c.Block.Severity)
+	}
+	for _, p := range append(append([]string{}, c.Paths.Include...), c.Paths.Exclude...) {
+		if err := glob.Validate(p); err != nil {
+			add("paths: invalid glob %q: %s", p, err)
+		}
+	}
+	if !strings.HasSuffix(c.Tests.Suffix, ".go") || c.Tests.Suffix == ".go" {
+		add("tests.suffix: must end in .go, got %q", c.Tests.Suffix)
+	}
+	if c.Tests.Budget <= 0 {
+		add("tests.budget: must be positive")
+	}
+	for i, r := range c.Tests.Conventions {
+		if len(r.Paths) == 0 {
+			add("tests.conventions[%d].paths: must list at least one glob", i)
+		}
+		for _, p := range r.Paths {
+			if err := glob.Validate(p); err != nil {
+				add("tests.conventions[%d].paths: invalid glob %q: %s", i, p, err)
+			}
+		}
+		for _, name := range r.Use {
+			if _, ok := agent.LookupConvention(name); !ok {
+				add("tests.conventions[%d].use: unknown convention %q (want one of %s)", i, name, strings.Join(agent.ConventionNames(), ", "))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid config:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// Enabled reports whether the named agent should run. Agents are on unless disabled.
+func (c Config) Enabled(name string) bool {
+	a, ok := c.Agents[name]
+	return !ok || a.Enabled == nil || *a.Enabled
+}
+
+// SelectBackend picks the runner backend: ENSEMBLE_RUNNER, then backend.
+func (r Runner) SelectBackend(getenv func(string) string) (string, error) {
+	b := getenv("ENSEMBLE_RUNNER")
+	if b == "" {
+		return r.Backend, nil
+	}
+	if err := checkBackend(b); err != nil {
+		return "", fmt.Errorf("ENSEMBLE_RUNNER: %w", err)
+	}
+	return b, nil
+}
+
+func checkBackend(b string) error {
+	switch b {
+	case "", runner.BackendCLI, runner.BackendAPI:
+		return nil
+	}
+	return fmt.Errorf("must be %q or %q, got %q", runner.BackendCLI, runner.BackendAPI, b)
+}
+
+// Model picks the model for an agent. Environment variables override the file:
+// ENSEMBLE_TIER_<AGENT>, then agents.<name>.model or .tier, then ENSEMBLE_TIER, then tier.
+func (c Config) Model(name string, getenv func(string) string) (string, error) {
+	if getenv(runner.TierEnvKey(name)) == "" {
+		a := c.Agents[name]
+		if a.Model != "" {
+			return a.Model, nil
+		}
+		if a.Tier != "" {
+			return runner.ModelForTier(a.Tier)
+		}
+		if getenv("ENSEMBLE_TIER") == "" && c.Tier != "" {
+			return runner.ModelForTier(c.Tier)
+		}
+	}
+	return runner.ResolveModel(name, getenv)
+}
+
+// Fails reports whether a finding meets the block threshold.
+func (c Config) Fails(f agent.Finding) bool {
+	return f.Verdict.AtLeast(c.Block.Verdict) && f.Severity.AtLeast(c.Block.Severity)
+}
+
+// Policy returns the file-selection rules the TDD agent and hook apply.
+func (c Config) Policy() agent.Policy {
+	var rules []agent.ConventionRule
+	for _, r := range c.Tests.Conventions {
+		rules = append(rules, agent.ConventionRule(r))
+	}
+	return agent.Policy{
+		Root:        c.Root,
+		TestSuffix:  c.Tests.Suffix,
+		Include:     c.Paths.Include,
+		Exclude:     c.Paths.Exclude,
+		Conventions: rules,
+		VerifyRed:   c.Tests.VerifyRed,
+	}
+}
+
+// KnownAgent reports whether name is one of Agents.
+func KnownAgent(name string) bool {
+	for _, a := range Agents {
+		if a == name {
+			return true
+		}
+	}
+	return false
+}
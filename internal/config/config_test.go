@@ -0,0 +1,255 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gauthierbraillon/ensemble/internal/agent"
+	"github.com/gauthierbraillon/ensemble/internal/config"
+)
+
+func envMap(m map[string]string) func(string) string {
+	return func(k string) string { return m[k] }
+}
+
+func writeConfig(t *testing.T, dir, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, config.FileName), []byte(content), 0600))
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("returns defaults when no config file exists", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.Mkdir(filepath.Join(dir, ".git"), 0750))
+		c, err := config.Load(dir)
+		require.NoError(t, err)
+		assert.Empty(t, c.Path)
+		assert.Equal(t, dir, c.Root)
+		assert.Equal(t, 30*time.Second, c.Runner.Timeout)
+		assert.Equal(t, int64(32*1024), c.Runner.MaxBytes)
+		assert.Equal(t, "_test.go", c.Tests.Suffix)
+		for _, name := range config.Agents {
+			assert.True(t, c.Enabled(name), name)
+		}
+	})
+
+	t.Run("finds the config at the repository root from a subdirectory", func(t *testing.T) {
+		root := t.TempDir()
+		require.NoError(t, os.Mkdir(filepath.Join(root, ".git"), 0750))
+		writeConfig(t, root, "runner:\n  timeout: 90s\n")
+		sub := filepath.Join(root, "internal", "agent")
+		require.NoError(t, os.MkdirAll(sub, 0750))
+
+		c, err := config.Load(sub)
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(root, config.FileName), c.Path)
+		assert.Equal(t, root, c.Root)
+		assert.Equal(t, 90*time.Second, c.Runner.Timeout)
+		assert.Equal(t, int64(32*1024), c.Runner.MaxBytes, "unset fields keep their defaults")
+	})
+
+	t.Run("names the file in errors", func(t *testing.T) {
+		dir := t.TempDir()
+		writeConfig(t, dir, "runner: [")
+		_, err := config.Load(dir)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), config.FileName)
+	})
+}
+
+func TestParse(t *testing.T) {
+	t.Run("accepts a full configuration", func(t *testing.T) {
+		c, err := config.Parse([]byte(`
+tier: haiku
+runner:
+  backend: api
+  timeout: 1m
+  max_bytes: 65536
+  concurrency: 2
+agents:
+  ux-design:
+    enabled: false
+  security:
+    tier: opus
+block:
+  verdict: warn
+  severity: high
+paths:
+  exclude: ["vendor/**"]
+tests:
+  suffix: _spec.go
+  conventions:
+    - paths: ["web/**"]
+      use: [typescript]
+  verify_red: true
+`))
+		require.NoError(t, err)
+		assert.Equal(t, "api", c.Runner.Backend)
+		assert.Equal(t, 2, c.Runner.Concurrency)
+		assert.False(t, c.Enabled("ux-design"))
+		assert.True(t, c.Enabled("security"))
+		assert.Equal(t, "foo_spec.go", c.Policy().TestFile("foo.go"))
+		assert.False(t, c.Policy().IsImpl("vendor/x/x.go"))
+		assert.True(t, c.Policy().IsImpl("web/app.ts"))
+		assert.False(t, c.Policy().IsImpl("web/tool.py"), "the web rule only allows typescript")
+		assert.True(t, c.Policy().VerifyRed)
+	})
+
+	t.Run("empty document yields defaults", func(t *testing.T) {
+		c, err := config.Parse(nil)
+		require.NoError(t, err)
+		assert.Equal(t, config.Default().Runner, c.Runner)
+	})
+
+	t.Run("rejects unknown keys", func(t *testing.T) {
+		_, err := config.Parse([]byte("runner:\n  timout: 10s\n"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "timout")
+	})
+
+	t.Run("rejects malformed YAML with its line number", func(t *testing.T) {
+		_, err := config.Parse([]byte("tier: sonnet\nagents: [\n"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "malformed config")
+		assert.Contains(t, err.Error(), "line")
+	})
+
+	t.Run("reports every schema violation at once", func(t *testing.T) {
+		_, err := config.Parse([]byte(`
+tier: turbo
+runner:
+  backend: grpc
+  timeout: -1s
+agents:
+  linter: {}
+  security:
+    model: gpt-4
+block:
+  verdict: pass
+paths:
+  include: ["[a-"]
+tests:
+  suffix: .test.ts
+  budget: 0s
+  conventions:
+    - use: [cobol]
+`))
+		require.Error(t, err)
+		for _, want := range []string{"tier", "runner.backend", "runner.timeout", "agents.linter", "agents.security.model", "block.verdict", "paths", "tests.suffix", "tests.budget", "tests.conventions[0].paths", "unknown convention \"cobol\""} {
+			assert.Contains(t, err.Error(), want)
+		}
+	})
+}
+
+func TestModel(t *testing.T) {
+	c, err := config.Parse([]byte(`
+tier: haiku
+agents:
+  security:
+    tier: opus
+  ux-design:
+    model: claude-sonnet-4-6
+`))
+	require.NoError(t, err)
+
+	t.Run("agent tier overrides the file tier", func(t *testing.T) {
+		model, err := c.Model("security", envMap(nil))
+		require.NoError(t, err)
+		assert.Equal(t, "claude-opus-4-6", model)
+	})
+
+	t.Run("agent model is used verbatim", func(t *testing.T) {
+		model, err := c.Model("ux-design", envMap(nil))
+		require.NoError(t, err)
+		assert.Equal(t, "claude-sonnet-4-6", model)
+	})
+
+	t.Run("file tier applies to agents without their own", func(t *testing.T) {
+		model, err := c.Model("software-engineering", envMap(nil))
+		require.NoError(t, err)
+		assert.Equal(t, "claude-haiku-4-5-20251001", model)
+	})
+
+	t.Run("ENSEMBLE_TIER overrides the file tier", func(t *testing.T) {
+		model, err := c.Model("software-engineering", envMap(map[string]string{"ENSEMBLE_TIER": "opus"}))
+		require.NoError(t, err)
+		assert.Equal(t, "claude-opus-4-6", model)
+	})
+
+	t.Run("per-agent environment override wins over everything", func(t *testing.T) {
+		model, err := c.Model("security", envMap(map[string]string{"ENSEMBLE_TIER_SECURITY": "haiku"}))
+		require.NoError(t, err)
+		assert.Equal(t, "claude-haiku-4-5-20251001", model)
+	})
+}
+
+func TestOrchestratorModel(t *testing.T) {
+	c, err := config.Parse([]byte("tier: haiku\nagents:\n  orchestrator:\n    tier: opus\n"))
+	require.NoError(t, err)
+	model, err := c.Model(config.Orchestrator, envMap(nil))
+	require.NoError(t, err)
+	assert.Equal(t, "claude-opus-4-6", model)
+	model, err = c.Model(config.Orchestrator, envMap(map[string]string{"ENSEMBLE_TIER_ORCHESTRATOR": "sonnet"}))
+	require.NoError(t, err)
+	assert.Equal(t, "claude-sonnet-4-6", model, "the environment still wins")
+
+	c, err = config.Parse([]byte("agents:\n  orchestrator:\n    model: claude-haiku-4-5-20251001\n"))
+	require.NoError(t, err)
+	model, err = c.Model(config.Orchestrator, envMap(nil))
+	require.NoError(t, err)
+	assert.Equal(t, "claude-haiku-4-5-20251001", model)
+	assert.NotContains(t, config.Agents, config.Orchestrator, "it is not a review agent")
+
+	_, err = config.Parse([]byte("agents:\n  orchestrator:\n    enabled: false\n    model: gpt-4\n"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "agents.orchestrator.enabled")
+	assert.Contains(t, err.Error(), "agents.orchestrator.model")
+}
+
+func TestSelectBackend(t *testing.T) {
+	r := config.Runner{Backend: "api"}
+	env := func(v string) func(string) string {
+		return func(k string) string {
+			if k == "ENSEMBLE_RUNNER" {
+				return v
+			}
+			return ""
+		}
+	}
+	b, err := r.SelectBackend(env(""))
+	require.NoError(t, err)
+	assert.Equal(t, "api", b)
+	b, err = r.SelectBackend(env("cli"))
+	require.NoError(t, err)
+	assert.Equal(t, "cli", b, "the environment overrides the file")
+	_, err = r.SelectBackend(env("clii"))
+	assert.EqualError(t, err, `ENSEMBLE_RUNNER: must be "cli" or "api", got "clii"`)
+}
+
+func TestFails(t *testing.T) {
+	block := agent.Finding{Verdict: agent.Block, Severity: agent.Medium}
+	warn := agent.Finding{Verdict: agent.Warn, Severity: agent.Critical}
+
+	t.Run("default threshold fails on any block", func(t *testing.T) {
+		c := config.Default()
+		assert.True(t, c.Fails(block))
+		assert.False(t, c.Fails(warn))
+	})
+
+	t.Run("severity threshold ignores low-severity blocks", func(t *testing.T) {
+		c := config.Default()
+		c.Block.Severity = agent.High
+		assert.False(t, c.Fails(block))
+	})
+
+	t.Run("verdict threshold can fail on warnings", func(t *testing.T) {
+		c := config.Default()
+		c.Block.Verdict = agent.Warn
+		assert.True(t, c.Fails(warn))
+	})
+}
@@ -0,0 +1,432 @@
+// Package diff parses unified diffs, including git's extended headers,
+// into files, hunks and numbered lines.
+package diff
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type Status string
+
+const (
+	Added    Status = "added"
+	Deleted  Status = "deleted"
+	Modified Status = "modified"
+	Renamed  Status = "renamed"
+	Copied   Status = "copied"
+)
+
+type LineKind byte
+
+const (
+	Context LineKind = ' '
+	Add     LineKind = '+'
+	Remove  LineKind = '-'
+)
+
+// Line is one line of a hunk. OldLine is zero for additions, NewLine for removals.
+type Line struct {
+	Kind    LineKind
+	Text    string
+	OldLine int
+	NewLine int
+}
+
+type Hunk struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Section  string
+	Lines    []Line
+}
+
+// File is one file section of a patch. Paths have their a/ and b/ prefixes
+// removed; a path is empty on the /dev/null side of an addition or deletion.
+type File struct {
+	OldPath    string
+	NewPath    string
+	Status     Status
+	OldMode    string
+	NewMode    string
+	Similarity int
+	Binary     bool
+	Hunks      []Hunk
+
+	raw []string
+}
+
+type Patch struct {
+	Files []File
+}
+
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@ ?(.*)$`)
+
+// Path is the file's path after the change, or before it for deletions.
+func (f File) Path() string {
+	if f.Status == Deleted {
+		return f.OldPath
+	}
+	return f.NewPath
+}
+
+func (f File) AddedLines() []Line {
+	return f.lines(Add)
+}
+
+func (f File) RemovedLines() []Line {
+	return f.lines(Remove)
+}
+
+func (f File) lines(kind LineKind) []Line {
+	var out []Line
+	for _, h := range f.Hunks {
+		for _, l := range h.Lines {
+			if l.Kind == kind {
+				out = append(out, l)
+			}
+		}
+	}
+	return out
+}
+
+// String renders the file section exactly as it appeared in the input.
+func (f File) String() string {
+	if len(f.raw) == 0 {
+		return ""
+	}
+	return strings.Join(f.raw, "\n") + "\n"
+}
+
+// String renders every file section in order.
+func (p Patch) String() string {
+	var b strings.Builder
+	for _, f := range p.Files {
+		b.WriteString(f.String())
+	}
+	return b.String()
+}
+
+// Filter returns a patch holding only the files keep accepts.
+func (p Patch) Filter(keep func(File) bool) Patch {
+	var out Patch
+	for _, f := range p.Files {
+		if keep(f) {
+			out.Files = append(out.Files, f)
+		}
+	}
+	return out
+}
+
+// Parse reads a unified diff. Text outside file sections, such as a commit
+// message, is ignored. Only malformed hunk headers are errors; a hunk that
+// ends early is kept as far as it goes.
+func Parse(raw string) (Patch, error) {
+	if raw == "" {
+		return Patch{}, nil
+	}
+	p := &parser{lines: strings.Split(strings.TrimSuffix(raw, "\n"), "\n")}
+	if err := p.parse(); err != nil {
+		return Patch{}, err
+	}
+	return Patch{Files: p.files}, nil
+}
+
+type parser struct {
+	lines []string
+	pos   int
+	files []File
+	cur   *File
+	// prefixed records whether the git header used a/ and b/ prefixes.
+	prefixed bool
+	inGit    bool
+	oldRaw   string
+}
+
+func (p *parser) parse() error {
+	for p.pos < len(p.lines) {
+		line := strings.TrimSuffix(p.lines[p.pos], "\r")
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			p.startGit(line)
+		case strings.HasPrefix(line, "--- ") && p.nextHasPrefix("+++ "):
+			if p.cur == nil || len(p.cur.Hunks) > 0 || !p.inGit {
+				p.start(line)
+				p.inGit = false
+			}
+			p.oldRaw = strings.TrimPrefix(line, "--- ")
+			p.add(line)
+		case strings.HasPrefix(line, "+++ ") && p.cur != nil:
+			p.add(line)
+			p.setPaths(p.oldRaw, strings.TrimPrefix(line, "+++ "))
+		case strings.HasPrefix(line, "@@ ") && p.cur != nil:
+			if err := p.hunk(line); err != nil {
+				return err
+			}
+			continue
+		case p.cur != nil && p.inGit && len(p.cur.Hunks) == 0:
+			p.extendedHeader(line)
+		}
+		p.pos++
+	}
+	p.finish()
+	return nil
+}
+
+func (p *parser) nextHasPrefix(prefix string) bool {
+	return p.pos+1 < len(p.lines) && strings.HasPrefix(p.lines[p.pos+1], prefix)
+}
+
+func (p *parser) start(line string) {
+	p.finish()
+	p.cur = &File{Status: Modified}
+	p.oldRaw = ""
+	p.add(line)
+}
+
+func (p *parser) startGit(line string) {
+	p.start(line)
+	p.inGit = true
+	a, b := splitGitPaths(strings.TrimPrefix(line, "diff --git "))
+	p.prefixed = strings.HasPrefix(a, "a/") && strings.HasPrefix(b, "b/")
+	p.cur.OldPath = p.strip(a, "a/")
+	p.cur.NewPath = p.strip(b, "b/")
+}
+
+func (p *parser) finish() {
+	if p.cur != nil {
+		p.files = append(p.files, *p.cur)
+		p.cur = nil
+	}
+}
+
+func (p *parser) add(line string) {
+	p.cur.raw = append(p.cur.raw, line)
+}
+
+func (p *parser) strip(path, prefix string) string {
+	if p.prefixed {
+		return strings.TrimPrefix(path, prefix)
+	}
+	return path
+}
+
+func (p *parser) extendedHeader(line string) {
+	p.add(line)
+	f := p.cur
+	switch {
+	case strings.HasPrefix(line, "old mode "):
+		f.OldMode = strings.TrimPrefix(line, "old mode ")
+	case strings.HasPrefix(line, "new mode "):
+		f.NewMode = strings.TrimPrefix(line, "new mode ")
+	case strings.HasPrefix(line, "deleted file mode "):
+		f.Status = Deleted
+		f.OldMode = strings.TrimPrefix(line, "deleted file mode ")
+		f.NewPath = ""
+	case strings.HasPrefix(line, "new file mode "):
+		f.Status = Added
+		f.NewMode = strings.TrimPrefix(line, "new file mode ")
+		f.OldPath = ""
+	case strings.HasPrefix(line, "rename from "):
+		f.Status = Renamed
+		f.OldPath = unquote(strings.TrimPrefix(line, "rename from "))
+	case strings.HasPrefix(line, "rename to "):
+		f.Status = Renamed
+		f.NewPath = unquote(strings.TrimPrefix(line, "rename to "))
+	case strings.HasPrefix(line, "copy from "):
+		f.Status = Copied
+		f.OldPath = unquote(strings.TrimPrefix(line, "copy from "))
+	case strings.HasPrefix(line, "copy to "):
+		f.Status = Copied
+		f.NewPath = unquote(strings.TrimPrefix(line, "copy to "))
+	case strings.HasPrefix(line, "similarity index "):
+		f.Similarity, _ = strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(line, "similarity index "), "%"))
+	case strings.HasPrefix(line, "Binary files ") || line == "GIT binary patch":
+		f.Binary = true
+	}
+}
+
+func (p *parser) setPaths(oldRaw, newRaw string) {
+	oldPath, newPath := headerPath(oldRaw), headerPath(newRaw)
+	if !p.inGit {
+		p.prefixed = strings.HasPrefix(oldPath, "a/") && strings.HasPrefix(newPath, "b/")
+	}
+	f := p.cur
+	switch {
+	case oldPath == "/dev/null":
+		f.Status = Added
+		f.OldPath = ""
+		f.NewPath = p.strip(newPath, "b/")
+	case newPath == "/dev/null":
+		f.Status = Deleted
+		f.OldPath = p.strip(oldPath, "a/")
+		f.NewPath = ""
+	default:
+		f.OldPath = p.strip(oldPath, "a/")
+		f.NewPath = p.strip(newPath, "b/")
+	}
+}
+
+func (p *parser) hunk(header string) error {
+	m := hunkHeader.FindStringSubmatch(header)
+	if m == nil {
+		return fmt.Errorf("diff: line %d: malformed hunk header %q", p.pos+1, header)
+	}
+	h := Hunk{
+		OldStart: atoi(m[1], 0),
+		OldLines: atoi(m[2], 1),
+		NewStart: atoi(m[3], 0),
+		NewLines: atoi(m[4], 1),
+		Section:  m[5],
+	}
+	p.add(p.lines[p.pos])
+	p.pos++
+	oldNo, newNo := h.OldStart, h.NewStart
+	remOld, remNew := h.OldLines, h.NewLines
+	for p.pos < len(p.lines) && (remOld > 0 || remNew > 0) {
+		raw := p.lines[p.pos]
+		line := strings.TrimSuffix(raw, "\r")
+		var l Line
+		switch {
+		case line == "" || line[0] == ' ':
+			if remOld == 0 || remNew == 0 {
+				break
+			}
+			l = Line{Kind: Context, OldLine: oldNo, NewLine: newNo}
+			oldNo, newNo, remOld, remNew = oldNo+1, newNo+1, remOld-1, remNew-1
+		case line[0] == '-' && remOld > 0:
+			l = Line{Kind: Remove, OldLine: oldNo}
+			oldNo, remOld = oldNo+1, remOld-1
+		case line[0] == '+' && remNew > 0:
+			l = Line{Kind: Add, NewLine: newNo}
+			newNo, remNew = newNo+1, remNew-1
+		case line[0] == '\\':
+			p.add(raw)
+			p.pos++
+			continue
+		}
+		if l.Kind == 0 {
+			break
+		}
+		if line != "" {
+			l.Text = line[1:]
+		}
+		h.Lines = append(h.Lines, l)
+		p.add(raw)
+		p.pos++
+	}
+	if p.pos < len(p.lines) && strings.HasPrefix(p.lines[p.pos], "\\") {
+		p.add(p.lines[p.pos])
+		p.pos++
+	}
+	p.cur.Hunks = append(p.cur.Hunks, h)
+	return nil
+}
+
+func atoi(s string, fallback int) int {
+	if s == "" {
+		return fallback
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// headerPath extracts the path from a ---/+++ line, dropping the tab git
+// appends to names with spaces and the timestamp other tools append.
+func headerPath(s string) string {
+	if strings.HasPrefix(s, `"`) {
+		return unquote(s)
+	}
+	if i := strings.IndexByte(s, '\t'); i >= 0 {
+		s = s[:i]
+	}
+	return s
+}
+
+// splitGitPaths splits the two paths of a "diff --git" line. Unquoted paths
+// may contain spaces, so the split that yields matching names is preferred.
+func splitGitPaths(s string) (string, string) {
+	if strings.HasPrefix(s, `"`) {
+		end := closingQuote(s)
+		return unquote(s[:end+1]), unquote(strings.TrimPrefix(s[end+1:], " "))
+	}
+	if i := strings.Index(s, ` "`); i >= 0 {
+		return s[:i], unquote(s[i+1:])
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] != ' ' {
+			continue
+		}
+		a, b := s[:i], s[i+1:]
+		if strings.TrimPrefix(a, "a/") == strings.TrimPrefix(b, "b/") {
+			return a, b
+		}
+	}
+	if i := strings.Index(s, " b/"); i >= 0 {
+		return s[:i], s[i+1:]
+	}
+	if i := strings.IndexByte(s, ' '); i >= 0 {
+		return s[:i], s[i+1:]
+	}
+	return s, s
+}
+
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return len(s) - 1
+}
+
+// unquote decodes git's C-style quoted paths, including octal byte escapes.
+func unquote(s string) string {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s
+	}
+	s = s[1 : len(s)-1]
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' || i+1 >= len(s) {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case 'a':
+			b.WriteByte('\a')
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'v':
+			b.WriteByte('\v')
+		case '0', '1', '2', '3':
+			if i+2 < len(s) {
+				if n, err := strconv.ParseUint(s[i:i+3], 8, 8); err == nil {
+					b.WriteByte(byte(n))
+					i += 2
+					continue
+				}
+			}
+			b.WriteByte(s[i])
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
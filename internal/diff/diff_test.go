@@ -0,0 +1,184 @@
+package diff_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gauthierbraillon/ensemble/internal/diff"
+)
+
+func parseFixture(t *testing.T, name string) diff.Patch {
+	t.Helper()
+	raw, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	p, err := diff.Parse(string(raw))
+	require.NoError(t, err)
+	return p
+}
+
+func fileByPath(t *testing.T, p diff.Patch, path string) diff.File {
+	t.Helper()
+	for _, f := range p.Files {
+		if f.Path() == path {
+			return f
+		}
+	}
+	t.Fatalf("no file %q in patch", path)
+	return diff.File{}
+}
+
+func TestParseGitDiff(t *testing.T) {
+	p := parseFixture(t, "git.diff")
+
+	t.Run("finds every file section", func(t *testing.T) {
+		var paths []string
+		for _, f := range p.Files {
+			paths = append(paths, f.Path())
+		}
+		assert.Equal(t, []string{"a.go", "b.bin", "del.go", "mode.sh", "new file.go", "new.go", "sp ace.go", "ünï.go"}, paths)
+	})
+
+	t.Run("numbers hunk lines on both sides", func(t *testing.T) {
+		f := fileByPath(t, p, "a.go")
+		assert.Equal(t, diff.Modified, f.Status)
+		require.Len(t, f.Hunks, 1)
+		h := f.Hunks[0]
+		assert.Equal(t, 1, h.OldStart)
+		assert.Equal(t, 3, h.OldLines)
+		assert.Equal(t, 1, h.NewStart)
+		assert.Equal(t, 4, h.NewLines)
+
+		added := f.AddedLines()
+		require.Len(t, added, 2)
+		assert.Equal(t, "func A() { return }", added[0].Text)
+		assert.Equal(t, 3, added[0].NewLine)
+		assert.Equal(t, 4, added[1].NewLine)
+		removed := f.RemovedLines()
+		require.Len(t, removed, 1)
+		assert.Equal(t, 3, removed[0].OldLine)
+	})
+
+	t.Run("recognises deletions", func(t *testing.T) {
+		f := fileByPath(t, p, "del.go")
+		assert.Equal(t, diff.Deleted, f.Status)
+		assert.Empty(t, f.NewPath)
+		assert.Equal(t, "100644", f.OldMode)
+		assert.Empty(t, f.AddedLines())
+	})
+
+	t.Run("recognises additions", func(t *testing.T) {
+		f := fileByPath(t, p, "new file.go")
+		assert.Equal(t, diff.Added, f.Status)
+		assert.Empty(t, f.OldPath)
+		assert.Equal(t, "100644", f.NewMode)
+	})
+
+	t.Run("recognises renames without content changes", func(t *testing.T) {
+		f := fileByPath(t, p, "new.go")
+		assert.Equal(t, diff.Renamed, f.Status)
+		assert.Equal(t, "old.go", f.OldPath)
+		assert.Equal(t, 100, f.Similarity)
+		assert.Empty(t, f.Hunks)
+	})
+
+	t.Run("records mode changes", func(t *testing.T) {
+		f := fileByPath(t, p, "mode.sh")
+		assert.Equal(t, "100644", f.OldMode)
+		assert.Equal(t, "100755", f.NewMode)
+	})
+
+	t.Run("flags binary files", func(t *testing.T) {
+		assert.True(t, fileByPath(t, p, "b.bin").Binary)
+	})
+
+	t.Run("strips the tab git appends to paths with spaces", func(t *testing.T) {
+		f := fileByPath(t, p, "sp ace.go")
+		assert.Equal(t, "sp ace.go", f.OldPath)
+	})
+
+	t.Run("decodes quoted paths", func(t *testing.T) {
+		f := fileByPath(t, p, "ünï.go")
+		assert.Equal(t, "ünï.go", f.OldPath)
+		require.Len(t, f.AddedLines(), 1)
+	})
+
+	t.Run("round-trips each file section", func(t *testing.T) {
+		raw, err := os.ReadFile(filepath.Join("testdata", "git.diff"))
+		require.NoError(t, err)
+		assert.Equal(t, string(raw), p.String())
+	})
+}
+
+func TestParseNoPrefixDiff(t *testing.T) {
+	p := parseFixture(t, "no-prefix.diff")
+	f := fileByPath(t, p, "a.go")
+	assert.Equal(t, "a.go", f.OldPath)
+	assert.Equal(t, diff.Deleted, fileByPath(t, p, "del.go").Status)
+	assert.Equal(t, "old.go", fileByPath(t, p, "new.go").OldPath)
+}
+
+func TestParseBinaryPatch(t *testing.T) {
+	p := parseFixture(t, "binary.diff")
+	require.Len(t, p.Files, 1)
+	assert.True(t, p.Files[0].Binary)
+	assert.Empty(t, p.Files[0].Hunks)
+}
+
+func TestParsePlainUnifiedDiff(t *testing.T) {
+	raw := "--- foo.go\t2024-01-01 00:00:00\n+++ foo.go\t2024-01-02 00:00:00\n@@ -1 +1 @@\n-a\n+b\n"
+	p, err := diff.Parse(raw)
+	require.NoError(t, err)
+	require.Len(t, p.Files, 1)
+	assert.Equal(t, "foo.go", p.Files[0].NewPath)
+	assert.Equal(t, "b", p.Files[0].AddedLines()[0].Text)
+}
+
+func TestParseLeniency(t *testing.T) {
+	t.Run("empty input has no files", func(t *testing.T) {
+		p, err := diff.Parse("")
+		require.NoError(t, err)
+		assert.Empty(t, p.Files)
+	})
+
+	t.Run("ignores text outside file sections", func(t *testing.T) {
+		p, err := diff.Parse("commit abc\n\n    message\n\ndiff --git a/x.go b/x.go\n--- a/x.go\n+++ b/x.go\n@@ -1 +1 @@\n-a\n+b\n")
+		require.NoError(t, err)
+		require.Len(t, p.Files, 1)
+		assert.Equal(t, "x.go", p.Files[0].Path())
+	})
+
+	t.Run("a hunk shorter than its header ends at the next file", func(t *testing.T) {
+		raw := "diff --git a/x.go b/x.go\n--- /dev/null\n+++ b/x.go\n@@ -0,0 +1,3 @@\n+package x\ndiff --git a/y.go b/y.go\n--- /dev/null\n+++ b/y.go\n@@ -0,0 +1 @@\n+package y\n"
+		p, err := diff.Parse(raw)
+		require.NoError(t, err)
+		require.Len(t, p.Files, 2)
+		assert.Len(t, p.Files[0].AddedLines(), 1)
+		assert.Equal(t, "y.go", p.Files[1].Path())
+	})
+
+	t.Run("keeps the no-newline marker out of the lines", func(t *testing.T) {
+		raw := "--- a/x\n+++ b/x\n@@ -1 +1 @@\n-a\n\\ No newline at end of file\n+b\n\\ No newline at end of file\n"
+		p, err := diff.Parse(raw)
+		require.NoError(t, err)
+		require.Len(t, p.Files, 1)
+		assert.Len(t, p.Files[0].Hunks[0].Lines, 2)
+	})
+
+	t.Run("rejects a malformed hunk header with its line number", func(t *testing.T) {
+		_, err := diff.Parse("--- a/x\n+++ b/x\n@@ nonsense @@\n")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "line 3")
+	})
+}
+
+func TestFilter(t *testing.T) {
+	p := parseFixture(t, "git.diff")
+	only := p.Filter(func(f diff.File) bool { return f.Path() == "a.go" })
+	require.Len(t, only.Files, 1)
+	assert.Contains(t, only.String(), "diff --git a/a.go b/a.go")
+	assert.NotContains(t, only.String(), "del.go")
+}
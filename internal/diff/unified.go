@@ -0,0 +1,130 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// context is the number of unchanged lines shown around each change.
+const context = 3
+
+// Unified renders the change from before to after as a unified diff of the
+// file at path, with three lines of context. It is empty when nothing
+// changed; an empty before reads as a new file.
+func Unified(path, before, after string) string {
+	lines := compare(splitLines(before), splitLines(after))
+	var hunks []Hunk
+	for start := 0; start < len(lines); {
+		first := nextChange(lines, start)
+		if first == len(lines) {
+			break
+		}
+		from := max(first-context, start)
+		end := first
+		for end < len(lines) {
+			next := nextChange(lines, end+1)
+			if next == len(lines) || next-end > 2*context {
+				break
+			}
+			end = next
+		}
+		to := min(end+context+1, len(lines))
+		hunks = append(hunks, hunkOf(lines[from:to], lines[:from]))
+		start = to
+	}
+	if len(hunks) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	if before == "" {
+		b.WriteString("--- /dev/null\n")
+	} else {
+		fmt.Fprintf(&b, "--- a/%s\n", path)
+	}
+	fmt.Fprintf(&b, "+++ b/%s\n", path)
+	for _, h := range hunks {
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", h.OldStart, h.OldLines, h.NewStart, h.NewLines)
+		for _, l := range h.Lines {
+			b.WriteString(string(l.Kind) + l.Text + "\n")
+		}
+	}
+	return b.String()
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// compare lines up a and b along their longest common subsequence.
+func compare(a, b []string) []Line {
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+	var lines []Line
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, Line{Kind: Context, Text: a[i], OldLine: i + 1, NewLine: j + 1})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && common[i+1][j] >= common[i][j+1]):
+			lines = append(lines, Line{Kind: Remove, Text: a[i], OldLine: i + 1})
+			i++
+		default:
+			lines = append(lines, Line{Kind: Add, Text: b[j], NewLine: j + 1})
+			j++
+		}
+	}
+	return lines
+}
+
+func nextChange(lines []Line, from int) int {
+	for i := from; i < len(lines); i++ {
+		if lines[i].Kind != Context {
+			return i
+		}
+	}
+	return len(lines)
+}
+
+// hunkOf numbers a hunk holding lines, which follow the lines before it.
+func hunkOf(lines, before []Line) Hunk {
+	h := Hunk{Lines: lines}
+	for _, l := range before {
+		if l.Kind != Add {
+			h.OldStart++
+		}
+		if l.Kind != Remove {
+			h.NewStart++
+		}
+	}
+	for _, l := range lines {
+		if l.Kind != Add {
+			h.OldLines++
+		}
+		if l.Kind != Remove {
+			h.NewLines++
+		}
+	}
+	if h.OldLines > 0 {
+		h.OldStart++
+	}
+	if h.NewLines > 0 {
+		h.NewStart++
+	}
+	return h
+}
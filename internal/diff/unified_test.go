@@ -0,0 +1,61 @@
+package diff_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gauthierbraillon/ensemble/internal/diff"
+)
+
+func TestUnified(t *testing.T) {
+	numbered := func(from, to int) []string {
+		var lines []string
+		for i := from; i <= to; i++ {
+			lines = append(lines, "line "+strings.Repeat("x", i))
+		}
+		return lines
+	}
+	join := func(lines []string) string { return strings.Join(lines, "\n") + "\n" }
+
+	t.Run("nothing changed", func(t *testing.T) {
+		assert.Empty(t, diff.Unified("a.json", "x\n", "x\n"))
+	})
+
+	t.Run("a change with context", func(t *testing.T) {
+		before := numbered(1, 10)
+		after := append(append(append([]string{}, before[:5]...), "inserted"), before[6:]...)
+		got := diff.Unified("a.json", join(before), join(after))
+		assert.Equal(t, "--- a/a.json\n+++ b/a.json\n@@ -3,7 +3,7 @@\n line xxx\n line xxxx\n line xxxxx\n-line xxxxxx\n+inserted\n line xxxxxxx\n line xxxxxxxx\n line xxxxxxxxx\n", got)
+	})
+
+	t.Run("distant changes get their own hunks", func(t *testing.T) {
+		before := numbered(1, 20)
+		after := append([]string{}, before...)
+		after[1], after[18] = "first", "last"
+		p, err := diff.Parse(diff.Unified("a.json", join(before), join(after)))
+		require.NoError(t, err)
+		require.Len(t, p.Files, 1)
+		require.Len(t, p.Files[0].Hunks, 2)
+		assert.Equal(t, []string{"first", "last"}, texts(p.Files[0].AddedLines()))
+		assert.Equal(t, 19, p.Files[0].AddedLines()[1].NewLine)
+	})
+
+	t.Run("a new file", func(t *testing.T) {
+		got := diff.Unified("a.json", "", "{}\n")
+		assert.Equal(t, "--- /dev/null\n+++ b/a.json\n@@ -0,0 +1,1 @@\n+{}\n", got)
+		p, err := diff.Parse(got)
+		require.NoError(t, err)
+		assert.Equal(t, diff.Added, p.Files[0].Status)
+	})
+}
+
+func texts(lines []diff.Line) []string {
+	var out []string
+	for _, l := range lines {
+		out = append(out, l.Text)
+	}
+	return out
+}
@@ -0,0 +1,252 @@
+// Package doctor diagnoses an ensemble installation: whether Claude Code
+// will call the hooks, whether the hooks will find what they need, and
+// whether the quality gates can run.
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gauthierbraillon/ensemble/internal/config"
+	"github.com/gauthierbraillon/ensemble/internal/initcmd"
+	"github.com/gauthierbraillon/ensemble/internal/pipeline"
+	"github.com/gauthierbraillon/ensemble/internal/runner"
+)
+
+// Status is the outcome of one check.
+type Status string
+
+const (
+	Pass Status = "pass"
+	Warn Status = "warn"
+	Fail Status = "fail"
+	// Skip marks a check with nothing to look at, such as a scope without a
+	// settings file.
+	Skip Status = "skip"
+)
+
+// Check is one line of the diagnosis.
+type Check struct {
+	Name   string `json:"check"`
+	Status Status `json:"status"`
+	Detail string `json:"detail"`
+}
+
+// Env locates the installation. Programs are looked up on PATH and
+// credentials read from the environment.
+type Env struct {
+	// Dir is the project directory and Home the user's home; an empty Home
+	// leaves the user scope out.
+	Dir  string
+	Home string
+	// Version is the running ensemble's version.
+	Version string
+}
+
+// versionTimeout bounds asking the ensemble on PATH for its version.
+const versionTimeout = 5 * time.Second
+
+// Run performs every check, in the order a broken installation is best
+// repaired.
+func Run(ctx context.Context, env Env) []Check {
+	cfg, cfgCheck := checkConfig(env)
+	checks := []Check{cfgCheck}
+	checks = append(checks, checkHooks(env)...)
+	checks = append(checks, checkBinary(ctx, env), checkRunner(cfg), checkAPIKey(cfg))
+	return append(checks, checkGates(env)...)
+}
+
+// Failed reports whether any check failed.
+func Failed(checks []Check) bool {
+	for _, c := range checks {
+		if c.Status == Fail {
+			return true
+		}
+	}
+	return false
+}
+
+func checkConfig(env Env) (config.Config, Check) {
+	c := Check{Name: "config", Status: Pass}
+	cfg, err := config.Load(env.Dir)
+	switch {
+	case err != nil:
+		c.Status, c.Detail = Fail, err.Error()
+		cfg = config.Default()
+	case cfg.Path == "":
+		c.Detail = "no " + config.FileName + "; using the defaults"
+	default:
+		c.Detail = cfg.Path
+	}
+	return cfg, c
+}
+
+// checkHooks reports the hooks found in each scope, then whether every hook
+// is registered exactly once across them.
+func checkHooks(env Env) []Check {
+	l := initcmd.Locations{Project: env.Dir, Home: env.Home}
+	where := map[string][]string{}
+	var checks []Check
+	for _, s := range initcmd.Scopes {
+		c := Check{Name: "hooks (" + string(s) + ")"}
+		found, err := initcmd.Find(l, s)
+		var narrow []string
+		for _, h := range found {
+			where[h.Event] = append(where[h.Event], string(s))
+			if h.Narrow() {
+				narrow = append(narrow, h.Event+" ("+h.Matcher+")")
+			}
+		}
+		switch {
+		case l.Path(s) == "":
+			c.Status, c.Detail = Skip, "no home directory"
+		case err != nil:
+			c.Status, c.Detail = Fail, firstLine(err.Error())
+		case len(found) == 0:
+			c.Status, c.Detail = Skip, "none in "+s.File()
+		case len(narrow) > 0:
+			c.Status, c.Detail = Warn, fmt.Sprintf("%s fire for fewer tools than they should; run ensemble init --scope %s to upgrade them", strings.Join(narrow, ", "), s)
+		case len(found) == len(initcmd.Hooks):
+			c.Status, c.Detail = Pass, fmt.Sprintf("%d of %d in %s", len(found), len(initcmd.Hooks), s.File())
+		default:
+			c.Status, c.Detail = Warn, fmt.Sprintf("%d of %d in %s", len(found), len(initcmd.Hooks), s.File())
+		}
+		checks = append(checks, c)
+	}
+
+	c := Check{Name: "hooks", Status: Pass, Detail: "every hook registered once"}
+	var missing, twice []string
+	for _, h := range initcmd.Hooks {
+		switch n := len(where[h.Event]); {
+		case n == 0:
+			missing = append(missing, h.Event)
+		case n > 1:
+			twice = append(twice, h.Event+" ("+strings.Join(where[h.Event], ", ")+")")
+		}
+	}
+	switch {
+	case len(missing) > 0:
+		c.Status, c.Detail = Fail, "not registered: "+strings.Join(missing, ", ")+"; run ensemble init"
+	case len(twice) > 0:
+		c.Status, c.Detail = Warn, "registered in more than one scope, so run twice: "+strings.Join(twice, ", ")
+	}
+	return append(checks, c)
+}
+
+// checkBinary finds the ensemble Claude Code will run and compares its
+// version with this one.
+func checkBinary(ctx context.Context, env Env) Check {
+	c := Check{Name: "ensemble on PATH"}
+	path, err := exec.LookPath("ensemble")
+	if err != nil {
+		c.Status, c.Detail = Fail, "not found; the hooks cannot run"
+		return c
+	}
+	ctx, cancel := context.WithTimeout(ctx, versionTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, path, "version").Output() // #nosec G204
+	version := strings.TrimSpace(string(out))
+	switch {
+	case err != nil:
+		c.Status, c.Detail = Warn, fmt.Sprintf("%s does not report its version: %v", path, err)
+	case version != env.Version:
+		c.Status, c.Detail = Warn, fmt.Sprintf("%s is version %s, this is %s", path, version, env.Version)
+	default:
+		c.Status, c.Detail = Pass, fmt.Sprintf("%s (%s)", path, version)
+	}
+	return c
+}
+
+// checkRunner resolves the runner the model reviews would use, as
+// ensemble cycle does.
+func checkRunner(cfg config.Config) Check {
+	backend := os.Getenv("ENSEMBLE_RUNNER")
+	if backend == "" {
+		backend = cfg.Runner.Backend
+	}
+	c := Check{Name: "runner"}
+	switch backend {
+	case "", runner.BackendCLI:
+		r, err := runner.New(runner.Config{Binary: cfg.Runner.Binary})
+		if err != nil {
+			c.Status, c.Detail = Fail, err.Error()+"; install it or set runner.backend: api"
+			return c
+		}
+		c.Status, c.Detail = Pass, "cli: "+r.Path()
+	case runner.BackendAPI:
+		base := os.Getenv("ANTHROPIC_BASE_URL")
+		if base == "" {
+			base = cfg.Runner.BaseURL
+		}
+		if base == "" {
+			base = runner.DefaultBaseURL
+		}
+		c.Status, c.Detail = Pass, "api: "+base
+	default:
+		c.Status, c.Detail = Fail, fmt.Sprintf("unknown runner backend %q", backend)
+	}
+	return c
+}
+
+// checkAPIKey fails without ANTHROPIC_API_KEY only when the api backend,
+// which cannot run without it, is selected; otherwise the deterministic
+// checks still run and a missing key only skips the model reviews.
+func checkAPIKey(cfg config.Config) Check {
+	c := Check{Name: "API key", Status: Pass, Detail: "ANTHROPIC_API_KEY is set"}
+	if os.Getenv("ANTHROPIC_API_KEY") != "" {
+		return c
+	}
+	c.Status, c.Detail = Warn, "ANTHROPIC_API_KEY is not set; the model reviews are skipped"
+	if backend, _ := cfg.Runner.SelectBackend(os.Getenv); backend == runner.BackendAPI {
+		c.Status, c.Detail = Fail, "ANTHROPIC_API_KEY is not set; the api runner cannot review"
+	}
+	return c
+}
+
+// checkGates finds the tools each quality gate's make target runs.
+func checkGates(env Env) []Check {
+	data, err := os.ReadFile(filepath.Join(env.Dir, "Makefile")) // #nosec G304
+	if err != nil {
+		return []Check{{Name: "gates", Status: Warn, Detail: "no Makefile; the quality gates are not wired up"}}
+	}
+	var checks []Check
+	m := Check{Name: "make", Status: Pass}
+	if path, err := exec.LookPath("make"); err != nil {
+		m.Status, m.Detail = Fail, "not found on PATH"
+	} else {
+		m.Detail = path
+	}
+	checks = append(checks, m)
+	for _, g := range pipeline.Gates() {
+		c := Check{Name: "gate: " + g.Name, Status: Pass}
+		tools, ok := pipeline.Tools(string(data), g.MakeTarget)
+		if !ok {
+			c.Status, c.Detail = Fail, "no "+g.MakeTarget+" target in the Makefile"
+			checks = append(checks, c)
+			continue
+		}
+		var missing []string
+		for _, t := range tools {
+			if _, err := exec.LookPath(t); err != nil {
+				missing = append(missing, t)
+			}
+		}
+		if len(missing) > 0 {
+			c.Status, c.Detail = Fail, "not found on PATH: "+strings.Join(missing, ", ")
+		} else {
+			c.Detail = "make " + g.MakeTarget + ": " + strings.Join(tools, ", ")
+		}
+		checks = append(checks, c)
+	}
+	return checks
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
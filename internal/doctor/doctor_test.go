@@ -0,0 +1,181 @@
+package doctor_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gauthierbraillon/ensemble/internal/doctor"
+	"github.com/gauthierbraillon/ensemble/internal/initcmd"
+)
+
+// fakePath puts scripts named tools on a PATH of their own; ensemble
+// reports version.
+func fakePath(t *testing.T, version string, tools ...string) {
+	t.Helper()
+	bin := t.TempDir()
+	for _, tool := range tools {
+		script := "#!/bin/sh\n"
+		if tool == "ensemble" {
+			script += "echo " + version + "\n"
+		}
+		require.NoError(t, os.WriteFile(filepath.Join(bin, tool), []byte(script), 0755))
+	}
+	t.Setenv("PATH", bin)
+}
+
+// narrowSettings registers every hook, PreToolUse as an older version did:
+// for Write and Edit only.
+const narrowSettings = `{"hooks":{
+	"PreToolUse":[{"matcher":"Write|Edit","hooks":[{"type":"command","command":"ensemble hook"}]}],
+	"PostToolUse":[{"matcher":"Write|Edit|MultiEdit|NotebookEdit|Bash","hooks":[{"type":"command","command":"ensemble hook --phase post"}]}],
+	"Stop":[{"hooks":[{"type":"command","command":"ensemble hook --phase stop"}]}],
+	"UserPromptSubmit":[{"hooks":[{"type":"command","command":"ensemble hook --phase prompt"}]}]}}`
+
+var gateTools = []string{"make", "golangci-lint", "go", "staticcheck", "gitleaks", "gosec", "govulncheck"}
+
+// healthy returns a project with every hook registered and every program
+// in place.
+func healthy(t *testing.T) doctor.Env {
+	t.Helper()
+	env := doctor.Env{Dir: t.TempDir(), Home: t.TempDir(), Version: "1.2.3"}
+	_, err := initcmd.WriteSettings(env.Dir)
+	require.NoError(t, err)
+	makefile, err := os.ReadFile("../../Makefile")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(env.Dir, "Makefile"), makefile, 0644))
+	fakePath(t, "1.2.3", append([]string{"ensemble", "claude"}, gateTools...)...)
+	t.Setenv("ANTHROPIC_API_KEY", "sk-test")
+	t.Setenv("ENSEMBLE_RUNNER", "")
+	return env
+}
+
+func check(t *testing.T, checks []doctor.Check, name string) doctor.Check {
+	t.Helper()
+	for _, c := range checks {
+		if c.Name == name {
+			return c
+		}
+	}
+	t.Fatalf("no %q check in %v", name, checks)
+	return doctor.Check{}
+}
+
+func TestRun(t *testing.T) {
+	t.Run("a healthy installation passes", func(t *testing.T) {
+		checks := doctor.Run(context.Background(), healthy(t))
+		assert.False(t, doctor.Failed(checks), "%v", checks)
+		assert.Equal(t, doctor.Pass, check(t, checks, "hooks (project)").Status)
+		assert.Equal(t, doctor.Skip, check(t, checks, "hooks (user)").Status)
+		assert.Equal(t, doctor.Pass, check(t, checks, "ensemble on PATH").Status)
+		assert.Contains(t, check(t, checks, "runner").Detail, "claude")
+		assert.Contains(t, check(t, checks, "config").Detail, "defaults")
+		assert.Equal(t, "make lint: golangci-lint", check(t, checks, "gate: Lint").Detail)
+	})
+
+	t.Run("hooks registered nowhere fail", func(t *testing.T) {
+		env := healthy(t)
+		_, err := initcmd.RemoveSettings(env.Dir)
+		require.NoError(t, err)
+		c := check(t, doctor.Run(context.Background(), env), "hooks")
+		assert.Equal(t, doctor.Fail, c.Status)
+		assert.Contains(t, c.Detail, "PreToolUse")
+	})
+
+	t.Run("hooks registered twice warn", func(t *testing.T) {
+		env := healthy(t)
+		elsewhere := initcmd.Locations{Project: t.TempDir(), Home: env.Home}
+		c, _, err := initcmd.PlanInstall(elsewhere, initcmd.User)
+		require.NoError(t, err)
+		require.NoError(t, c.Apply())
+
+		got := check(t, doctor.Run(context.Background(), env), "hooks")
+		assert.Equal(t, doctor.Warn, got.Status)
+		assert.Contains(t, got.Detail, "PreToolUse (user, project)")
+	})
+
+	t.Run("hooks left by an older version warn", func(t *testing.T) {
+		env := healthy(t)
+		_, err := initcmd.RemoveSettings(env.Dir)
+		require.NoError(t, err)
+		user := filepath.Join(env.Home, ".claude", "settings.json")
+		require.NoError(t, os.MkdirAll(filepath.Dir(user), 0755))
+		require.NoError(t, os.WriteFile(user, []byte(narrowSettings), 0644))
+
+		c := check(t, doctor.Run(context.Background(), env), "hooks (user)")
+		assert.Equal(t, doctor.Warn, c.Status)
+		assert.Contains(t, c.Detail, "PreToolUse (Write|Edit)")
+		assert.Contains(t, c.Detail, "ensemble init --scope user")
+	})
+
+	t.Run("blank settings have no hooks", func(t *testing.T) {
+		env := healthy(t)
+		require.NoError(t, os.WriteFile(filepath.Join(env.Dir, ".claude", "settings.local.json"), []byte("\n"), 0644))
+		checks := doctor.Run(context.Background(), env)
+		assert.Equal(t, doctor.Skip, check(t, checks, "hooks (local)").Status)
+		assert.Equal(t, doctor.Pass, check(t, checks, "hooks").Status)
+	})
+
+	t.Run("invalid settings fail their scope", func(t *testing.T) {
+		env := healthy(t)
+		require.NoError(t, os.WriteFile(filepath.Join(env.Dir, ".claude", "settings.local.json"), []byte("{"), 0644))
+		assert.Equal(t, doctor.Fail, check(t, doctor.Run(context.Background(), env), "hooks (local)").Status)
+	})
+
+	t.Run("a different ensemble on PATH warns", func(t *testing.T) {
+		env := healthy(t)
+		env.Version = "2.0.0"
+		c := check(t, doctor.Run(context.Background(), env), "ensemble on PATH")
+		assert.Equal(t, doctor.Warn, c.Status)
+		assert.Contains(t, c.Detail, "is version 1.2.3, this is 2.0.0")
+	})
+
+	t.Run("missing programs and credentials fail", func(t *testing.T) {
+		env := healthy(t)
+		fakePath(t, "", "make", "go")
+		t.Setenv("ANTHROPIC_API_KEY", "")
+		checks := doctor.Run(context.Background(), env)
+		assert.True(t, doctor.Failed(checks))
+		assert.Equal(t, doctor.Fail, check(t, checks, "ensemble on PATH").Status)
+		assert.Equal(t, doctor.Fail, check(t, checks, "runner").Status)
+		assert.Equal(t, doctor.Warn, check(t, checks, "API key").Status, "the cli runner still runs the deterministic checks")
+		assert.Equal(t, "not found on PATH: golangci-lint", check(t, checks, "gate: Lint").Detail)
+		assert.Equal(t, doctor.Pass, check(t, checks, "gate: Build").Status)
+	})
+
+	t.Run("a missing API key fails only the api runner", func(t *testing.T) {
+		for backend, want := range map[string]doctor.Status{"": doctor.Warn, "cli": doctor.Warn, "api": doctor.Fail} {
+			env := healthy(t)
+			t.Setenv("ANTHROPIC_API_KEY", "")
+			t.Setenv("ENSEMBLE_RUNNER", backend)
+			checks := doctor.Run(context.Background(), env)
+			assert.Equal(t, want, check(t, checks, "API key").Status, "backend %q", backend)
+			assert.Equal(t, want == doctor.Fail, doctor.Failed(checks), "backend %q", backend)
+		}
+	})
+
+	t.Run("the api runner needs no CLI", func(t *testing.T) {
+		env := healthy(t)
+		fakePath(t, "1.2.3", "ensemble")
+		t.Setenv("ENSEMBLE_RUNNER", "api")
+		c := check(t, doctor.Run(context.Background(), env), "runner")
+		assert.Equal(t, doctor.Pass, c.Status)
+		assert.Contains(t, c.Detail, "api")
+	})
+
+	t.Run("broken config fails", func(t *testing.T) {
+		env := healthy(t)
+		require.NoError(t, os.WriteFile(filepath.Join(env.Dir, ".ensemble.yaml"), []byte("tier: ["), 0644))
+		assert.Equal(t, doctor.Fail, check(t, doctor.Run(context.Background(), env), "config").Status)
+	})
+
+	t.Run("no Makefile warns", func(t *testing.T) {
+		env := healthy(t)
+		require.NoError(t, os.Remove(filepath.Join(env.Dir, "Makefile")))
+		assert.Equal(t, doctor.Warn, check(t, doctor.Run(context.Background(), env), "gates").Status)
+	})
+}
@@ -0,0 +1,215 @@
+// Package git runs the git binary to produce the diffs and file trees a
+// cycle reviews.
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// emptyTree is the object name git gives a tree with no entries; diffing
+// against it shows every file in a repository without commits as added.
+const emptyTree = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+// diffArgs pin the output format so user settings such as diff.noprefix,
+// color.ui or an external diff driver cannot change what the parser sees.
+var diffArgs = []string{"diff", "--no-color", "--no-ext-diff", "--find-renames", "--src-prefix=a/", "--dst-prefix=b/"}
+
+// Repo is a git working tree rooted at Root.
+type Repo struct {
+	Root string
+}
+
+// Open finds the repository containing dir.
+func Open(ctx context.Context, dir string) (Repo, error) {
+	out, err := run(ctx, dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return Repo{}, err
+	}
+	return Repo{Root: strings.TrimSpace(out)}, nil
+}
+
+// Tree reads files as they stand at one revision, in the index, or in the
+// working tree.
+type Tree struct {
+	repo Repo
+	// spec prefixes the path in a "git cat-file" object name: "<rev>:" for
+	// a revision, ":" for the index. Empty means the working tree.
+	spec string
+}
+
+// ReadFile returns the contents of path, relative to the repository root.
+func (t Tree) ReadFile(path string) ([]byte, error) {
+	if t.spec == "" {
+		return os.ReadFile(filepath.Join(t.repo.Root, filepath.FromSlash(path)))
+	}
+	out, err := run(context.Background(), t.repo.Root, "cat-file", "blob", t.spec+path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, os.ErrNotExist)
+	}
+	return []byte(out), nil
+}
+
+// At returns the tree of files as they stand at rev.
+func (r Repo) At(rev string) Tree {
+	return Tree{repo: r, spec: rev + ":"}
+}
+
+// Range diffs a revision range: "A..B" compares A with B, "A...B" compares
+// the merge base of A and B with B, and a single revision compares it with
+// HEAD. Files are read at the range's end.
+func (r Repo) Range(ctx context.Context, spec string) (string, Tree, error) {
+	if spec == "" || strings.HasPrefix(spec, "-") {
+		return "", Tree{}, fmt.Errorf("git: invalid revision range %q", spec)
+	}
+	from, to, merge := spec, "HEAD", false
+	if i := strings.Index(spec, "..."); i >= 0 {
+		from, to, merge = orHEAD(spec[:i]), orHEAD(spec[i+3:]), true
+	} else if i := strings.Index(spec, ".."); i >= 0 {
+		from, to = orHEAD(spec[:i]), orHEAD(spec[i+2:])
+	}
+	// A merge base needs commits; otherwise either end may be a tree, such
+	// as the empty tree a pre-push hook diffs a new branch's root from.
+	kind := "tree"
+	if merge {
+		kind = "commit"
+	}
+	from, err := r.resolve(ctx, from, kind)
+	if err != nil {
+		return "", Tree{}, err
+	}
+	if to, err = r.resolve(ctx, to, kind); err != nil {
+		return "", Tree{}, err
+	}
+	if merge {
+		if from, err = r.MergeBase(ctx, from, to); err != nil {
+			return "", Tree{}, err
+		}
+	}
+	out, err := r.diff(ctx, from, to)
+	if err != nil {
+		return "", Tree{}, err
+	}
+	return out, Tree{repo: r, spec: to + ":"}, nil
+}
+
+// Base diffs HEAD against its merge base with base, the changes a branch
+// would bring into base.
+func (r Repo) Base(ctx context.Context, base string) (string, Tree, error) {
+	return r.Range(ctx, base+"...HEAD")
+}
+
+// Staged diffs the index against HEAD; files are read from the index.
+func (r Repo) Staged(ctx context.Context) (string, Tree, error) {
+	out, err := r.diff(ctx, "--cached", r.head(ctx))
+	if err != nil {
+		return "", Tree{}, err
+	}
+	return out, Tree{repo: r, spec: ":"}, nil
+}
+
+// Worktree diffs the working tree, untracked files included, against HEAD;
+// files are read from disk.
+func (r Repo) Worktree(ctx context.Context) (string, Tree, error) {
+	out, err := r.diff(ctx, r.head(ctx))
+	if err != nil {
+		return "", Tree{}, err
+	}
+	untracked, err := run(ctx, r.Root, "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return "", Tree{}, err
+	}
+	var b strings.Builder
+	b.WriteString(out)
+	for _, path := range strings.Split(strings.TrimSuffix(untracked, "\x00"), "\x00") {
+		if path == "" {
+			continue
+		}
+		// --no-index exits 1 whenever the files differ, which they always do.
+		added, err := r.diff(ctx, "--no-index", "--", os.DevNull, path)
+		var exit *exec.ExitError
+		if err != nil && !(errors.As(err, &exit) && exit.ExitCode() == 1) {
+			return "", Tree{}, err
+		}
+		b.WriteString(added)
+	}
+	return b.String(), Tree{repo: r}, nil
+}
+
+// MergeBase returns the best common ancestor of a and b.
+func (r Repo) MergeBase(ctx context.Context, a, b string) (string, error) {
+	out, err := run(ctx, r.Root, "merge-base", "--end-of-options", a, b)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// HooksDir returns the directory git runs hooks from, core.hooksPath
+// included.
+func (r Repo) HooksDir(ctx context.Context) (string, error) {
+	out, err := run(ctx, r.Root, "rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", err
+	}
+	dir := strings.TrimSpace(out)
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(r.Root, dir)
+	}
+	return dir, nil
+}
+
+// resolve names the object of kind, "commit" or "tree", rev points to. Only
+// the resolved object name is passed on, so no part of a range can reach git
+// as an option.
+func (r Repo) resolve(ctx context.Context, rev, kind string) (string, error) {
+	out, err := run(ctx, r.Root, "rev-parse", "--verify", "--quiet", "--end-of-options", rev+"^{"+kind+"}")
+	if err != nil || strings.HasPrefix(rev, "-") {
+		return "", fmt.Errorf("git: unknown revision %q", rev)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// head names HEAD, or the empty tree before the first commit.
+func (r Repo) head(ctx context.Context) string {
+	if _, err := run(ctx, r.Root, "rev-parse", "--verify", "--quiet", "HEAD"); err != nil {
+		return emptyTree
+	}
+	return "HEAD"
+}
+
+func (r Repo) diff(ctx context.Context, args ...string) (string, error) {
+	return run(ctx, r.Root, append(append([]string{}, diffArgs...), args...)...)
+}
+
+func orHEAD(rev string) string {
+	if rev == "" {
+		return "HEAD"
+	}
+	return rev
+}
+
+// run returns stdout. On failure the error carries git's stderr and wraps
+// the *exec.ExitError, so callers can still inspect the exit code; stdout
+// is returned either way.
+func run(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = "git " + args[0] + " failed"
+		}
+		return stdout.String(), fmt.Errorf("git: %s: %w", msg, err)
+	}
+	return stdout.String(), nil
+}
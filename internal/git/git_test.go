@@ -0,0 +1,233 @@
+package git_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gauthierbraillon/ensemble/internal/diff"
+	"github.com/gauthierbraillon/ensemble/internal/git"
+)
+
+func gitRun(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "commit.gpgsign=false"}, args...)...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, "git %v: %s", args, out)
+}
+
+func writeFile(t *testing.T, dir, path, content string) {
+	t.Helper()
+	full := filepath.Join(dir, path)
+	require.NoError(t, os.MkdirAll(filepath.Dir(full), 0750))
+	require.NoError(t, os.WriteFile(full, []byte(content), 0600))
+}
+
+func commit(t *testing.T, dir, msg string) {
+	t.Helper()
+	gitRun(t, dir, "add", "-A")
+	gitRun(t, dir, "commit", "-q", "-m", msg)
+}
+
+// newRepo returns a repository on branch main with one commit holding a.go.
+func newRepo(t *testing.T) git.Repo {
+	t.Helper()
+	dir := t.TempDir()
+	gitRun(t, dir, "init", "-q", "-b", "main")
+	gitRun(t, dir, "config", "diff.noprefix", "true")
+	writeFile(t, dir, "a.go", "package a\n")
+	commit(t, dir, "initial")
+	repo, err := git.Open(context.Background(), dir)
+	require.NoError(t, err)
+	return repo
+}
+
+func paths(t *testing.T, raw string) []string {
+	t.Helper()
+	p, err := diff.Parse(raw)
+	require.NoError(t, err)
+	var out []string
+	for _, f := range p.Files {
+		out = append(out, f.Path())
+	}
+	return out
+}
+
+func TestOpen(t *testing.T) {
+	t.Run("finds the root from a subdirectory", func(t *testing.T) {
+		repo := newRepo(t)
+		sub := filepath.Join(repo.Root, "sub")
+		require.NoError(t, os.Mkdir(sub, 0750))
+		got, err := git.Open(context.Background(), sub)
+		require.NoError(t, err)
+		assert.Equal(t, repo.Root, got.Root)
+	})
+
+	t.Run("fails outside a repository", func(t *testing.T) {
+		_, err := git.Open(context.Background(), t.TempDir())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "git:")
+	})
+}
+
+func TestRange(t *testing.T) {
+	ctx := context.Background()
+	repo := newRepo(t)
+	writeFile(t, repo.Root, "b.go", "package a\n\nfunc B() {}\n")
+	commit(t, repo.Root, "add b")
+
+	t.Run("diffs two revisions with prefixes despite diff.noprefix", func(t *testing.T) {
+		raw, tree, err := repo.Range(ctx, "HEAD~1..HEAD")
+		require.NoError(t, err)
+		assert.Contains(t, raw, "+++ b/b.go")
+		content, err := tree.ReadFile("b.go")
+		require.NoError(t, err)
+		assert.Contains(t, string(content), "func B()")
+	})
+
+	t.Run("a single revision is compared with HEAD", func(t *testing.T) {
+		raw, _, err := repo.Range(ctx, "HEAD~1")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"b.go"}, paths(t, raw))
+	})
+
+	t.Run("reads files at the end of the range", func(t *testing.T) {
+		_, tree, err := repo.Range(ctx, "HEAD~1..HEAD~1")
+		require.NoError(t, err)
+		_, err = tree.ReadFile("b.go")
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("rejects option-like revisions", func(t *testing.T) {
+		out := filepath.Join(t.TempDir(), "x")
+		for _, spec := range []string{"--output=" + out, "HEAD..--output=" + out, "HEAD~1...--output=" + out, "--output=" + out + "..HEAD"} {
+			_, _, err := repo.Range(ctx, spec)
+			require.Error(t, err, spec)
+		}
+		assert.NoFileExists(t, out)
+	})
+
+	t.Run("reports unknown revisions", func(t *testing.T) {
+		_, _, err := repo.Range(ctx, "nope..HEAD")
+		require.Error(t, err)
+	})
+}
+
+func TestBase(t *testing.T) {
+	ctx := context.Background()
+	repo := newRepo(t)
+	gitRun(t, repo.Root, "checkout", "-q", "-b", "feature")
+	writeFile(t, repo.Root, "feature.go", "package a\n")
+	commit(t, repo.Root, "feature work")
+	gitRun(t, repo.Root, "checkout", "-q", "main")
+	writeFile(t, repo.Root, "main.go", "package a\n")
+	commit(t, repo.Root, "main moves on")
+	gitRun(t, repo.Root, "checkout", "-q", "feature")
+
+	raw, _, err := repo.Base(ctx, "main")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"feature.go"}, paths(t, raw), "changes on main since the fork are not the branch's")
+
+	out := filepath.Join(t.TempDir(), "x")
+	for _, base := range []string{"--output=" + out, "main..--output=" + out} {
+		_, _, err := repo.Base(ctx, base)
+		assert.Error(t, err, base)
+	}
+	assert.NoFileExists(t, out)
+}
+
+func TestStaged(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("diffs the index and reads staged content", func(t *testing.T) {
+		repo := newRepo(t)
+		writeFile(t, repo.Root, "a.go", "package a\n\nvar staged = 1\n")
+		gitRun(t, repo.Root, "add", "a.go")
+		writeFile(t, repo.Root, "a.go", "package a\n\nvar unstaged = 1\n")
+
+		raw, tree, err := repo.Staged(ctx)
+		require.NoError(t, err)
+		assert.Contains(t, raw, "+var staged = 1")
+		content, err := tree.ReadFile("a.go")
+		require.NoError(t, err)
+		assert.Contains(t, string(content), "staged = 1")
+		assert.NotContains(t, string(content), "unstaged")
+	})
+
+	t.Run("works before the first commit", func(t *testing.T) {
+		dir := t.TempDir()
+		gitRun(t, dir, "init", "-q")
+		writeFile(t, dir, "a.go", "package a\n")
+		gitRun(t, dir, "add", "a.go")
+		repo, err := git.Open(ctx, dir)
+		require.NoError(t, err)
+
+		raw, _, err := repo.Staged(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a.go"}, paths(t, raw))
+	})
+}
+
+func TestWorktree(t *testing.T) {
+	ctx := context.Background()
+	repo := newRepo(t)
+	writeFile(t, repo.Root, "a.go", "package a\n\nvar changed = 1\n")
+	writeFile(t, repo.Root, "new dir/new.go", "package a\n")
+	writeFile(t, repo.Root, ".gitignore", "ignored.go\n")
+	writeFile(t, repo.Root, "ignored.go", "package a\n")
+
+	raw, tree, err := repo.Worktree(ctx)
+	require.NoError(t, err)
+
+	p, err := diff.Parse(raw)
+	require.NoError(t, err)
+	got := map[string]diff.Status{}
+	for _, f := range p.Files {
+		got[f.Path()] = f.Status
+	}
+	assert.Equal(t, map[string]diff.Status{
+		"a.go":           diff.Modified,
+		".gitignore":     diff.Added,
+		"new dir/new.go": diff.Added,
+	}, got)
+
+	content, err := tree.ReadFile("new dir/new.go")
+	require.NoError(t, err)
+	assert.Equal(t, "package a\n", string(content))
+}
+
+func TestAt(t *testing.T) {
+	repo := newRepo(t)
+	writeFile(t, repo.Root, "a.go", "package a // edited\n")
+
+	got, err := repo.At("HEAD").ReadFile("a.go")
+	require.NoError(t, err)
+	assert.Equal(t, "package a\n", string(got), "reads the committed version, not the working tree")
+
+	_, err = repo.At("HEAD").ReadFile("missing.go")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestHooksDir(t *testing.T) {
+	ctx := context.Background()
+	t.Run("defaults to .git/hooks", func(t *testing.T) {
+		repo := newRepo(t)
+		dir, err := repo.HooksDir(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(repo.Root, ".git", "hooks"), dir)
+	})
+
+	t.Run("honours core.hooksPath", func(t *testing.T) {
+		repo := newRepo(t)
+		gitRun(t, repo.Root, "config", "core.hooksPath", ".husky/_")
+		dir, err := repo.HooksDir(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(repo.Root, ".husky", "_"), dir)
+	})
+}
@@ -0,0 +1,73 @@
+// Package glob matches slash-separated paths against patterns.
+// It extends path.Match with "**", which matches zero or more whole path segments.
+package glob
+
+import (
+	"path"
+	"strings"
+)
+
+// Match reports whether name matches pattern. Both are slash-separated.
+func Match(pattern, name string) (bool, error) {
+	return matchSegments(split(pattern), split(name))
+}
+
+// Validate reports a malformed pattern without matching anything.
+func Validate(pattern string) error {
+	_, err := Match(pattern, "")
+	return err
+}
+
+// Any reports whether name matches at least one of patterns. Malformed patterns never match.
+func Any(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func split(p string) []string {
+	p = strings.Trim(path.Clean("/"+p), "/")
+	if p == "" {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
+
+func matchSegments(pattern, name []string) (bool, error) {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			for i := 0; i <= len(name); i++ {
+				ok, err := matchSegments(rest, name[i:])
+				if ok || err != nil {
+					return ok, err
+				}
+			}
+			return false, nil
+		}
+		if len(name) == 0 {
+			return false, validateRest(pattern)
+		}
+		ok, err := path.Match(pattern[0], name[0])
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			return false, validateRest(pattern[1:])
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0, nil
+}
+
+func validateRest(pattern []string) error {
+	for _, p := range pattern {
+		if _, err := path.Match(p, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
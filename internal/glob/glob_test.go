@@ -0,0 +1,45 @@
+package glob_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gauthierbraillon/ensemble/internal/glob"
+)
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"*.go", "foo.go", true},
+		{"*.go", "internal/foo.go", false},
+		{"**/*.go", "foo.go", true},
+		{"**/*.go", "internal/agent/foo.go", true},
+		{"vendor/**", "vendor/github.com/x/y.go", true},
+		{"vendor/**", "internal/vendor/y.go", false},
+		{"**/testdata/**", "internal/agent/testdata/fixture.go", true},
+		{"internal/*/foo.go", "internal/agent/foo.go", true},
+		{"internal/*/foo.go", "internal/agent/sub/foo.go", false},
+		{"cmd", "cmd", true},
+		{"./cmd/*.go", "cmd/root.go", true},
+	}
+	for _, c := range cases {
+		got, err := glob.Match(c.pattern, c.name)
+		require.NoError(t, err, c.pattern)
+		assert.Equal(t, c.want, got, "%s ~ %s", c.pattern, c.name)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, glob.Validate("**/*.go"))
+	assert.Error(t, glob.Validate("internal/[a-"))
+}
+
+func TestAny(t *testing.T) {
+	assert.True(t, glob.Any([]string{"vendor/**", "**/*.pb.go"}, "api/v1/user.pb.go"))
+	assert.False(t, glob.Any([]string{"vendor/**"}, "cmd/root.go"))
+	assert.False(t, glob.Any(nil, "cmd/root.go"))
+}
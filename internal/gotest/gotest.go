@@ -0,0 +1,166 @@
+// Package gotest finds the test functions a change touches and runs them
+// with the go tool.
+package gotest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os/exec"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ChangedTests lists, in source order, the top-level Test functions in after
+// that before lacks or that differ from their version in before. An empty
+// before means the whole file is new.
+func ChangedTests(before, after []byte) ([]string, error) {
+	old := map[string]string{}
+	if len(before) > 0 {
+		var err error
+		if old, _, err = testFuncs(before); err != nil {
+			return nil, err
+		}
+	}
+	cur, order, err := testFuncs(after)
+	if err != nil {
+		return nil, err
+	}
+	var changed []string
+	for _, name := range order {
+		if body, ok := old[name]; !ok || body != cur[name] {
+			changed = append(changed, name)
+		}
+	}
+	return changed, nil
+}
+
+// testFuncs returns the source of each Test function, keyed by name, and the
+// names in source order.
+func testFuncs(src []byte) (map[string]string, []string, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, nil, err
+	}
+	bodies := map[string]string{}
+	var order []string
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv != nil || !isTestName(fn.Name.Name) {
+			continue
+		}
+		start, end := fset.Position(fn.Pos()).Offset, fset.Position(fn.End()).Offset
+		bodies[fn.Name.Name] = string(src[start:end])
+		order = append(order, fn.Name.Name)
+	}
+	return bodies, order, nil
+}
+
+// isTestName follows the go tool: Test followed by nothing or by a
+// character that is not a lower-case letter.
+func isTestName(name string) bool {
+	rest, ok := strings.CutPrefix(name, "Test")
+	if !ok {
+		return false
+	}
+	if rest == "" {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(rest)
+	return !unicode.IsLower(r)
+}
+
+// RunPattern anchors a -run pattern to exactly the named top-level tests.
+func RunPattern(names []string) string {
+	quoted := make([]string, len(names))
+	for i, n := range names {
+		quoted[i] = regexp.QuoteMeta(n)
+	}
+	return "^(" + strings.Join(quoted, "|") + ")$"
+}
+
+// Result is the outcome of a go test run.
+type Result struct {
+	// Passed is false when a test failed or the package did not build.
+	Passed bool
+	Output string
+}
+
+// maxExcerpt caps the failure excerpt handed back to the model.
+const maxExcerpt = 4 * 1024
+
+// Failures returns the output with the noise of passing tests removed,
+// keeping its end when it is still too long to hand to a model.
+func (r Result) Failures() string {
+	var b strings.Builder
+	for _, line := range strings.Split(strings.TrimSpace(r.Output), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "=== ") || strings.HasPrefix(trimmed, "--- PASS") ||
+			trimmed == "PASS" || strings.HasPrefix(trimmed, "ok ") || strings.HasPrefix(trimmed, "ok\t") {
+			continue
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	out := b.String()
+	if len(out) > maxExcerpt {
+		out = "..." + out[len(out)-maxExcerpt:]
+	}
+	return out
+}
+
+// ErrNotRun means go test could not get as far as building the tests, for
+// instance outside a module.
+var ErrNotRun = errors.New("go test did not run")
+
+// Run runs go test in dir for pkgs, restricted to run when it is not empty.
+// Failing tests and build failures are results, not errors.
+func Run(ctx context.Context, dir, run string, pkgs ...string) (Result, error) {
+	args := []string{"test", "-count=1"}
+	if run != "" {
+		args = append(args, "-run", run)
+	}
+	cmd := exec.CommandContext(ctx, "go", append(args, pkgs...)...) // #nosec G204
+	cmd.Dir = dir
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+	res := Result{Passed: err == nil, Output: out.String()}
+	if err == nil {
+		return res, nil
+	}
+	if ctx.Err() != nil {
+		return res, ctx.Err()
+	}
+	var exit *exec.ExitError
+	if !errors.As(err, &exit) || !failed(res.Output) {
+		return res, fmt.Errorf("%w: %s", ErrNotRun, firstLine(res.Output, err))
+	}
+	return res, nil
+}
+
+// failed reports whether output records a test or build failure, as
+// opposed to the go tool giving up before it could build anything.
+func failed(output string) bool {
+	for _, line := range strings.Split(output, "\n") {
+		if line == "FAIL" || strings.HasPrefix(line, "FAIL\t") || strings.HasPrefix(line, "--- FAIL") {
+			return true
+		}
+	}
+	return false
+}
+
+func firstLine(output string, err error) string {
+	if line, _, _ := strings.Cut(strings.TrimSpace(output), "\n"); line != "" {
+		return line
+	}
+	return err.Error()
+}
@@ -0,0 +1,122 @@
+package gotest_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gauthierbraillon/ensemble/internal/gotest"
+)
+
+const committed = `package foo
+
+import "testing"
+
+func TestKept(t *testing.T) { t.Log("same") }
+
+func TestEdited(t *testing.T) { t.Log("before") }
+`
+
+func TestChangedTests(t *testing.T) {
+	t.Run("new and edited tests, in source order", func(t *testing.T) {
+		after := committed + `
+func TestAdded(t *testing.T) {}
+
+func Testing(t *testing.T) {}
+
+func helper(t *testing.T) {}
+`
+		after = strings.Replace(after, `t.Log("before")`, `t.Log("after")`, 1)
+		got, err := gotest.ChangedTests([]byte(committed), []byte(after))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"TestEdited", "TestAdded"}, got)
+	})
+
+	t.Run("a file with no history is all new", func(t *testing.T) {
+		got, err := gotest.ChangedTests(nil, []byte(committed))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"TestKept", "TestEdited"}, got)
+	})
+
+	t.Run("unchanged file", func(t *testing.T) {
+		got, err := gotest.ChangedTests([]byte(committed), []byte(committed))
+		require.NoError(t, err)
+		assert.Empty(t, got)
+	})
+
+	t.Run("unparsable test file", func(t *testing.T) {
+		_, err := gotest.ChangedTests(nil, []byte("package foo\nfunc TestX("))
+		assert.Error(t, err)
+	})
+}
+
+func TestFailuresKeepsTheEnd(t *testing.T) {
+	res := gotest.Result{Output: strings.Repeat("noise\n", 2000) + "--- FAIL: TestX\n"}
+	assert.LessOrEqual(t, len(res.Failures()), 5*1024)
+	assert.Contains(t, res.Failures(), "--- FAIL: TestX")
+}
+
+func TestRunPattern(t *testing.T) {
+	assert.Equal(t, "^(TestA|TestB)$", gotest.RunPattern([]string{"TestA", "TestB"}))
+}
+
+func module(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	files["go.mod"] = "module example.com/foo\n\ngo 1.21\n"
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+	}
+	return dir
+}
+
+func TestRun(t *testing.T) {
+	dir := module(t, map[string]string{
+		"foo.go": "package foo\n\nfunc Foo() int { return 1 }\n",
+		"foo_test.go": `package foo
+
+import "testing"
+
+func TestPasses(t *testing.T) {}
+
+func TestFails(t *testing.T) { if Foo() != 2 { t.Fatal("want 2") } }
+`,
+	})
+
+	t.Run("passing test", func(t *testing.T) {
+		res, err := gotest.Run(context.Background(), dir, gotest.RunPattern([]string{"TestPasses"}), ".")
+		require.NoError(t, err)
+		assert.True(t, res.Passed)
+	})
+
+	t.Run("failing test", func(t *testing.T) {
+		res, err := gotest.Run(context.Background(), dir, gotest.RunPattern([]string{"TestPasses", "TestFails"}), ".")
+		require.NoError(t, err)
+		assert.False(t, res.Passed)
+		assert.Contains(t, res.Output, "want 2")
+		assert.Contains(t, res.Failures(), "--- FAIL: TestFails")
+		assert.NotContains(t, res.Failures(), "TestPasses")
+	})
+
+	t.Run("a build failure is a failing result", func(t *testing.T) {
+		broken := module(t, map[string]string{
+			"foo.go":      "package foo\n",
+			"foo_test.go": "package foo\n\nimport \"testing\"\n\nfunc TestBar(t *testing.T) { Bar() }\n",
+		})
+		res, err := gotest.Run(context.Background(), broken, "", ".")
+		require.NoError(t, err)
+		assert.False(t, res.Passed)
+	})
+
+	t.Run("outside a module go test does not run", func(t *testing.T) {
+		empty := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(empty, "x_test.go"), []byte("package x\n"), 0600))
+		_, err := gotest.Run(context.Background(), empty, "", ".")
+		assert.ErrorIs(t, err, gotest.ErrNotRun)
+	})
+}
@@ -0,0 +1,110 @@
+// Package hook speaks Claude Code's hook protocol: it turns agent findings
+// into the structured decisions Claude Code reads from a hook's stdout.
+package hook
+
+import (
+	"strings"
+
+	"github.com/gauthierbraillon/ensemble/internal/agent"
+)
+
+// Permission is a PreToolUse decision.
+type Permission string
+
+const (
+	Allow Permission = "allow"
+	Deny  Permission = "deny"
+	Ask   Permission = "ask"
+)
+
+// Event names Claude Code expects back in hookSpecificOutput.
+const (
+	PreToolUse  = "PreToolUse"
+	PostToolUse = "PostToolUse"
+	Stop        = "Stop"
+
+	UserPromptSubmit = "UserPromptSubmit"
+)
+
+// Output is the JSON a hook prints on stdout.
+type Output struct {
+	// Decision "block" hands Reason back to the model after the fact; the
+	// tool call has already happened.
+	Decision           string          `json:"decision,omitempty"`
+	Reason             string          `json:"reason,omitempty"`
+	HookSpecificOutput *SpecificOutput `json:"hookSpecificOutput,omitempty"`
+}
+
+type SpecificOutput struct {
+	HookEventName            string     `json:"hookEventName"`
+	PermissionDecision       Permission `json:"permissionDecision,omitempty"`
+	PermissionDecisionReason string     `json:"permissionDecisionReason,omitempty"`
+	// AdditionalContext is added to the model's context without blocking.
+	AdditionalContext string `json:"additionalContext,omitempty"`
+}
+
+// Decide maps a finding to a PreToolUse decision: a block denies the tool
+// call, a warning asks the user, and a pass returns false so the hook stays
+// silent and Claude Code's own permission rules apply.
+func Decide(f agent.Finding) (Output, bool) {
+	var p Permission
+	switch f.Verdict {
+	case agent.Block:
+		p = Deny
+	case agent.Warn:
+		p = Ask
+	default:
+		return Output{}, false
+	}
+	return Output{HookSpecificOutput: &SpecificOutput{
+		HookEventName:            PreToolUse,
+		PermissionDecision:       p,
+		PermissionDecisionReason: Reason(f),
+	}}, true
+}
+
+// Feedback maps a finding to the answer of a hook that runs after the tool:
+// a block is handed back to the model as something it must address, a
+// warning is added to its context, and a pass returns false.
+func Feedback(event string, f agent.Finding) (Output, bool) {
+	switch f.Verdict {
+	case agent.Block:
+		return Output{Decision: "block", Reason: Reason(f)}, true
+	case agent.Warn:
+		return Output{HookSpecificOutput: &SpecificOutput{HookEventName: event, AdditionalContext: Reason(f)}}, true
+	}
+	return Output{}, false
+}
+
+// Continue answers a Stop hook: while any finding remains the model may not
+// end its turn, and every finding is handed back as the reason to go on.
+func Continue(findings []agent.Finding) (Output, bool) {
+	if len(findings) == 0 {
+		return Output{}, false
+	}
+	var b strings.Builder
+	b.WriteString("ensemble: the uncommitted changes are not done yet; address these before stopping:")
+	for _, f := range findings {
+		reason := Reason(f)
+		if f.File != "" && !strings.Contains(reason, f.File) {
+			reason += " (" + f.File + ")"
+		}
+		b.WriteString("\n- ")
+		b.WriteString(reason)
+	}
+	return Output{Decision: "block", Reason: b.String()}, true
+}
+
+// Inject adds text to the model's context for the event, without blocking.
+func Inject(event, text string) Output {
+	return Output{HookSpecificOutput: &SpecificOutput{HookEventName: event, AdditionalContext: text}}
+}
+
+// Reason is the text shown to the model: who objected, why, and the fix.
+func Reason(f agent.Finding) string {
+	reason := "ensemble " + f.Agent + ": " + f.Finding
+	if f.Fix != "" {
+		reason += ". Fix: " + f.Fix
+	}
+	return reason
+}
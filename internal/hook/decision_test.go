@@ -0,0 +1,88 @@
+package hook_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gauthierbraillon/ensemble/internal/agent"
+	"github.com/gauthierbraillon/ensemble/internal/hook"
+)
+
+func TestDecide(t *testing.T) {
+	t.Run("a block denies with the fix as the reason", func(t *testing.T) {
+		out, ok := hook.Decide(agent.Finding{Agent: "testing-quality", Verdict: agent.Block, Finding: "no test file for foo.go", Fix: "write foo_test.go with a failing test first"})
+		require.True(t, ok)
+		assert.Equal(t, hook.Deny, out.HookSpecificOutput.PermissionDecision)
+		assert.Equal(t, "ensemble testing-quality: no test file for foo.go. Fix: write foo_test.go with a failing test first", out.HookSpecificOutput.PermissionDecisionReason)
+	})
+
+	t.Run("a warning asks the user", func(t *testing.T) {
+		out, ok := hook.Decide(agent.Finding{Agent: "testing-quality", Verdict: agent.Warn, Finding: "test untouched"})
+		require.True(t, ok)
+		assert.Equal(t, hook.Ask, out.HookSpecificOutput.PermissionDecision)
+		assert.Equal(t, "ensemble testing-quality: test untouched", out.HookSpecificOutput.PermissionDecisionReason)
+	})
+
+	t.Run("a pass is silent", func(t *testing.T) {
+		_, ok := hook.Decide(agent.Finding{Verdict: agent.Pass})
+		assert.False(t, ok)
+	})
+
+	t.Run("serialises in Claude Code's hook schema", func(t *testing.T) {
+		out, _ := hook.Decide(agent.Finding{Agent: "a", Verdict: agent.Block, Finding: "x"})
+		data, err := json.Marshal(out)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"hookSpecificOutput":{"hookEventName":"PreToolUse","permissionDecision":"deny","permissionDecisionReason":"ensemble a: x"}}`, string(data))
+	})
+}
+
+func TestFeedback(t *testing.T) {
+	t.Run("a block is handed back to the model", func(t *testing.T) {
+		out, ok := hook.Feedback(hook.PostToolUse, agent.Finding{Agent: "testing-quality", Verdict: agent.Block, Finding: "go test failed", Fix: "make it pass"})
+		require.True(t, ok)
+		data, err := json.Marshal(out)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"decision":"block","reason":"ensemble testing-quality: go test failed. Fix: make it pass"}`, string(data))
+	})
+
+	t.Run("a warning becomes context", func(t *testing.T) {
+		out, ok := hook.Feedback(hook.PostToolUse, agent.Finding{Agent: "testing-quality", Verdict: agent.Warn, Finding: "slow"})
+		require.True(t, ok)
+		data, err := json.Marshal(out)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"hookSpecificOutput":{"hookEventName":"PostToolUse","additionalContext":"ensemble testing-quality: slow"}}`, string(data))
+	})
+
+	t.Run("a pass is silent", func(t *testing.T) {
+		_, ok := hook.Feedback(hook.PostToolUse, agent.Finding{Verdict: agent.Pass})
+		assert.False(t, ok)
+	})
+}
+
+func TestContinue(t *testing.T) {
+	t.Run("every finding is a reason to go on", func(t *testing.T) {
+		out, ok := hook.Continue([]agent.Finding{
+			{Agent: "testing-quality", Verdict: agent.Block, Finding: "implementation without test", Fix: "write foo_test.go"},
+			{Agent: "security", Verdict: agent.Block, Finding: "sql injection", File: "db.go:12"},
+		})
+		require.True(t, ok)
+		assert.Equal(t, "block", out.Decision)
+		assert.Contains(t, out.Reason, "\n- ensemble testing-quality: implementation without test. Fix: write foo_test.go")
+		assert.Contains(t, out.Reason, "\n- ensemble security: sql injection (db.go:12)")
+		assert.Nil(t, out.HookSpecificOutput)
+	})
+
+	t.Run("nothing left lets the model stop", func(t *testing.T) {
+		_, ok := hook.Continue(nil)
+		assert.False(t, ok)
+	})
+}
+
+func TestInject(t *testing.T) {
+	data, err := json.Marshal(hook.Inject(hook.UserPromptSubmit, "phase: RED"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"hookSpecificOutput":{"hookEventName":"UserPromptSubmit","additionalContext":"phase: RED"}}`, string(data))
+}
@@ -0,0 +1,85 @@
+package hook
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/gauthierbraillon/ensemble/internal/shell"
+)
+
+// Event is the JSON Claude Code sends a hook on stdin.
+type Event struct {
+	SessionID     string    `json:"session_id"`
+	Cwd           string    `json:"cwd"`
+	HookEventName string    `json:"hook_event_name"`
+	ToolName      string    `json:"tool_name"`
+	ToolInput     ToolInput `json:"tool_input"`
+	// StopHookActive is set on a Stop event when the model is already
+	// working on because a Stop hook sent it back.
+	StopHookActive bool `json:"stop_hook_active"`
+}
+
+// ToolInput holds the fields of every file-writing tool's input that the
+// hook reads.
+type ToolInput struct {
+	FilePath     string `json:"file_path"`
+	Content      string `json:"content"`
+	OldString    string `json:"old_string"`
+	NewString    string `json:"new_string"`
+	Edits        []Edit `json:"edits"`
+	NotebookPath string `json:"notebook_path"`
+	NewSource    string `json:"new_source"`
+	Command      string `json:"command"`
+}
+
+// Edit is one replacement in a MultiEdit call.
+type Edit struct {
+	OldString string `json:"old_string"`
+	NewString string `json:"new_string"`
+}
+
+// Write is one file a tool call writes. Content is the text it puts there
+// and Replaced the text that goes away, each empty when the payload does
+// not say.
+type Write struct {
+	Path     string
+	Content  string
+	Replaced string
+}
+
+// Writes lists the files the tool call would write. Bash commands are parsed
+// for redirections and in-place edits; their relative paths resolve against
+// the session's working directory. A Write replaces the whole file, so what
+// it replaces is read from disk.
+func (e Event) Writes() []Write {
+	in := e.ToolInput
+	switch e.ToolName {
+	case "Bash":
+		var writes []Write
+		for _, w := range shell.Writes(in.Command) {
+			if e.Cwd != "" && !filepath.IsAbs(w.Path) {
+				w.Path = filepath.Join(e.Cwd, filepath.FromSlash(w.Path))
+			}
+			writes = append(writes, Write{Path: w.Path, Content: w.Content})
+		}
+		return writes
+	case "NotebookEdit":
+		return []Write{{Path: in.NotebookPath, Content: in.NewSource}}
+	case "MultiEdit":
+		var content, replaced string
+		for _, edit := range in.Edits {
+			content += edit.NewString + "\n"
+			replaced += edit.OldString + "\n"
+		}
+		return []Write{{Path: in.FilePath, Content: content, Replaced: replaced}}
+	}
+	if in.FilePath == "" {
+		return nil
+	}
+	w := Write{Path: in.FilePath, Content: in.Content + in.NewString, Replaced: in.OldString}
+	if e.ToolName == "Write" {
+		onDisk, _ := os.ReadFile(in.FilePath) // #nosec G304
+		w.Replaced = string(onDisk)
+	}
+	return []Write{w}
+}
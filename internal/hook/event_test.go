@@ -0,0 +1,64 @@
+package hook_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gauthierbraillon/ensemble/internal/hook"
+)
+
+func writesOf(t *testing.T, raw string) []hook.Write {
+	t.Helper()
+	var e hook.Event
+	require.NoError(t, json.Unmarshal([]byte(raw), &e))
+	return e.Writes()
+}
+
+func TestEventWrites(t *testing.T) {
+	t.Run("Write", func(t *testing.T) {
+		ws := writesOf(t, `{"tool_name":"Write","tool_input":{"file_path":"/p/foo.go","content":"package foo"}}`)
+		assert.Equal(t, []hook.Write{{Path: "/p/foo.go", Content: "package foo"}}, ws)
+	})
+
+	t.Run("Edit", func(t *testing.T) {
+		ws := writesOf(t, `{"tool_name":"Edit","tool_input":{"file_path":"/p/foo.go","old_string":"a","new_string":"b"}}`)
+		assert.Equal(t, []hook.Write{{Path: "/p/foo.go", Content: "b", Replaced: "a"}}, ws)
+	})
+
+	t.Run("MultiEdit joins every replacement", func(t *testing.T) {
+		ws := writesOf(t, `{"tool_name":"MultiEdit","tool_input":{"file_path":"/p/lib.rs","edits":[{"old_string":"a","new_string":"b"},{"old_string":"c","new_string":"#[cfg(test)]"}]}}`)
+		require.Len(t, ws, 1)
+		assert.Equal(t, "/p/lib.rs", ws[0].Path)
+		assert.Contains(t, ws[0].Content, "#[cfg(test)]")
+		assert.Equal(t, "a\nc\n", ws[0].Replaced)
+	})
+
+	t.Run("Write replaces what is on disk", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "foo.go")
+		require.NoError(t, os.WriteFile(path, []byte("package old"), 0600))
+		raw, _ := json.Marshal(map[string]interface{}{
+			"tool_name":  "Write",
+			"tool_input": map[string]string{"file_path": path, "content": "package foo"},
+		})
+		assert.Equal(t, []hook.Write{{Path: path, Content: "package foo", Replaced: "package old"}}, writesOf(t, string(raw)))
+	})
+
+	t.Run("NotebookEdit", func(t *testing.T) {
+		ws := writesOf(t, `{"tool_name":"NotebookEdit","tool_input":{"notebook_path":"/p/n.ipynb","new_source":"print(1)"}}`)
+		assert.Equal(t, []hook.Write{{Path: "/p/n.ipynb", Content: "print(1)"}}, ws)
+	})
+
+	t.Run("Bash resolves relative targets against cwd", func(t *testing.T) {
+		ws := writesOf(t, `{"tool_name":"Bash","cwd":"/p","tool_input":{"command":"cat > foo.go <<EOF\npackage foo\nEOF\nsed -i s/a/b/ /abs/bar.go"}}`)
+		assert.Equal(t, []hook.Write{{Path: "/p/foo.go", Content: "package foo\n"}, {Path: "/abs/bar.go"}}, ws)
+	})
+
+	t.Run("Bash that writes nothing", func(t *testing.T) {
+		assert.Empty(t, writesOf(t, `{"tool_name":"Bash","tool_input":{"command":"go test ./..."}}`))
+	})
+}
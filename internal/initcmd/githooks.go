@@ -0,0 +1,164 @@
+package initcmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// gitHookMarker identifies the git hooks ensemble installed.
+const gitHookMarker = "Installed by ensemble init --git-hooks."
+
+// chainedSuffix is appended to a hook that was in place before ensemble's,
+// which runs it first.
+const chainedSuffix = ".pre-ensemble"
+
+// GitHook is a script ensemble installs in a repository's hooks directory.
+type GitHook struct {
+	Name   string
+	Script string
+}
+
+// GitHooks lists ensemble's git hooks. pre-commit runs the agents that need
+// no model on the staged changes; pre-push runs the full cycle on each
+// pushed range, from the remote's commit or, for a new branch, from before
+// the first commit no remote has yet.
+var GitHooks = []GitHook{
+	{Name: "pre-commit", Script: `#!/bin/sh
+# ensemble: check the staged changes before they are committed.
+# ` + gitHookMarker + ` A hook that was here before
+# runs first, from pre-commit` + chainedSuffix + `.
+chained="$0` + chainedSuffix + `"
+if [ -x "$chained" ]; then
+	"$chained" "$@" || exit $?
+fi
+exec ensemble cycle --staged --deterministic
+`},
+	{Name: "pre-push", Script: `#!/bin/sh
+# ensemble: review the commits being pushed.
+# ` + gitHookMarker + ` A hook that was here before
+# runs first, from pre-push` + chainedSuffix + `.
+input=$(cat)
+chained="$0` + chainedSuffix + `"
+if [ -x "$chained" ]; then
+	printf '%s\n' "$input" | "$chained" "$@" || exit $?
+fi
+empty_tree=$(git hash-object -t tree /dev/null)
+printf '%s\n' "$input" | while read -r local_ref local_sha remote_ref remote_sha; do
+	case "$local_sha" in *[!0]*) ;; *) continue ;; esac
+	case "$remote_sha" in
+	*[!0]*) from=$remote_sha ;;
+	*)
+		first=$(git rev-list --reverse "$local_sha" --not --remotes | head -n 1)
+		[ -n "$first" ] || continue
+		from=$(git rev-parse -q --verify "$first^" || echo "$empty_tree")
+		;;
+	esac
+	ensemble cycle "$from..$local_sha" || exit 1
+done
+`},
+}
+
+// GitHookChange is a proposed change to one git hook.
+type GitHookChange struct {
+	Name string
+	Path string
+	// Script is what to write, empty when removing.
+	Script string
+	// Chain moves the hook already at Path aside so the new one runs it;
+	// Unchain moves it back.
+	Chain, Unchain bool
+}
+
+// PlanGitHooks works out how installing ensemble's git hooks in dir, the
+// repository's hooks directory, would change it. Hooks ensemble already
+// installed are brought up to date; others are chained, never overwritten.
+func PlanGitHooks(dir string) ([]GitHookChange, error) {
+	var changes []GitHookChange
+	for _, h := range GitHooks {
+		c := GitHookChange{Name: h.Name, Path: filepath.Join(dir, h.Name), Script: h.Script}
+		data, err := os.ReadFile(c.Path) // #nosec G304
+		switch {
+		case os.IsNotExist(err):
+		case err != nil:
+			return nil, err
+		case bytes.Equal(data, []byte(c.Script)):
+			continue
+		case !ours(data):
+			if _, err := os.Stat(c.Path + chainedSuffix); err == nil {
+				return nil, fmt.Errorf("%s: both it and %s exist; remove one before installing", c.Path, c.Name+chainedSuffix)
+			}
+			c.Chain = true
+		}
+		changes = append(changes, c)
+	}
+	return changes, nil
+}
+
+// PlanRemoveGitHooks works out how taking ensemble's git hooks out of dir
+// would change it: each goes, and the hook it chained comes back.
+func PlanRemoveGitHooks(dir string) ([]GitHookChange, error) {
+	var changes []GitHookChange
+	for _, h := range GitHooks {
+		c := GitHookChange{Name: h.Name, Path: filepath.Join(dir, h.Name)}
+		data, err := os.ReadFile(c.Path) // #nosec G304
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !ours(data) {
+			continue
+		}
+		if _, err := os.Stat(c.Path + chainedSuffix); err == nil {
+			c.Unchain = true
+		}
+		changes = append(changes, c)
+	}
+	return changes, nil
+}
+
+// Apply makes the change.
+func (c GitHookChange) Apply() error {
+	if c.Script == "" {
+		if err := os.Remove(c.Path); err != nil {
+			return err
+		}
+		if c.Unchain {
+			return os.Rename(c.Path+chainedSuffix, c.Path)
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(c.Path), 0750); err != nil {
+		return err
+	}
+	if c.Chain {
+		if err := os.Rename(c.Path, c.Path+chainedSuffix); err != nil {
+			return err
+		}
+	}
+	// git runs hooks only when they are executable.
+	if err := os.WriteFile(c.Path, []byte(c.Script), 0755); err != nil { // #nosec G306
+		return err
+	}
+	return os.Chmod(c.Path, 0755) // #nosec G302
+}
+
+// String names the hook and what happens to the one it chains.
+func (c GitHookChange) String() string {
+	s := fmt.Sprintf("%s hook at %s", c.Name, c.Path)
+	switch {
+	case c.Chain:
+		s += "; the hook that was there runs first, as " + c.Name + chainedSuffix
+	case c.Unchain:
+		s += "; the hook it chained is restored"
+	}
+	return s
+}
+
+func ours(script []byte) bool {
+	return strings.Contains(string(script), gitHookMarker)
+}
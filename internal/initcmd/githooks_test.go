@@ -0,0 +1,106 @@
+package initcmd_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gauthierbraillon/ensemble/internal/initcmd"
+)
+
+func applyAll(t *testing.T, changes []initcmd.GitHookChange) {
+	t.Helper()
+	for _, c := range changes {
+		require.NoError(t, c.Apply())
+	}
+}
+
+func TestPlanGitHooks(t *testing.T) {
+	t.Run("installs executable hooks in a fresh directory", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "hooks")
+		changes, err := initcmd.PlanGitHooks(dir)
+		require.NoError(t, err)
+		require.Len(t, changes, len(initcmd.GitHooks))
+		applyAll(t, changes)
+
+		for _, h := range initcmd.GitHooks {
+			info, err := os.Stat(filepath.Join(dir, h.Name))
+			require.NoError(t, err)
+			assert.NotZero(t, info.Mode()&0100, "%s must be executable", h.Name)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, "pre-commit"))
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "ensemble cycle --staged --deterministic")
+	})
+
+	t.Run("changes nothing once installed", func(t *testing.T) {
+		dir := t.TempDir()
+		changes, err := initcmd.PlanGitHooks(dir)
+		require.NoError(t, err)
+		applyAll(t, changes)
+
+		changes, err = initcmd.PlanGitHooks(dir)
+		require.NoError(t, err)
+		assert.Empty(t, changes)
+	})
+
+	t.Run("brings an older ensemble hook up to date in place", func(t *testing.T) {
+		dir := t.TempDir()
+		old := "#!/bin/sh\n# Installed by ensemble init --git-hooks.\nensemble cycle --staged\n"
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "pre-commit"), []byte(old), 0700))
+		changes, err := initcmd.PlanGitHooks(dir)
+		require.NoError(t, err)
+		require.NotEmpty(t, changes)
+		assert.Equal(t, "pre-commit", changes[0].Name)
+		assert.False(t, changes[0].Chain, "its own hook is replaced, not chained")
+	})
+
+	t.Run("chains a hook that was there and restores it on removal", func(t *testing.T) {
+		dir := t.TempDir()
+		mine := "#!/bin/sh\necho mine\n"
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "pre-commit"), []byte(mine), 0700))
+		changes, err := initcmd.PlanGitHooks(dir)
+		require.NoError(t, err)
+		require.NotEmpty(t, changes)
+		assert.True(t, changes[0].Chain)
+		assert.Contains(t, changes[0].String(), "pre-commit.pre-ensemble")
+		applyAll(t, changes)
+
+		data, err := os.ReadFile(filepath.Join(dir, "pre-commit.pre-ensemble"))
+		require.NoError(t, err)
+		assert.Equal(t, mine, string(data))
+
+		changes, err = initcmd.PlanRemoveGitHooks(dir)
+		require.NoError(t, err)
+		require.Len(t, changes, len(initcmd.GitHooks))
+		assert.True(t, changes[0].Unchain)
+		applyAll(t, changes)
+
+		data, err = os.ReadFile(filepath.Join(dir, "pre-commit"))
+		require.NoError(t, err)
+		assert.Equal(t, mine, string(data))
+		_, err = os.Stat(filepath.Join(dir, "pre-commit.pre-ensemble"))
+		assert.True(t, os.IsNotExist(err))
+		_, err = os.Stat(filepath.Join(dir, "pre-push"))
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("refuses when a chained hook is already in the way", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "pre-commit"), []byte("#!/bin/sh\n"), 0700))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "pre-commit.pre-ensemble"), []byte("#!/bin/sh\n"), 0700))
+		_, err := initcmd.PlanGitHooks(dir)
+		assert.ErrorContains(t, err, "pre-commit.pre-ensemble")
+	})
+
+	t.Run("removal leaves hooks it did not install", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "pre-push"), []byte("#!/bin/sh\n"), 0700))
+		changes, err := initcmd.PlanRemoveGitHooks(dir)
+		require.NoError(t, err)
+		assert.Empty(t, changes)
+	})
+}
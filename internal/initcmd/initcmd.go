@@ -1,35 +1,220 @@
 package initcmd
 
 import (
-	"encoding/json"
+	"bytes"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
+
+	"github.com/gauthierbraillon/ensemble/internal/diff"
+	"github.com/gauthierbraillon/ensemble/internal/jsonedit"
 )
 
-const hookCommand = "ensemble hook"
+// writeMatcher lists every tool that can write a file.
+const writeMatcher = "Write|Edit|MultiEdit|NotebookEdit|Bash"
 
-func WriteSettings(dir string) (bool, error) {
-	settingsPath := filepath.Join(dir, ".claude", "settings.json")
-	settings := map[string]interface{}{}
-	if data, err := os.ReadFile(settingsPath); err == nil { // #nosec G304
-		_ = json.Unmarshal(data, &settings)
+// Hook is one Claude Code hook ensemble registers.
+type Hook struct {
+	Event string
+	// Matcher selects the tools the hook fires for; empty for events that
+	// are not about a tool call.
+	Matcher string
+	Command string
+}
+
+// Hooks lists every hook ensemble registers. Entries registered by older
+// versions with a narrower matcher are upgraded in place.
+var Hooks = []Hook{
+	{Event: "PreToolUse", Matcher: writeMatcher, Command: "ensemble hook"},
+	{Event: "PostToolUse", Matcher: writeMatcher, Command: "ensemble hook --phase post"},
+	{Event: "Stop", Command: "ensemble hook --phase stop"},
+	{Event: "UserPromptSubmit", Command: "ensemble hook --phase prompt"},
+}
+
+// Change is a proposed rewrite of a settings file.
+type Change struct {
+	Path          string
+	Before, After []byte
+}
+
+// Empty reports whether the change leaves the file as it is.
+func (c Change) Empty() bool {
+	return bytes.Equal(c.Before, c.After)
+}
+
+// Diff renders the change as a unified diff.
+func (c Change) Diff() string {
+	if c.Empty() {
+		return ""
 	}
-	if hasHook(settings) {
-		return false, nil
+	return diff.Unified(strings.TrimPrefix(filepath.ToSlash(c.Path), "/"), string(c.Before), string(c.After))
+}
+
+// Apply writes the change, through a temporary file renamed over the
+// original so the settings are never left half written.
+func (c Change) Apply() error {
+	if c.Empty() {
+		return nil
 	}
-	mergeHook(settings)
-	if err := os.MkdirAll(filepath.Dir(settingsPath), 0750); err != nil {
-		return false, err
+	dir := filepath.Dir(c.Path)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return err
+	}
+	mode := os.FileMode(0600)
+	if info, err := os.Stat(c.Path); err == nil {
+		mode = info.Mode().Perm()
 	}
-	data, err := json.MarshalIndent(settings, "", "  ")
+	tmp, err := os.CreateTemp(dir, filepath.Base(c.Path)+".*")
 	if err != nil {
-		return false, err
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(c.After); err != nil {
+		tmp.Close()
+		return err
 	}
-	if err := os.WriteFile(settingsPath, append(data, '\n'), 0600); err != nil { // #nosec G306
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.Path)
+}
+
+// WriteSettings adds the ensemble hooks to .claude/settings.json under dir
+// and reports whether the file changed.
+func WriteSettings(dir string) (bool, error) {
+	c, _, err := PlanInstall(Locations{Project: dir}, Project)
+	if err != nil {
 		return false, err
 	}
-	return true, nil
+	return !c.Empty(), c.Apply()
+}
+
+// PlanInstall works out how adding the ensemble hooks to scope would change
+// its settings, without writing them. Hooks another scope already runs are
+// left out, and returned with the matcher found there, so that none runs
+// twice; a Narrow one is upgraded by installing to its own scope.
+func PlanInstall(l Locations, scope Scope) (Change, []Registration, error) {
+	c, settings, err := read(l.Path(scope))
+	if err != nil {
+		return Change{}, nil, err
+	}
+	changed := false
+	var elsewhere []Registration
+	for _, h := range Hooks {
+		entry := hookEntry(settings, h)
+		if entry == nil {
+			r, ok, err := registered(l, scope, h)
+			if err != nil {
+				return Change{}, nil, err
+			}
+			if ok {
+				elsewhere = append(elsewhere, r)
+				continue
+			}
+		}
+		switch {
+		case entry == nil:
+			mergeHook(settings, h)
+		case h.Matcher != "" && matcher(entry) != h.Matcher:
+			entry.Set("matcher", h.Matcher)
+		default:
+			continue
+		}
+		changed = true
+	}
+	if !changed {
+		return c, elsewhere, nil
+	}
+	c.After, err = settings.Marshal()
+	return c, elsewhere, err
+}
+
+// RemoveSettings takes every ensemble hook out of .claude/settings.json
+// under dir and returns what it removed.
+func RemoveSettings(dir string) ([]Hook, error) {
+	c, removed, err := PlanRemove(Locations{Project: dir}, Project)
+	if err != nil {
+		return nil, err
+	}
+	return removed, c.Apply()
+}
+
+// PlanRemove works out how taking the ensemble hooks out of scope would
+// change its settings, without writing them. Every ensemble hook goes,
+// including those registered by older versions; so do entries left without
+// hooks, events left without entries and a hooks map left empty. Everything
+// else is kept as it was.
+func PlanRemove(l Locations, scope Scope) (Change, []Hook, error) {
+	settingsPath := l.Path(scope)
+	if _, err := os.Stat(settingsPath); os.IsNotExist(err) {
+		return Change{Path: settingsPath}, nil, nil
+	}
+	c, settings, err := read(settingsPath)
+	if err != nil {
+		return Change{}, nil, err
+	}
+	hooks, ok := object(settings, "hooks")
+	if !ok {
+		return c, nil, nil
+	}
+	var removed []Hook
+	for _, event := range hooks.Keys() {
+		v, _ := hooks.Get(event)
+		entries, ok := v.([]interface{})
+		if !ok {
+			continue
+		}
+		var kept []interface{}
+		for _, e := range entries {
+			entry, ok := e.(*jsonedit.Object)
+			if !ok {
+				kept = append(kept, e)
+				continue
+			}
+			v, _ := entry.Get("hooks")
+			inner, ok := v.([]interface{})
+			if !ok {
+				kept = append(kept, e)
+				continue
+			}
+			var others []interface{}
+			for _, h := range inner {
+				if command := command(h); isEnsemble(command) {
+					removed = append(removed, Hook{Event: event, Matcher: matcher(entry), Command: command})
+				} else {
+					others = append(others, h)
+				}
+			}
+			switch {
+			case len(others) == len(inner):
+				kept = append(kept, e)
+			case len(others) > 0:
+				entry.Set("hooks", others)
+				kept = append(kept, e)
+			}
+		}
+		switch {
+		case len(kept) == len(entries):
+		case len(kept) == 0:
+			hooks.Delete(event)
+		default:
+			hooks.Set(event, kept)
+		}
+	}
+	if len(removed) == 0 {
+		return c, nil, nil
+	}
+	if hooks.Len() == 0 {
+		settings.Delete("hooks")
+	}
+	c.After, err = settings.Marshal()
+	return c, removed, err
 }
 
 func EnsembleOnPath() bool {
@@ -37,55 +222,100 @@ func EnsembleOnPath() bool {
 	return err == nil
 }
 
-func hasHook(settings map[string]interface{}) bool {
-	hooks, ok := settings["hooks"].(map[string]interface{})
+// isEnsemble reports whether a hook command runs ensemble's hook, whatever
+// the phase or the version that registered it.
+func isEnsemble(command string) bool {
+	return command == "ensemble hook" || strings.HasPrefix(command, "ensemble hook ")
+}
+
+// read parses the settings at path into an unchanged Change and the
+// document to edit. A missing or blank file reads as an empty object;
+// invalid JSON is an error rather than something to overwrite.
+func read(path string) (Change, *jsonedit.Object, error) {
+	c := Change{Path: path}
+	data, err := os.ReadFile(path) // #nosec G304
+	if err != nil && !os.IsNotExist(err) {
+		return Change{}, nil, err
+	}
+	c.Before, c.After = data, data
+	if len(bytes.TrimSpace(data)) == 0 {
+		return c, jsonedit.NewObject(), nil
+	}
+	settings, err := jsonedit.Parse(data)
+	if err != nil {
+		return Change{}, nil, fmt.Errorf("%s is not valid JSON; fix it and run again, nothing was changed:\n%w", path, err)
+	}
+	return c, settings, nil
+}
+
+// object returns the object under key.
+func object(o *jsonedit.Object, key string) (*jsonedit.Object, bool) {
+	v, _ := o.Get(key)
+	child, ok := v.(*jsonedit.Object)
+	return child, ok
+}
+
+func matcher(entry *jsonedit.Object) string {
+	v, _ := entry.Get("matcher")
+	s, _ := v.(string)
+	return s
+}
+
+func command(hook interface{}) string {
+	h, ok := hook.(*jsonedit.Object)
+	if !ok {
+		return ""
+	}
+	v, _ := h.Get("command")
+	s, _ := v.(string)
+	return s
+}
+
+// hookEntry returns the entry under h's event that runs h's command, or nil.
+func hookEntry(settings *jsonedit.Object, h Hook) *jsonedit.Object {
+	hooks, ok := object(settings, "hooks")
 	if !ok {
-		return false
+		return nil
 	}
-	preToolUse, ok := hooks["PreToolUse"].([]interface{})
+	v, _ := hooks.Get(h.Event)
+	entries, ok := v.([]interface{})
 	if !ok {
-		return false
+		return nil
 	}
-	for _, entry := range preToolUse {
-		entryMap, ok := entry.(map[string]interface{})
+	for _, e := range entries {
+		entry, ok := e.(*jsonedit.Object)
 		if !ok {
 			continue
 		}
-		innerHooks, ok := entryMap["hooks"].([]interface{})
+		v, _ := entry.Get("hooks")
+		inner, ok := v.([]interface{})
 		if !ok {
 			continue
 		}
-		for _, h := range innerHooks {
-			hMap, ok := h.(map[string]interface{})
-			if !ok {
-				continue
-			}
-			if hMap["command"] == hookCommand {
-				return true
+		for _, hook := range inner {
+			if command(hook) == h.Command {
+				return entry
 			}
 		}
 	}
-	return false
+	return nil
 }
 
-func mergeHook(settings map[string]interface{}) {
-	hooks, ok := settings["hooks"].(map[string]interface{})
+func mergeHook(settings *jsonedit.Object, h Hook) {
+	hooks, ok := object(settings, "hooks")
 	if !ok {
-		hooks = map[string]interface{}{}
-		settings["hooks"] = hooks
-	}
-	entry := map[string]interface{}{
-		"matcher": "Write|Edit",
-		"hooks": []interface{}{
-			map[string]interface{}{
-				"type":    "command",
-				"command": hookCommand,
-			},
-		},
-	}
-	preToolUse, ok := hooks["PreToolUse"].([]interface{})
-	if !ok {
-		preToolUse = []interface{}{}
+		hooks = jsonedit.NewObject()
+		settings.Set("hooks", hooks)
+	}
+	hook := jsonedit.NewObject()
+	hook.Set("type", "command")
+	hook.Set("command", h.Command)
+	entry := jsonedit.NewObject()
+	if h.Matcher != "" {
+		entry.Set("matcher", h.Matcher)
 	}
-	hooks["PreToolUse"] = append(preToolUse, entry)
+	entry.Set("hooks", []interface{}{hook})
+	v, _ := hooks.Get(h.Event)
+	entries, _ := v.([]interface{})
+	hooks.Set(h.Event, append(entries, entry))
 }
@@ -1,8 +1,10 @@
 package initcmd_test
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -55,4 +57,196 @@ func TestWriteSettings(t *testing.T) {
 		require.NoError(t, err)
 		assert.False(t, changed)
 	})
+
+	t.Run("widens the matcher of a hook registered by an older version", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, ".claude"), 0755))
+		require.NoError(t, os.WriteFile(
+			filepath.Join(dir, ".claude", "settings.json"),
+			[]byte(`{"hooks":{"PreToolUse":[{"matcher":"Write|Edit","hooks":[{"type":"command","command":"ensemble hook"}]}]}}`),
+			0644,
+		))
+		changed, err := initcmd.WriteSettings(dir)
+		require.NoError(t, err)
+		assert.True(t, changed)
+		data, err := os.ReadFile(filepath.Join(dir, ".claude", "settings.json"))
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"matcher": "Write|Edit|MultiEdit|NotebookEdit|Bash"`)
+		assert.Equal(t, 1, strings.Count(string(data), `"ensemble hook"`))
+	})
+
+	t.Run("registers the PostToolUse, Stop and UserPromptSubmit hooks", func(t *testing.T) {
+		dir := t.TempDir()
+		_, err := initcmd.WriteSettings(dir)
+		require.NoError(t, err)
+		data, err := os.ReadFile(filepath.Join(dir, ".claude", "settings.json"))
+		require.NoError(t, err)
+		var settings struct {
+			Hooks map[string][]struct {
+				Matcher string `json:"matcher"`
+				Hooks   []struct {
+					Command string `json:"command"`
+				} `json:"hooks"`
+			} `json:"hooks"`
+		}
+		require.NoError(t, json.Unmarshal(data, &settings))
+		require.Len(t, settings.Hooks["PostToolUse"], 1)
+		assert.Equal(t, "ensemble hook --phase post", settings.Hooks["PostToolUse"][0].Hooks[0].Command)
+		assert.Equal(t, "Write|Edit|MultiEdit|NotebookEdit|Bash", settings.Hooks["PostToolUse"][0].Matcher)
+		require.Len(t, settings.Hooks["Stop"], 1)
+		assert.Equal(t, "ensemble hook --phase stop", settings.Hooks["Stop"][0].Hooks[0].Command)
+		assert.Empty(t, settings.Hooks["Stop"][0].Matcher, "Stop is not about a tool")
+		require.Len(t, settings.Hooks["UserPromptSubmit"], 1)
+		assert.Equal(t, "ensemble hook --phase prompt", settings.Hooks["UserPromptSubmit"][0].Hooks[0].Command)
+	})
+}
+
+func TestRemoveSettings(t *testing.T) {
+	settingsFile := func(t *testing.T, dir string) string {
+		t.Helper()
+		return filepath.Join(dir, ".claude", "settings.json")
+	}
+
+	t.Run("undoes WriteSettings", func(t *testing.T) {
+		dir := t.TempDir()
+		_, err := initcmd.WriteSettings(dir)
+		require.NoError(t, err)
+
+		removed, err := initcmd.RemoveSettings(dir)
+		require.NoError(t, err)
+		assert.Equal(t, initcmd.Hooks, removed)
+		data, err := os.ReadFile(settingsFile(t, dir))
+		require.NoError(t, err)
+		assert.Equal(t, "{}\n", string(data), "an empty hooks map goes too")
+	})
+
+	t.Run("keeps unrelated hooks and key order", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, ".claude"), 0755))
+		require.NoError(t, os.WriteFile(settingsFile(t, dir), []byte(`{
+  "model": "opus",
+  "hooks": {
+    "PreToolUse": [
+      {
+        "matcher": "Write|Edit",
+        "hooks": [
+          {"type": "command", "command": "ensemble hook"},
+          {"type": "command", "command": "gofmt-check"}
+        ]
+      },
+      {"matcher": "Bash", "hooks": [{"type": "command", "command": "audit"}]}
+    ],
+    "Stop": [{"hooks": [{"type": "command", "command": "ensemble hook --phase stop"}]}]
+  },
+  "env": {"B": "1", "A": "2"}
+}`), 0644))
+
+		removed, err := initcmd.RemoveSettings(dir)
+		require.NoError(t, err)
+		assert.Equal(t, []initcmd.Hook{
+			{Event: "PreToolUse", Matcher: "Write|Edit", Command: "ensemble hook"},
+			{Event: "Stop", Command: "ensemble hook --phase stop"},
+		}, removed)
+
+		data, err := os.ReadFile(settingsFile(t, dir))
+		require.NoError(t, err)
+		content := string(data)
+		assert.NotContains(t, content, "ensemble hook")
+		assert.NotContains(t, content, "Stop", "an event left without entries goes")
+		assert.Contains(t, content, "gofmt-check")
+		assert.Contains(t, content, "audit")
+		assert.Less(t, strings.Index(content, `"model"`), strings.Index(content, `"hooks"`))
+		assert.Less(t, strings.Index(content, `"hooks"`), strings.Index(content, `"env"`))
+		assert.Less(t, strings.Index(content, `"B"`), strings.Index(content, `"A"`))
+	})
+
+	t.Run("changes nothing without ensemble hooks", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, ".claude"), 0755))
+		original := `{"hooks": {"Stop": [{"hooks": [{"type": "command", "command": "notify"}]}]}}`
+		require.NoError(t, os.WriteFile(settingsFile(t, dir), []byte(original), 0644))
+
+		removed, err := initcmd.RemoveSettings(dir)
+		require.NoError(t, err)
+		assert.Empty(t, removed)
+		data, err := os.ReadFile(settingsFile(t, dir))
+		require.NoError(t, err)
+		assert.Equal(t, original, string(data))
+	})
+
+	t.Run("changes nothing without settings", func(t *testing.T) {
+		dir := t.TempDir()
+		removed, err := initcmd.RemoveSettings(dir)
+		require.NoError(t, err)
+		assert.Empty(t, removed)
+		assert.NoFileExists(t, settingsFile(t, dir))
+	})
+
+	t.Run("refuses to rewrite invalid settings", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, ".claude"), 0755))
+		require.NoError(t, os.WriteFile(settingsFile(t, dir), []byte(`{"hooks": `), 0644))
+		_, err := initcmd.RemoveSettings(dir)
+		assert.Error(t, err)
+	})
+}
+
+func TestPlanInstall(t *testing.T) {
+	writeSettings := func(t *testing.T, dir, content string) string {
+		t.Helper()
+		path := filepath.Join(dir, ".claude", "settings.json")
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		return path
+	}
+
+	t.Run("writes nothing", func(t *testing.T) {
+		dir := t.TempDir()
+		c, _, err := initcmd.PlanInstall(initcmd.Locations{Project: dir}, initcmd.Project)
+		require.NoError(t, err)
+		assert.False(t, c.Empty())
+		assert.Contains(t, c.Diff(), "+        \"matcher\": \"Write|Edit|MultiEdit|NotebookEdit|Bash\",")
+		assert.NoFileExists(t, filepath.Join(dir, ".claude", "settings.json"))
+	})
+
+	t.Run("keeps the rest of the file as it was", func(t *testing.T) {
+		dir := t.TempDir()
+		original := "{\n\t\"permissions\": {\"allow\": [\"Bash(go test:*)\"]},\n\t\"model\": \"opus\"\n}\n"
+		path := writeSettings(t, dir, original)
+		_, err := initcmd.WriteSettings(dir)
+		require.NoError(t, err)
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		content := string(data)
+		assert.True(t, strings.HasPrefix(content, "{\n\t\"permissions\": {\"allow\": [\"Bash(go test:*)\"]},\n\t\"model\": \"opus\",\n\t\"hooks\": {\n\t\t\"PreToolUse\": [\n"), content)
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0644), info.Mode().Perm(), "the file keeps its permissions")
+		entries, err := os.ReadDir(filepath.Dir(path))
+		require.NoError(t, err)
+		assert.Len(t, entries, 1, "no temporary files left behind")
+	})
+
+	t.Run("refuses to overwrite invalid JSON", func(t *testing.T) {
+		dir := t.TempDir()
+		original := "{\n  \"model\": \"opus\",\n}\n"
+		path := writeSettings(t, dir, original)
+
+		_, err := initcmd.WriteSettings(dir)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "not valid JSON")
+		assert.Contains(t, err.Error(), "line 3, column 1")
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, original, string(data))
+	})
+
+	t.Run("a blank file reads as empty settings", func(t *testing.T) {
+		dir := t.TempDir()
+		writeSettings(t, dir, "\n")
+		changed, err := initcmd.WriteSettings(dir)
+		require.NoError(t, err)
+		assert.True(t, changed)
+	})
 }
@@ -0,0 +1,136 @@
+package initcmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gauthierbraillon/ensemble/internal/jsonedit"
+)
+
+// Scope is one of the settings files Claude Code reads. Claude Code runs
+// the hooks of every scope, so ensemble is registered in only one of them.
+type Scope string
+
+const (
+	// Project settings are committed and shared with the team.
+	Project Scope = "project"
+	// Local settings stay on this machine, in this project.
+	Local Scope = "local"
+	// User settings apply to every project on this machine.
+	User Scope = "user"
+)
+
+// Scopes lists every scope, broadest first.
+var Scopes = []Scope{User, Project, Local}
+
+// ParseScope returns the scope called name.
+func ParseScope(name string) (Scope, error) {
+	for _, s := range Scopes {
+		if string(s) == name {
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("unknown scope %q: want project, local or user", name)
+}
+
+// File is the scope's settings file as a person would write it.
+func (s Scope) File() string {
+	switch s {
+	case Local:
+		return ".claude/settings.local.json"
+	case User:
+		return "~/.claude/settings.json"
+	}
+	return ".claude/settings.json"
+}
+
+// Locations are the directories the scopes live in: the project's, and the
+// user's home. An empty Home leaves the user scope out.
+type Locations struct {
+	Project string
+	Home    string
+}
+
+// Path is the settings file of scope, or empty when l leaves it out.
+func (l Locations) Path(s Scope) string {
+	if s == User {
+		if l.Home == "" {
+			return ""
+		}
+		return filepath.Join(l.Home, ".claude", "settings.json")
+	}
+	return filepath.Join(l.Project, filepath.FromSlash(strings.TrimPrefix(s.File(), "~/")))
+}
+
+// Registration is an ensemble hook found in a scope.
+type Registration struct {
+	Scope Scope
+	Hook  Hook
+}
+
+// Narrow reports whether h, as found in a settings file, fires for other
+// tools than the hook ensemble registers: an entry left by an older version,
+// such as one matching only Write|Edit.
+func (h Hook) Narrow() bool {
+	for _, c := range Hooks {
+		if c.Event == h.Event && c.Command == h.Command {
+			return c.Matcher != h.Matcher
+		}
+	}
+	return false
+}
+
+// Find returns the ensemble hooks registered in scope, each with the
+// matcher its entry has. A missing or blank file has none; one that does
+// not parse is an error.
+func Find(l Locations, scope Scope) ([]Hook, error) {
+	path := l.Path(scope)
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path) // #nosec G304
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+	settings, err := jsonedit.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	var found []Hook
+	for _, h := range Hooks {
+		if entry := hookEntry(settings, h); entry != nil {
+			found = append(found, Hook{Event: h.Event, Matcher: matcher(entry), Command: h.Command})
+		}
+	}
+	return found, nil
+}
+
+// registered returns where a scope other than except already runs h, if
+// any. A settings file that does not parse is an error: what it registers
+// cannot be known.
+func registered(l Locations, except Scope, h Hook) (Registration, bool, error) {
+	for _, s := range Scopes {
+		if s == except {
+			continue
+		}
+		found, err := Find(l, s)
+		if err != nil {
+			return Registration{}, false, fmt.Errorf("%w; fix it and run again, nothing was changed", err)
+		}
+		for _, f := range found {
+			if f.Event == h.Event && f.Command == h.Command {
+				return Registration{Scope: s, Hook: f}, true, nil
+			}
+		}
+	}
+	return Registration{}, false, nil
+}
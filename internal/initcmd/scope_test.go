@@ -0,0 +1,137 @@
+package initcmd_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gauthierbraillon/ensemble/internal/initcmd"
+)
+
+func TestScopes(t *testing.T) {
+	l := initcmd.Locations{Project: "/work/p", Home: "/home/me"}
+	assert.Equal(t, filepath.FromSlash("/work/p/.claude/settings.json"), l.Path(initcmd.Project))
+	assert.Equal(t, filepath.FromSlash("/work/p/.claude/settings.local.json"), l.Path(initcmd.Local))
+	assert.Equal(t, filepath.FromSlash("/home/me/.claude/settings.json"), l.Path(initcmd.User))
+	assert.Empty(t, initcmd.Locations{Project: "/work/p"}.Path(initcmd.User), "no home, no user scope")
+
+	for _, name := range []string{"project", "local", "user"} {
+		s, err := initcmd.ParseScope(name)
+		require.NoError(t, err)
+		assert.Equal(t, name, string(s))
+	}
+	_, err := initcmd.ParseScope("global")
+	assert.ErrorContains(t, err, "unknown scope")
+}
+
+func TestPlanInstallScopes(t *testing.T) {
+	locations := func(t *testing.T) initcmd.Locations {
+		t.Helper()
+		return initcmd.Locations{Project: t.TempDir(), Home: t.TempDir()}
+	}
+	install := func(t *testing.T, l initcmd.Locations, s initcmd.Scope) []initcmd.Registration {
+		t.Helper()
+		c, elsewhere, err := initcmd.PlanInstall(l, s)
+		require.NoError(t, err)
+		require.NoError(t, c.Apply())
+		return elsewhere
+	}
+
+	t.Run("writes the scope's own file", func(t *testing.T) {
+		l := locations(t)
+		install(t, l, initcmd.Local)
+		assert.FileExists(t, l.Path(initcmd.Local))
+		assert.NoFileExists(t, l.Path(initcmd.Project))
+		assert.NoFileExists(t, l.Path(initcmd.User))
+	})
+
+	t.Run("leaves out hooks another scope already runs", func(t *testing.T) {
+		l := locations(t)
+		install(t, l, initcmd.User)
+
+		c, elsewhere, err := initcmd.PlanInstall(l, initcmd.Project)
+		require.NoError(t, err)
+		assert.True(t, c.Empty(), "nothing to register twice")
+		require.Len(t, elsewhere, len(initcmd.Hooks))
+		assert.Equal(t, initcmd.Registration{Scope: initcmd.User, Hook: initcmd.Hooks[0]}, elsewhere[0])
+	})
+
+	t.Run("adds only the hooks missing everywhere", func(t *testing.T) {
+		l := locations(t)
+		require.NoError(t, os.MkdirAll(filepath.Dir(l.Path(initcmd.Project)), 0755))
+		require.NoError(t, os.WriteFile(l.Path(initcmd.Project),
+			[]byte(`{"hooks":{"PreToolUse":[{"matcher":"Write|Edit|MultiEdit|NotebookEdit|Bash","hooks":[{"type":"command","command":"ensemble hook"}]}]}}`), 0644))
+
+		elsewhere := install(t, l, initcmd.Local)
+		assert.Equal(t, []initcmd.Registration{{Scope: initcmd.Project, Hook: initcmd.Hooks[0]}}, elsewhere)
+		data, err := os.ReadFile(l.Path(initcmd.Local))
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), `"ensemble hook"`)
+		assert.Contains(t, string(data), `"ensemble hook --phase post"`)
+	})
+
+	t.Run("reports the matcher a hook has in another scope", func(t *testing.T) {
+		l := locations(t)
+		require.NoError(t, os.MkdirAll(filepath.Dir(l.Path(initcmd.Project)), 0755))
+		require.NoError(t, os.WriteFile(l.Path(initcmd.Project),
+			[]byte(`{"hooks":{"PreToolUse":[{"matcher":"Write|Edit","hooks":[{"type":"command","command":"ensemble hook"}]}]}}`), 0644))
+
+		elsewhere := install(t, l, initcmd.Local)
+		require.Len(t, elsewhere, 1)
+		assert.Equal(t, "Write|Edit", elsewhere[0].Hook.Matcher)
+		assert.True(t, elsewhere[0].Hook.Narrow())
+
+		c, _, err := initcmd.PlanInstall(l, initcmd.Project)
+		require.NoError(t, err)
+		assert.Contains(t, string(c.After), `"matcher": "Write|Edit|MultiEdit|NotebookEdit|Bash"`, "installing to its own scope upgrades it")
+	})
+
+	t.Run("refuses when another scope does not parse", func(t *testing.T) {
+		l := locations(t)
+		require.NoError(t, os.MkdirAll(filepath.Dir(l.Path(initcmd.User)), 0755))
+		require.NoError(t, os.WriteFile(l.Path(initcmd.User), []byte("{"), 0644))
+		_, _, err := initcmd.PlanInstall(l, initcmd.Project)
+		assert.ErrorContains(t, err, l.Path(initcmd.User))
+	})
+
+	t.Run("removes from the named scope only", func(t *testing.T) {
+		l := locations(t)
+		install(t, l, initcmd.User)
+		_, removed, err := initcmd.PlanRemove(l, initcmd.Project)
+		require.NoError(t, err)
+		assert.Empty(t, removed)
+
+		c, removed, err := initcmd.PlanRemove(l, initcmd.User)
+		require.NoError(t, err)
+		assert.Len(t, removed, len(initcmd.Hooks))
+		require.NoError(t, c.Apply())
+		_, elsewhere, err := initcmd.PlanInstall(l, initcmd.Project)
+		require.NoError(t, err)
+		assert.Empty(t, elsewhere)
+	})
+}
+
+func TestFind(t *testing.T) {
+	l := initcmd.Locations{Project: t.TempDir(), Home: t.TempDir()}
+	found, err := initcmd.Find(l, initcmd.Project)
+	require.NoError(t, err)
+	assert.Empty(t, found, "no settings file")
+
+	_, err = initcmd.WriteSettings(l.Project)
+	require.NoError(t, err)
+	found, err = initcmd.Find(l, initcmd.Project)
+	require.NoError(t, err)
+	assert.Equal(t, initcmd.Hooks, found)
+
+	require.NoError(t, os.WriteFile(l.Path(initcmd.Local), []byte(" \n\t"), 0644))
+	found, err = initcmd.Find(l, initcmd.Local)
+	require.NoError(t, err, "a blank file reads as {}")
+	assert.Empty(t, found)
+
+	require.NoError(t, os.WriteFile(l.Path(initcmd.Local), []byte("{"), 0644))
+	_, err = initcmd.Find(l, initcmd.Local)
+	assert.Error(t, err)
+}
@@ -0,0 +1,335 @@
+// Package jsonedit edits JSON documents that people also edit by hand, such
+// as Claude Code's settings.json. Objects keep their keys in the order they
+// were written, and whatever an edit leaves alone is written back exactly as
+// it was read; only the objects that changed are laid out afresh, in the
+// document's own indentation.
+package jsonedit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Object is a JSON object that remembers the order of its keys. Its values
+// are *Object, []interface{}, string, json.Number, bool or nil.
+type Object struct {
+	keys   []string
+	values map[string]interface{}
+
+	// raw is the object's source while it is unchanged, and rawValues the
+	// source of its other values, by key.
+	raw       []byte
+	rawValues map[string][]byte
+	// indent is the document's indentation unit, set on the root.
+	indent string
+}
+
+// NewObject returns an empty object.
+func NewObject() *Object {
+	return &Object{values: map[string]interface{}{}, rawValues: map[string][]byte{}}
+}
+
+// Get returns the value under key.
+func (o *Object) Get(key string) (interface{}, bool) {
+	v, ok := o.values[key]
+	return v, ok
+}
+
+// Set stores v under key, in place when key exists and last otherwise.
+func (o *Object) Set(key string, v interface{}) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = v
+	o.raw = nil
+	delete(o.rawValues, key)
+}
+
+// Delete removes key, if present.
+func (o *Object) Delete(key string) {
+	if _, ok := o.values[key]; !ok {
+		return
+	}
+	delete(o.values, key)
+	delete(o.rawValues, key)
+	o.raw = nil
+	for i, k := range o.keys {
+		if k == key {
+			o.keys = append(o.keys[:i], o.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// Keys returns the keys in document order.
+func (o *Object) Keys() []string {
+	return append([]string(nil), o.keys...)
+}
+
+// Len is the number of keys.
+func (o *Object) Len() int {
+	return len(o.keys)
+}
+
+// Parse decodes a document whose top-level value is an object. Malformed
+// documents return a *SyntaxError.
+func Parse(data []byte) (*Object, error) {
+	p := parser{data: data, dec: json.NewDecoder(bytes.NewReader(data))}
+	// The full decoder reports errors where a reader would look for them,
+	// which the token stream does not always do.
+	var probe interface{}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, p.syntaxError(err)
+	}
+	p.dec.UseNumber()
+	v, err := p.value()
+	if err != nil {
+		return nil, p.syntaxError(err)
+	}
+	o, ok := v.(*Object)
+	if !ok {
+		return nil, errors.New("top-level value is not an object")
+	}
+	o.indent = indentOf(data)
+	return o, nil
+}
+
+type parser struct {
+	data []byte
+	dec  *json.Decoder
+}
+
+// start is the offset of the next token, past the separators before it.
+func (p *parser) start() int {
+	i := int(p.dec.InputOffset())
+	for i < len(p.data) && strings.IndexByte(" \t\r\n:,", p.data[i]) >= 0 {
+		i++
+	}
+	return i
+}
+
+func (p *parser) value() (interface{}, error) {
+	start := p.start()
+	tok, err := p.dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		o := NewObject()
+		for p.dec.More() {
+			key, err := p.dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			valueStart := p.start()
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			o.Set(key.(string), v)
+			if _, ok := v.(*Object); !ok {
+				o.rawValues[key.(string)] = p.data[valueStart:p.dec.InputOffset()]
+			}
+		}
+		if _, err := p.dec.Token(); err != nil {
+			return nil, err
+		}
+		o.raw = p.data[start:p.dec.InputOffset()]
+		return o, nil
+	case json.Delim('['):
+		a := []interface{}{}
+		for p.dec.More() {
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, v)
+		}
+		_, err := p.dec.Token()
+		return a, err
+	}
+	return tok, nil
+}
+
+// syntaxError locates a decoding error in the document.
+func (p *parser) syntaxError(err error) error {
+	var syntax *json.SyntaxError
+	switch {
+	case errors.As(err, &syntax) && syntax.Error() != "unexpected end of JSON input":
+		return p.locate(int(syntax.Offset)-1, syntax.Error())
+	case syntax != nil || err == io.EOF || err == io.ErrUnexpectedEOF:
+		return p.locate(len(p.data), "unexpected end of document")
+	}
+	return err
+}
+
+func (p *parser) locate(offset int, msg string) *SyntaxError {
+	offset = max(0, min(offset, len(p.data)))
+	lines := strings.Split(string(p.data[:offset]), "\n")
+	e := &SyntaxError{Line: len(lines), Column: len(lines[len(lines)-1]) + 1, Msg: msg}
+	all := strings.Split(string(p.data), "\n")
+	e.Source = all[e.Line-1]
+	if e.Line > 1 {
+		e.Before = all[e.Line-2]
+	}
+	return e
+}
+
+// SyntaxError is a malformed document, located by line and column.
+type SyntaxError struct {
+	Line, Column int
+	Msg          string
+	// Source is the offending line, and Before the one above it.
+	Source, Before string
+}
+
+// Error quotes the offending line, marked with a caret under the column.
+func (e *SyntaxError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "line %d, column %d: %s\n", e.Line, e.Column, e.Msg)
+	width := len(fmt.Sprint(e.Line))
+	if e.Line > 1 {
+		fmt.Fprintf(&b, "  %*d | %s\n", width, e.Line-1, e.Before)
+	}
+	fmt.Fprintf(&b, "> %*d | %s\n", width, e.Line, e.Source)
+	fmt.Fprintf(&b, "  %*s | %s^", width, "", caretPad(e.Source, e.Column))
+	return b.String()
+}
+
+// caretPad is the blank run that puts a caret under column, keeping tabs so
+// it lines up however they are shown.
+func caretPad(line string, column int) string {
+	var pad strings.Builder
+	for i := 0; i < column-1 && i < len(line); i++ {
+		if line[i] == '\t' {
+			pad.WriteByte('\t')
+		} else {
+			pad.WriteByte(' ')
+		}
+	}
+	return pad.String()
+}
+
+// indentOf is the indentation of the first indented line, two spaces when
+// the document has none.
+func indentOf(data []byte) string {
+	for _, line := range strings.Split(string(data), "\n")[1:] {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed != "" && len(trimmed) < len(line) {
+			return line[:len(line)-len(trimmed)]
+		}
+	}
+	return "  "
+}
+
+// Marshal encodes the object with a final newline. Parts left unchanged
+// since Parse are written as they were read; changed objects get one key or
+// element per line, in the parsed document's indentation.
+func (o *Object) Marshal() ([]byte, error) {
+	indent := o.indent
+	if indent == "" {
+		indent = "  "
+	}
+	var b bytes.Buffer
+	if err := write(&b, o, nil, indent, ""); err != nil {
+		return nil, err
+	}
+	b.WriteByte('\n')
+	return b.Bytes(), nil
+}
+
+// clean reports whether v is unchanged since Parse.
+func clean(v interface{}) bool {
+	switch v := v.(type) {
+	case *Object:
+		if v.raw == nil {
+			return false
+		}
+		for _, child := range v.values {
+			if !clean(child) {
+				return false
+			}
+		}
+	case []interface{}:
+		for _, e := range v {
+			if !clean(e) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// write encodes v at the indentation prefix; raw is its source, if known.
+func write(b *bytes.Buffer, v interface{}, raw []byte, indent, prefix string) error {
+	if o, ok := v.(*Object); ok {
+		raw = o.raw
+	}
+	if raw != nil && clean(v) {
+		b.Write(raw)
+		return nil
+	}
+	inner := prefix + indent
+	switch v := v.(type) {
+	case *Object:
+		if v.Len() == 0 {
+			b.WriteString("{}")
+			return nil
+		}
+		b.WriteString("{\n")
+		for i, k := range v.keys {
+			b.WriteString(inner)
+			if err := scalar(b, k); err != nil {
+				return err
+			}
+			b.WriteString(": ")
+			if err := write(b, v.values[k], v.rawValues[k], indent, inner); err != nil {
+				return err
+			}
+			separator(b, i, v.Len())
+		}
+		b.WriteString(prefix + "}")
+	case []interface{}:
+		if len(v) == 0 {
+			b.WriteString("[]")
+			return nil
+		}
+		b.WriteString("[\n")
+		for i, e := range v {
+			b.WriteString(inner)
+			if err := write(b, e, nil, indent, inner); err != nil {
+				return err
+			}
+			separator(b, i, len(v))
+		}
+		b.WriteString(prefix + "]")
+	default:
+		return scalar(b, v)
+	}
+	return nil
+}
+
+func separator(b *bytes.Buffer, i, n int) {
+	if i < n-1 {
+		b.WriteByte(',')
+	}
+	b.WriteByte('\n')
+}
+
+// scalar encodes v as encoding/json would, but leaves <, > and & alone so
+// shell commands read as written.
+func scalar(b *bytes.Buffer, v interface{}) error {
+	var s strings.Builder
+	enc := json.NewEncoder(&s)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("encode %v: %w", v, err)
+	}
+	b.WriteString(strings.TrimSuffix(s.String(), "\n"))
+	return nil
+}
@@ -0,0 +1,99 @@
+package jsonedit_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gauthierbraillon/ensemble/internal/jsonedit"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("round-trips key order and numbers", func(t *testing.T) {
+		in := "{\n  \"zeta\": 1.50,\n  \"alpha\": {\n    \"b\": [\n      true,\n      null\n    ],\n    \"a\": \"x && y > z\"\n  },\n  \"empty\": {},\n  \"none\": []\n}\n"
+		o, err := jsonedit.Parse([]byte(in))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"zeta", "alpha", "empty", "none"}, o.Keys())
+		out, err := o.Marshal()
+		require.NoError(t, err)
+		assert.Equal(t, in, string(out))
+	})
+
+	t.Run("writes back what an edit leaves alone", func(t *testing.T) {
+		in := "{\n\t\"env\": {\"B\":  \"1\", \"A\": 2e3},\n\t\"hooks\": {\n\t\t\"Stop\": [ {\"command\": \"notify\"} ]\n\t}\n}\n"
+		o, err := jsonedit.Parse([]byte(in))
+		require.NoError(t, err)
+		v, _ := o.Get("hooks")
+		v.(*jsonedit.Object).Set("PreToolUse", []interface{}{})
+
+		out, err := o.Marshal()
+		require.NoError(t, err)
+		assert.Equal(t, "{\n\t\"env\": {\"B\":  \"1\", \"A\": 2e3},\n\t\"hooks\": {\n\t\t\"Stop\": [ {\"command\": \"notify\"} ],\n\t\t\"PreToolUse\": []\n\t}\n}\n", string(out))
+	})
+
+	t.Run("lays out a compact document in two spaces once changed", func(t *testing.T) {
+		o, err := jsonedit.Parse([]byte(`{"a": [1,2]}`))
+		require.NoError(t, err)
+		o.Set("b", true)
+		out, err := o.Marshal()
+		require.NoError(t, err)
+		assert.Equal(t, "{\n  \"a\": [1,2],\n  \"b\": true\n}\n", string(out))
+	})
+
+	for name, in := range map[string]string{
+		"not an object":  `[1, 2]`,
+		"trailing data":  `{} {}`,
+		"malformed":      `{"a": }`,
+		"unterminated":   `{"a": 1`,
+		"empty document": ``,
+	} {
+		t.Run("rejects "+name, func(t *testing.T) {
+			_, err := jsonedit.Parse([]byte(in))
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("points at the error", func(t *testing.T) {
+		_, err := jsonedit.Parse([]byte("{\n  \"hooks\": {\n    \"Stop\": ]\n  }\n}\n"))
+		var syntax *jsonedit.SyntaxError
+		require.ErrorAs(t, err, &syntax)
+		assert.Equal(t, 3, syntax.Line)
+		assert.Equal(t, 13, syntax.Column)
+		assert.Contains(t, err.Error(), "line 3, column 13: invalid character ']' looking for beginning of value")
+		assert.Contains(t, err.Error(), "\n"+
+			"  2 |   \"hooks\": {\n"+
+			"> 3 |     \"Stop\": ]\n"+
+			"    |             ^", err.Error())
+	})
+
+	t.Run("points past the end of a truncated document", func(t *testing.T) {
+		_, err := jsonedit.Parse([]byte("{\"a\": 1"))
+		var syntax *jsonedit.SyntaxError
+		require.ErrorAs(t, err, &syntax)
+		assert.Equal(t, 1, syntax.Line)
+		assert.Equal(t, 8, syntax.Column)
+	})
+}
+
+func TestObject(t *testing.T) {
+	o := jsonedit.NewObject()
+	o.Set("b", "1")
+	o.Set("a", "2")
+	o.Set("b", "3")
+	assert.Equal(t, []string{"b", "a"}, o.Keys(), "setting an existing key keeps its place")
+	v, ok := o.Get("b")
+	assert.True(t, ok)
+	assert.Equal(t, "3", v)
+
+	o.Delete("b")
+	o.Delete("missing")
+	assert.Equal(t, []string{"a"}, o.Keys())
+	_, ok = o.Get("b")
+	assert.False(t, ok)
+	assert.Equal(t, 1, o.Len())
+
+	out, err := o.Marshal()
+	require.NoError(t, err)
+	assert.Equal(t, "{\n  \"a\": \"2\"\n}\n", string(out))
+}
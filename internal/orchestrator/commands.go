@@ -0,0 +1,358 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gauthierbraillon/ensemble/internal/agent"
+	"github.com/gauthierbraillon/ensemble/internal/config"
+	"github.com/gauthierbraillon/ensemble/internal/diff"
+	"github.com/gauthierbraillon/ensemble/internal/git"
+	"github.com/gauthierbraillon/ensemble/internal/pipeline"
+	"github.com/gauthierbraillon/ensemble/internal/runner"
+)
+
+// Command is a slash command of the session. Help and completion are
+// generated from the registry.
+type Command struct {
+	Name string
+	// Args describes the arguments for help, empty when there are none.
+	Args string
+	Help string
+	// Complete lists the values the argument at len(args)-1, the one being
+	// typed, may take. Nil completes nothing.
+	Complete func(s *Session, args []string) []string
+	Run      func(ctx context.Context, s *Session, args []string) error
+}
+
+// errQuit ends the session.
+var errQuit = errors.New("quit")
+
+// Commands lists the slash commands, in the order help shows them.
+var Commands []Command
+
+func init() {
+	Commands = []Command{
+		{Name: "help", Help: "list the commands", Run: help},
+		{Name: "cycle", Help: "review the uncommitted changes, as ensemble cycle --worktree", Run: cycle},
+		{Name: "gates", Help: "run the quality gates in order, stopping at the first failure", Run: gates},
+		{Name: "findings", Help: "show the last review's outstanding findings", Run: findings},
+		{Name: "override", Args: "<id> <reason>", Help: "accept a finding, recording why; overriding every blocking finding of a declined change applies it", Complete: completeFindings, Run: override},
+		{Name: "model", Args: "[opus|sonnet|haiku]", Help: "show or switch the orchestrator's model tier", Complete: completeTiers, Run: model},
+		{Name: "agents", Args: "[enable|disable <agent>]", Help: "list the review agents, or turn one on or off for the session", Complete: completeAgents, Run: agents},
+		{Name: "history", Help: "show the conversation so far", Run: history},
+		{Name: "quit", Help: "end the session (or Ctrl-D)", Run: func(context.Context, *Session, []string) error { return errQuit }},
+	}
+}
+
+// lookup finds a command by name, without its slash.
+func lookup(name string) (Command, bool) {
+	for _, c := range Commands {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Command{}, false
+}
+
+// Command runs a line starting with a slash.
+func (s *Session) Command(ctx context.Context, line string) error {
+	fields := strings.Fields(strings.TrimPrefix(line, "/"))
+	if len(fields) == 0 {
+		return help(ctx, s, nil)
+	}
+	c, ok := lookup(fields[0])
+	if !ok {
+		return fmt.Errorf("unknown command /%s; /help lists them", fields[0])
+	}
+	return c.Run(ctx, s, fields[1:])
+}
+
+// complete lists the lines a partly typed command may become.
+func (s *Session) complete(line string) []string {
+	if !strings.HasPrefix(line, "/") {
+		return nil
+	}
+	fields := strings.Fields(line[1:])
+	if strings.HasSuffix(line, " ") || len(fields) == 0 {
+		fields = append(fields, "")
+	}
+	var out []string
+	if len(fields) == 1 {
+		for _, c := range Commands {
+			if strings.HasPrefix(c.Name, fields[0]) {
+				name := "/" + c.Name
+				if c.Args != "" {
+					name += " "
+				}
+				out = append(out, name)
+			}
+		}
+		return out
+	}
+	c, ok := lookup(fields[0])
+	if !ok || c.Complete == nil {
+		return nil
+	}
+	args := fields[1:]
+	typed := args[len(args)-1]
+	head := "/" + strings.Join(fields[:len(fields)-1], " ") + " "
+	for _, v := range c.Complete(s, args) {
+		if strings.HasPrefix(v, typed) {
+			out = append(out, head+v)
+		}
+	}
+	return out
+}
+
+func help(_ context.Context, s *Session, _ []string) error {
+	w := tabwriter.NewWriter(s.out, 0, 0, 2, ' ', 0)
+	for _, c := range Commands {
+		fmt.Fprintf(w, "  /%s %s\t%s\n", c.Name, c.Args, c.Help)
+	}
+	fmt.Fprintln(w, "  anything else\tasks the orchestrator")
+	return w.Flush()
+}
+
+func cycle(ctx context.Context, s *Session, _ []string) error {
+	repo, err := git.Open(ctx, s.Config.Root)
+	if err != nil {
+		return err
+	}
+	raw, tree, err := repo.Worktree(ctx)
+	if err != nil {
+		return err
+	}
+	patch, err := diff.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if len(patch.Files) == 0 {
+		fmt.Fprintln(s.out, "No uncommitted changes.")
+		return nil
+	}
+	blocking, err := s.review(ctx, agent.Change{Patch: patch, Tree: tree})
+	if err != nil {
+		return err
+	}
+	switch {
+	case len(blocking) > 0:
+		fmt.Fprintf(s.out, "%d blocking finding(s).\n", len(blocking))
+	case len(s.Findings) == 0:
+		fmt.Fprintln(s.out, "No findings.")
+	}
+	return nil
+}
+
+// gateOutputLines is how much of a failing gate's output is shown.
+const gateOutputLines = 20
+
+func gates(ctx context.Context, s *Session, _ []string) error {
+	if _, err := os.Stat(filepath.Join(s.Config.Root, "Makefile")); err != nil {
+		return fmt.Errorf("no Makefile in %s; the quality gates are not wired up", s.Config.Root)
+	}
+	for _, g := range pipeline.Gates() {
+		out, err := g.Run(ctx, s.Config.Root)
+		if err != nil {
+			fmt.Fprintf(s.out, "  FAIL  %s (make %s)\n", g.Name, g.MakeTarget)
+			lines := strings.Split(strings.TrimRight(out, "\n"), "\n")
+			for _, l := range lines[max(0, len(lines)-gateOutputLines):] {
+				fmt.Fprintln(s.out, "        "+l)
+			}
+			return fmt.Errorf("gate %s failed", g.Name)
+		}
+		fmt.Fprintf(s.out, "  pass  %s\n", g.Name)
+	}
+	return nil
+}
+
+func findings(_ context.Context, s *Session, _ []string) error {
+	if len(s.Findings) == 0 {
+		fmt.Fprintln(s.out, "No outstanding findings.")
+		return nil
+	}
+	for _, o := range s.Findings {
+		fmt.Fprintln(s.out, o)
+	}
+	if s.pending != nil {
+		fmt.Fprintln(s.out, "Declined change waiting on them:", strings.Join(s.pending.paths(), ", "))
+	}
+	return nil
+}
+
+func override(_ context.Context, s *Session, args []string) error {
+	if len(args) < 2 {
+		return errors.New("usage: /override <id> <reason>")
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+	if err != nil {
+		return fmt.Errorf("finding ID %q is not a number", args[0])
+	}
+	i := s.outstanding(id)
+	if i < 0 {
+		return fmt.Errorf("no outstanding finding #%d; /findings lists them", id)
+	}
+	o := s.Findings[i]
+	s.Findings = append(s.Findings[:i], s.Findings[i+1:]...)
+	reason := strings.Join(args[1:], " ")
+	fmt.Fprintf(s.out, "Overrode #%d.\n", id)
+	s.record(Entry{Kind: KindDecision, Decision: Overridden, Finding: id, Text: reason})
+	s.note(fmt.Sprintf("The user overrode %s because: %s", FormatFinding(o.Finding), reason))
+
+	if s.pending == nil || s.blocked() {
+		return nil
+	}
+	p := *s.pending
+	if err := s.apply(p); err != nil {
+		return err
+	}
+	s.record(Entry{Kind: KindDecision, Decision: Applied, Files: p.paths()})
+	s.note("With its blocking findings overridden, the change to " + strings.Join(p.paths(), ", ") + " was applied.")
+	return nil
+}
+
+// outstanding finds the index of the finding with id, -1 if none.
+func (s *Session) outstanding(id int) int {
+	for i, o := range s.Findings {
+		if o.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// blocked reports whether any outstanding finding blocks.
+func (s *Session) blocked() bool {
+	for _, o := range s.Findings {
+		if o.Blocking {
+			return true
+		}
+	}
+	return false
+}
+
+func completeFindings(s *Session, args []string) []string {
+	if len(args) != 1 {
+		return nil
+	}
+	var ids []string
+	for _, o := range s.Findings {
+		ids = append(ids, strconv.Itoa(o.ID))
+	}
+	return ids
+}
+
+func model(_ context.Context, s *Session, args []string) error {
+	if len(args) == 0 {
+		current := s.Model
+		if s.Runner == nil {
+			current += " (no model available)"
+		}
+		fmt.Fprintln(s.out, "Model:", current)
+		return nil
+	}
+	m, err := runner.ModelForTier(args[0])
+	if err != nil {
+		return err
+	}
+	r, err := s.Connect(m)
+	if err != nil {
+		return err
+	}
+	if r == nil {
+		return fmt.Errorf("no model available; set ANTHROPIC_API_KEY")
+	}
+	s.Model, s.Runner = m, r
+	s.record(Entry{Kind: KindModel, Model: m})
+	fmt.Fprintln(s.out, "Model:", m)
+	return nil
+}
+
+func completeTiers(_ *Session, args []string) []string {
+	if len(args) != 1 {
+		return nil
+	}
+	tiers := make([]string, 0, len(runner.Tiers))
+	for t := range runner.Tiers {
+		tiers = append(tiers, t)
+	}
+	sort.Strings(tiers)
+	return tiers
+}
+
+func agents(_ context.Context, s *Session, args []string) error {
+	if len(args) == 0 {
+		for _, name := range config.Agents {
+			state := "enabled"
+			if !s.Config.Enabled(name) {
+				state = "disabled"
+			}
+			fmt.Fprintf(s.out, "  %-20s %s\n", name, state)
+		}
+		return nil
+	}
+	if len(args) != 2 || (args[0] != "enable" && args[0] != "disable") {
+		return errors.New("usage: /agents [enable|disable <agent>]")
+	}
+	name := args[1]
+	if !config.KnownAgent(name) {
+		return fmt.Errorf("unknown agent %q (want one of %s)", name, strings.Join(config.Agents, ", "))
+	}
+	enabled := args[0] == "enable"
+	s.setAgent(name, enabled)
+	s.record(Entry{Kind: KindAgent, Agent: name, Enabled: &enabled})
+	fmt.Fprintf(s.out, "%s %sd for this session.\n", name, args[0])
+	return nil
+}
+
+// setAgent turns a review agent on or off for the session alone.
+func (s *Session) setAgent(name string, enabled bool) {
+	// Copy the map so the change stays with this session's configuration.
+	updated := make(map[string]config.Agent, len(s.Config.Agents)+1)
+	for k, v := range s.Config.Agents {
+		updated[k] = v
+	}
+	a := updated[name]
+	a.Enabled = &enabled
+	updated[name] = a
+	s.Config.Agents = updated
+}
+
+func completeAgents(_ *Session, args []string) []string {
+	switch len(args) {
+	case 1:
+		return []string{"enable", "disable"}
+	case 2:
+		return config.Agents
+	}
+	return nil
+}
+
+func history(_ context.Context, s *Session, _ []string) error {
+	if len(s.History) == 0 {
+		fmt.Fprintln(s.out, "Nothing said yet.")
+		return nil
+	}
+	for _, t := range s.History {
+		fmt.Fprintf(s.out, "%s: ", t.Role)
+		if t.Role != Orchestrator {
+			fmt.Fprintln(s.out, t.Text)
+			continue
+		}
+		w := &proseWriter{out: s.out}
+		if _, err := w.Write([]byte(t.Text)); err != nil {
+			return err
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package orchestrator
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gauthierbraillon/ensemble/internal/agent"
+	"github.com/gauthierbraillon/ensemble/internal/config"
+	"github.com/gauthierbraillon/ensemble/internal/runner"
+)
+
+type reply string
+
+func (r reply) Run(context.Context, string) (string, error) { return string(r), nil }
+
+// commandSession runs input through a session whose orchestrator always
+// proposes bar/bar.go and whose review returns findings.
+func commandSession(t *testing.T, input string, findings ...agent.Finding) (*Session, *strings.Builder, *[]config.Config) {
+	t.Helper()
+	var out strings.Builder
+	var reviewedWith []config.Config
+	s := New(strings.NewReader(input), &out)
+	s.Config = config.Default()
+	s.Config.Root = t.TempDir()
+	s.Model = "claude-sonnet-4-6"
+	s.Connect = func(string) (runner.Runner, error) {
+		return reply("<file path=\"bar/bar.go\">\npackage bar\n</file>\n"), nil
+	}
+	s.Runner, _ = s.Connect(s.Model)
+	s.Review = func(_ context.Context, cfg config.Config, _ agent.Change) ([]agent.Finding, error) {
+		reviewedWith = append(reviewedWith, cfg)
+		return findings, nil
+	}
+	require.NoError(t, s.Run(context.Background()))
+	return s, &out, &reviewedWith
+}
+
+var noTest = agent.Finding{Agent: "testing-quality", Verdict: agent.Block, Severity: agent.Critical, Finding: "no test"}
+
+func TestHelpListsEveryCommand(t *testing.T) {
+	_, out, _ := commandSession(t, "/help\n")
+	for _, c := range Commands {
+		assert.Contains(t, out.String(), "/"+c.Name+" "+c.Args)
+		assert.Contains(t, out.String(), c.Help)
+	}
+}
+
+func TestUnknownCommand(t *testing.T) {
+	_, out, _ := commandSession(t, "/frobnicate\n")
+	assert.Contains(t, out.String(), "error: unknown command /frobnicate; /help lists them")
+}
+
+func TestQuit(t *testing.T) {
+	s, _, _ := commandSession(t, "/quit\nhello\n")
+	assert.Empty(t, s.History, "nothing after /quit is read")
+}
+
+func TestOverride(t *testing.T) {
+	t.Run("applies a declined change once its blocks are overridden", func(t *testing.T) {
+		warn := agent.Finding{Agent: "ux-design", Verdict: agent.Warn, Severity: agent.Low, Finding: "wording"}
+		s, out, _ := commandSession(t, "add bar\nn\n/findings\n/override 2 fine\n/override 1 spike, deleted tomorrow\n", noTest, warn)
+
+		assert.Contains(t, out.String(), "#1   block critical testing-quality: no test")
+		assert.Contains(t, out.String(), "Declined change waiting on them: bar/bar.go")
+		assert.Contains(t, out.String(), "Overrode #2.\nensemble> ", "a warning alone does not apply it")
+		assert.Contains(t, out.String(), "Overrode #1.\nApplied: bar/bar.go")
+		_, err := os.Stat(filepath.Join(s.Config.Root, "bar", "bar.go"))
+		assert.NoError(t, err)
+		assert.Empty(t, s.Findings)
+
+		var notes []string
+		for _, turn := range s.History {
+			if turn.Role == Ensemble {
+				notes = append(notes, turn.Text)
+			}
+		}
+		require.Len(t, notes, 4)
+		assert.Contains(t, notes[2], "overrode block critical testing-quality: no test because: spike, deleted tomorrow")
+		assert.Contains(t, notes[3], "was applied")
+	})
+
+	t.Run("does not overwrite files changed since the proposal", func(t *testing.T) {
+		var out strings.Builder
+		s := New(strings.NewReader(""), &out)
+		s.Config = config.Default()
+		s.Config.Root = t.TempDir()
+		s.Connect = func(string) (runner.Runner, error) {
+			return reply("<file path=\"bar/bar.go\">\npackage bar\n</file>\n"), nil
+		}
+		s.Runner, _ = s.Connect("")
+		s.Review = func(context.Context, config.Config, agent.Change) ([]agent.Finding, error) {
+			return []agent.Finding{noTest}, nil
+		}
+		s.lines = plainLines{in: bufio.NewScanner(strings.NewReader("n\n")), out: &out}
+		ctx := context.Background()
+		require.NoError(t, s.Ask(ctx, "add bar"))
+		require.NotNil(t, s.pending)
+
+		path := filepath.Join(s.Config.Root, "bar", "bar.go")
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0750))
+		require.NoError(t, os.WriteFile(path, []byte("package bar\n\n// Written by hand meanwhile.\n"), 0600))
+
+		err := s.Command(ctx, "/override 1 spike")
+		assert.ErrorContains(t, err, "not applied: bar/bar.go changed since the change was proposed")
+		data, rerr := os.ReadFile(path)
+		require.NoError(t, rerr)
+		assert.Contains(t, string(data), "Written by hand", "the newer work is kept")
+		assert.Nil(t, s.pending, "the stale change is dropped")
+		assert.Contains(t, s.History[len(s.History)-1].Text, "was not applied: bar/bar.go changed since it was proposed")
+	})
+
+	t.Run("requires an outstanding finding and a reason", func(t *testing.T) {
+		_, out, _ := commandSession(t, "/override 1\n/override x why\n/override 7 why\n")
+		assert.Contains(t, out.String(), "usage: /override <id> <reason>")
+		assert.Contains(t, out.String(), `finding ID "x" is not a number`)
+		assert.Contains(t, out.String(), "no outstanding finding #7")
+	})
+}
+
+func TestModel(t *testing.T) {
+	s, out, _ := commandSession(t, "/model\n/model haiku\n/model turbo\n")
+	assert.Contains(t, out.String(), "Model: claude-sonnet-4-6\n")
+	assert.Contains(t, out.String(), "Model: claude-haiku-4-5-20251001\n")
+	assert.Contains(t, out.String(), "invalid tier")
+	assert.Equal(t, "claude-haiku-4-5-20251001", s.Model)
+}
+
+func TestAgents(t *testing.T) {
+	s, out, reviewedWith := commandSession(t, "/agents disable security\n/agents\nadd bar\n/agents enable nobody\n")
+	assert.Contains(t, out.String(), "security disabled for this session.")
+	assert.Regexp(t, `security +disabled`, out.String())
+	assert.Regexp(t, `ux-design +enabled`, out.String())
+	require.Len(t, *reviewedWith, 1)
+	assert.False(t, (*reviewedWith)[0].Enabled("security"), "reviews run with the session's agents")
+	assert.True(t, (*reviewedWith)[0].Enabled("software-engineering"))
+	assert.Contains(t, out.String(), `unknown agent "nobody"`)
+	assert.False(t, s.Config.Enabled("security"))
+}
+
+func TestHistory(t *testing.T) {
+	_, out, _ := commandSession(t, "/history\nadd bar\n/history\n")
+	assert.Contains(t, out.String(), "Nothing said yet.")
+	assert.Contains(t, out.String(), "user: add bar\norchestrator:   [proposes bar/bar.go]\nensemble: Applied the change to bar/bar.go.")
+}
+
+func TestCycleCommand(t *testing.T) {
+	var out strings.Builder
+	s := New(strings.NewReader(""), &out)
+	s.Config = config.Default()
+	s.Config.Root = t.TempDir()
+	var reviewed []agent.Change
+	s.Review = func(_ context.Context, _ config.Config, c agent.Change) ([]agent.Finding, error) {
+		reviewed = append(reviewed, c)
+		return []agent.Finding{noTest}, nil
+	}
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "commit.gpgsign=false"}, args...)...)
+		cmd.Dir = s.Config.Root
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, "%s", out)
+	}
+	git("init", "-q")
+	git("commit", "-q", "--allow-empty", "-m", "initial")
+
+	require.NoError(t, s.Command(context.Background(), "/cycle"))
+	assert.Contains(t, out.String(), "No uncommitted changes.")
+	assert.Empty(t, reviewed)
+
+	require.NoError(t, os.WriteFile(filepath.Join(s.Config.Root, "bar.go"), []byte("package bar\n"), 0600))
+	require.NoError(t, s.Command(context.Background(), "/cycle"))
+	require.Len(t, reviewed, 1)
+	assert.Equal(t, "bar.go", reviewed[0].Patch.Files[0].Path())
+	assert.Contains(t, out.String(), "#1   block critical testing-quality: no test")
+	assert.Contains(t, out.String(), "1 blocking finding(s).")
+	assert.Len(t, s.Findings, 1)
+}
+
+func TestGatesCommand(t *testing.T) {
+	var out strings.Builder
+	s := New(strings.NewReader(""), &out)
+	s.Config.Root = t.TempDir()
+	assert.ErrorContains(t, s.Command(context.Background(), "/gates"), "no Makefile")
+
+	makefile := "lint typecheck secrets sast build:\n\t@true\ntest:\n\t@echo 'FAIL: TestAdd'; exit 1\n"
+	require.NoError(t, os.WriteFile(filepath.Join(s.Config.Root, "Makefile"), []byte(makefile), 0600))
+	err := s.Command(context.Background(), "/gates")
+	assert.ErrorContains(t, err, "gate Unit tests failed")
+	assert.Contains(t, out.String(), "  pass  Lint\n")
+	assert.Contains(t, out.String(), "  pass  Build\n  FAIL  Unit tests (make test)\n        FAIL: TestAdd\n")
+	assert.NotContains(t, out.String(), "Vuln check", "the first failure stops the pipeline")
+}
+
+func TestComplete(t *testing.T) {
+	s := New(strings.NewReader(""), &strings.Builder{})
+	s.Findings = []Outstanding{{ID: 3}, {ID: 12}}
+
+	for line, want := range map[string][]string{
+		"":                  nil,
+		"hello":             nil,
+		"/":                 {"/help", "/cycle", "/gates", "/findings", "/override ", "/model ", "/agents ", "/history", "/quit"},
+		"/h":                {"/help", "/history"},
+		"/mo":               {"/model "},
+		"/model ":           {"/model haiku", "/model opus", "/model sonnet"},
+		"/model o":          {"/model opus"},
+		"/agents d":         {"/agents disable"},
+		"/agents disable s": {"/agents disable software-engineering", "/agents disable security"},
+		"/override 1":       {"/override 12"},
+		"/override 3 ":      nil,
+		"/nope ":            nil,
+	} {
+		assert.Equal(t, want, s.complete(line), "%q", line)
+	}
+}
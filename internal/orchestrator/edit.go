@@ -0,0 +1,270 @@
+package orchestrator
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gauthierbraillon/ensemble/internal/agent"
+	"github.com/gauthierbraillon/ensemble/internal/diff"
+)
+
+// fileBlock is how the orchestrator proposes a file: its full new contents
+// between an opening and a closing tag, each on a line of its own.
+var fileBlock = regexp.MustCompile(`(?ms)^<file path="([^"\n]+)">\n(.*?)^</file>[ \t]*$`)
+
+// Edit is a file the orchestrator proposes to write, with its new contents.
+type Edit struct {
+	Path    string
+	Content string
+}
+
+// Edits finds the files a reply proposes, in the order given. A path
+// proposed twice keeps its last contents.
+func Edits(reply string) []Edit {
+	var edits []Edit
+	index := map[string]int{}
+	for _, m := range fileBlock.FindAllStringSubmatch(reply, -1) {
+		e := Edit{Path: m[1], Content: m[2]}
+		if i, ok := index[e.Path]; ok {
+			edits[i] = e
+			continue
+		}
+		index[e.Path] = len(edits)
+		edits = append(edits, e)
+	}
+	return edits
+}
+
+// guarded are the files that switch on the hooks and the review gating the
+// orchestrator, and those defining the quality gates and CI that judge its
+// work; it may not change them, however it names them. Paths are lower
+// case, for filesystems that ignore it.
+var guarded = []struct{ path, why string }{
+	{".git", "configures the hooks and review"},
+	{".claude", "configures the hooks and review"},
+	{".ensemble", "configures the hooks and review"},
+	{".ensemble.yaml", "configures the hooks and review"},
+	{"makefile", "defines the quality gates"},
+	{"gnumakefile", "defines the quality gates"},
+	{".golangci.yml", "defines the quality gates"},
+	{".golangci.yaml", "defines the quality gates"},
+	{".github/workflows", "defines the quality gates"},
+	{".goreleaser.yml", "defines the quality gates"},
+	{".goreleaser.yaml", "defines the quality gates"},
+}
+
+// checkPath refuses paths that would write outside the project under root,
+// symlinks followed, or into git's files, ensemble's own configuration or
+// the quality gates.
+func checkPath(root, p string) error {
+	clean := path.Clean(p)
+	switch {
+	case p == "" || path.IsAbs(p) || filepath.IsAbs(p) || strings.Contains(p, `\`):
+		return fmt.Errorf("%q is not a relative slash-separated path", p)
+	case clean == ".":
+		return fmt.Errorf("%q names no file", p)
+	case clean == ".." || strings.HasPrefix(clean, "../"):
+		return fmt.Errorf("%q is outside the project", p)
+	}
+	if err := checkGuarded(p, clean); err != nil {
+		return err
+	}
+	real, err := resolve(root, clean)
+	if err != nil {
+		return fmt.Errorf("%q: %w", p, err)
+	}
+	if real == ".." || strings.HasPrefix(real, "../") {
+		return fmt.Errorf("%q leads outside the project through a symlink", p)
+	}
+	return checkGuarded(p, real)
+}
+
+func checkGuarded(p, clean string) error {
+	c := strings.ToLower(clean)
+	for _, g := range guarded {
+		if c == g.path || strings.HasPrefix(c, g.path+"/") {
+			return fmt.Errorf("%q is inside %s, which %s; change it yourself", p, g.path, g.why)
+		}
+	}
+	return nil
+}
+
+// resolve follows the symlinks along the part of rel that exists under root
+// and returns where it leads, relative to root and slash-separated.
+func resolve(root, rel string) (string, error) {
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", err
+	}
+	existing, rest := filepath.Join(root, filepath.FromSlash(rel)), ""
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			break
+		}
+		rest = filepath.Join(filepath.Base(existing), rest)
+		existing = parent
+	}
+	real, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return "", err
+	}
+	out, err := filepath.Rel(realRoot, filepath.Join(real, rest))
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(out), nil
+}
+
+// proposal is a set of edits against the files under root.
+type proposal struct {
+	root  string
+	edits []Edit
+	// before holds the current contents of the files that exist.
+	before map[string]string
+}
+
+// propose reads the current contents of each edit's file, leaving out the
+// edits that change nothing.
+func propose(root string, edits []Edit) (proposal, error) {
+	p := proposal{root: root, before: map[string]string{}}
+	for _, e := range edits {
+		data, err := os.ReadFile(p.abs(e.Path))
+		if err != nil && !os.IsNotExist(err) {
+			return proposal{}, err
+		}
+		if err == nil {
+			if string(data) == e.Content {
+				continue
+			}
+			p.before[e.Path] = string(data)
+		}
+		p.edits = append(p.edits, e)
+	}
+	return p, nil
+}
+
+func (p proposal) abs(rel string) string {
+	return filepath.Join(p.root, filepath.FromSlash(rel))
+}
+
+// change is the proposal as the review agents see it: a patch against the
+// working tree and the tree it would produce.
+func (p proposal) change() (agent.Change, error) {
+	var raw strings.Builder
+	for _, e := range p.edits {
+		before, ok := p.before[e.Path]
+		fmt.Fprintf(&raw, "diff --git a/%s b/%s\n", e.Path, e.Path)
+		if !ok {
+			raw.WriteString("new file mode 100644\n")
+		}
+		raw.WriteString(diff.Unified(e.Path, before, e.Content))
+	}
+	patch, err := diff.Parse(raw.String())
+	return agent.Change{Patch: patch, Tree: p}, err
+}
+
+// ReadFile reads path as it would stand with the proposal applied.
+func (p proposal) ReadFile(path string) ([]byte, error) {
+	for _, e := range p.edits {
+		if e.Path == path {
+			return []byte(e.Content), nil
+		}
+	}
+	return os.ReadFile(p.abs(path))
+}
+
+// apply writes the edits, keeping the mode of files that exist. Every file
+// is staged beside its target before any is renamed over it, so failing to
+// write one leaves the tree as it was; should a rename fail, the error names
+// the files already written.
+func (p proposal) apply() error {
+	var staged []string
+	defer func() {
+		for _, tmp := range staged {
+			os.Remove(tmp)
+		}
+	}()
+	for _, e := range p.edits {
+		tmp, err := stage(p.abs(e.Path), e.Content)
+		if err != nil {
+			return err
+		}
+		staged = append(staged, tmp)
+	}
+	for i, e := range p.edits {
+		if err := os.Rename(staged[i], p.abs(e.Path)); err != nil {
+			if i > 0 {
+				return fmt.Errorf("%w; %s already written", err, strings.Join(p.paths()[:i], ", "))
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// stage writes content to a temporary file beside abs, with abs's mode if
+// it exists, and returns its name.
+func stage(abs, content string) (string, error) {
+	dir := filepath.Dir(abs)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return "", err
+	}
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(abs); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(abs)+".*")
+	if err != nil {
+		return "", err
+	}
+	_, err = tmp.WriteString(content)
+	if err == nil {
+		err = tmp.Chmod(mode)
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+// stale lists the files that changed on disk since the proposal read them,
+// whose edits would overwrite work done since.
+func (p proposal) stale() ([]string, error) {
+	var changed []string
+	for _, e := range p.edits {
+		before, existed := p.before[e.Path]
+		data, err := os.ReadFile(p.abs(e.Path))
+		switch {
+		case os.IsNotExist(err):
+			if existed {
+				changed = append(changed, e.Path)
+			}
+		case err != nil:
+			return nil, err
+		case !existed || string(data) != before:
+			changed = append(changed, e.Path)
+		}
+	}
+	return changed, nil
+}
+
+// paths lists the files the proposal writes.
+func (p proposal) paths() []string {
+	var paths []string
+	for _, e := range p.edits {
+		paths = append(paths, e.Path)
+	}
+	return paths
+}
@@ -0,0 +1,115 @@
+package orchestrator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gauthierbraillon/ensemble/internal/diff"
+)
+
+func TestEdits(t *testing.T) {
+	reply := "First the test.\n" +
+		"<file path=\"foo/foo_test.go\">\npackage foo\n</file>\n" +
+		"Inline <file path=\"x\"> is not a proposal.\n" +
+		"<file path=\"foo/foo.go\">\npackage foo\n\nfunc A() {}\n</file>\n" +
+		"<file path=\"foo/foo_test.go\">\npackage foo\n\n// v2\n</file>\n"
+	assert.Equal(t, []Edit{
+		{Path: "foo/foo_test.go", Content: "package foo\n\n// v2\n"},
+		{Path: "foo/foo.go", Content: "package foo\n\nfunc A() {}\n"},
+	}, Edits(reply))
+	assert.Empty(t, Edits("no files here"))
+}
+
+func TestCheckPath(t *testing.T) {
+	root := t.TempDir()
+	for _, ok := range []string{"a.go", "pkg/a.go", "./pkg/a.go", ".github/ISSUE_TEMPLATE/bug.md", "docs/Makefile.md", "sub/Makefile", ".ensemble.yaml.example"} {
+		assert.NoError(t, checkPath(root, ok), ok)
+	}
+	for _, bad := range []string{"", ".", "./", "/etc/passwd", "../a.go", "pkg/../../a.go", ".git/hooks/pre-commit", `pkg\a.go`} {
+		assert.Error(t, checkPath(root, bad), bad)
+	}
+
+	t.Run("refuses ensemble's own configuration", func(t *testing.T) {
+		for _, bad := range []string{".claude/settings.json", ".claude/settings.local.json", ".ensemble.yaml", ".ensemble/cycle.json", "./.Claude/settings.json"} {
+			assert.ErrorContains(t, checkPath(root, bad), "configures the hooks and review", bad)
+		}
+	})
+
+	t.Run("refuses the quality gates and CI", func(t *testing.T) {
+		for _, bad := range []string{"Makefile", "./GNUmakefile", ".golangci.yml", ".golangci.yaml", ".goreleaser.yml", ".goreleaser.yaml", ".github/workflows/ci.yml", ".github//Workflows/release.yaml"} {
+			assert.ErrorContains(t, checkPath(root, bad), "defines the quality gates", bad)
+		}
+	})
+
+	t.Run("follows symlinks", func(t *testing.T) {
+		outside := t.TempDir()
+		require.NoError(t, os.Symlink(outside, filepath.Join(root, "out")))
+		require.NoError(t, os.Mkdir(filepath.Join(root, ".claude"), 0750))
+		require.NoError(t, os.Symlink(filepath.Join(root, ".claude"), filepath.Join(root, "settings")))
+		require.NoError(t, os.Symlink(filepath.Join(outside, "a.go"), filepath.Join(root, "dangling.go")))
+		require.NoError(t, os.MkdirAll(filepath.Join(root, "real"), 0750))
+		require.NoError(t, os.Symlink("real", filepath.Join(root, "alias")))
+
+		assert.ErrorContains(t, checkPath(root, "out/a.go"), "outside the project through a symlink")
+		assert.ErrorContains(t, checkPath(root, "out/new/dir/a.go"), "outside the project through a symlink")
+		assert.ErrorContains(t, checkPath(root, "settings/settings.json"), "configures the hooks and review")
+		assert.Error(t, checkPath(root, "dangling.go"))
+		assert.NoError(t, checkPath(root, "alias/a.go"), "a symlink that stays inside is fine")
+	})
+}
+
+func TestProposal(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "foo"), 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "foo", "foo.go"), []byte("package foo\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "foo", "same.go"), []byte("package foo\n"), 0600))
+
+	p, err := propose(root, []Edit{
+		{Path: "foo/foo.go", Content: "package foo\n\nfunc A() {}\n"},
+		{Path: "foo/same.go", Content: "package foo\n"},
+		{Path: "bar/bar.go", Content: "package bar\n"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"foo/foo.go", "bar/bar.go"}, p.paths(), "unchanged files are left out")
+
+	c, err := p.change()
+	require.NoError(t, err)
+	require.Len(t, c.Patch.Files, 2)
+	assert.Equal(t, diff.Modified, c.Patch.Files[0].Status)
+	assert.Equal(t, diff.Added, c.Patch.Files[1].Status)
+	data, err := c.Tree.ReadFile("bar/bar.go")
+	require.NoError(t, err)
+	assert.Equal(t, "package bar\n", string(data), "the tree shows the proposal")
+	data, err = c.Tree.ReadFile("foo/same.go")
+	require.NoError(t, err)
+	assert.Equal(t, "package foo\n", string(data), "and the working tree around it")
+
+	require.NoError(t, p.apply())
+	data, err = os.ReadFile(filepath.Join(root, "bar", "bar.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "package bar\n", string(data))
+	info, err := os.Stat(filepath.Join(root, "foo", "foo.go"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), "an existing file keeps its mode")
+
+	t.Run("writes nothing when a file cannot be staged", func(t *testing.T) {
+		root := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(root, "a.go"), []byte("package a\n"), 0600))
+		require.NoError(t, os.WriteFile(filepath.Join(root, "file"), nil, 0600))
+		p := proposal{root: root, edits: []Edit{
+			{Path: "a.go", Content: "package a\n\nfunc A() {}\n"},
+			{Path: "file/b.go", Content: "package b\n"},
+		}}
+		require.Error(t, p.apply())
+		data, err := os.ReadFile(filepath.Join(root, "a.go"))
+		require.NoError(t, err)
+		assert.Equal(t, "package a\n", string(data), "the first file is not written either")
+		entries, err := os.ReadDir(root)
+		require.NoError(t, err)
+		assert.Len(t, entries, 2, "no staged file is left behind")
+	})
+}
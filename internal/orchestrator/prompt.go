@@ -0,0 +1,32 @@
+package orchestrator
+
+import (
+	"strings"
+)
+
+// instructions open every prompt: who the orchestrator is and how it
+// proposes changes so the session can review them.
+const instructions = `You are the orchestrator of ensemble, an agent team that practises strict test-driven development with the user directing: RED (a failing test), GREEN (the simplest code that passes it), REFACTOR (improve the code with the tests green). You work in the project in the current directory.
+
+Answer in plain prose. To change a file, give its complete new contents, with the tags on lines of their own and the path relative to the project root:
+<file path="pkg/name.go">
+...the whole file...
+</file>
+Write or change a test before the code it drives. Every change you propose is reviewed by the testing-quality, software-engineering, security and ux-design agents before it is applied; a change they block is applied only if the user agrees. Lines from "ensemble" report what became of your proposals.`
+
+// prompt renders the conversation so far followed by the new request.
+func (s *Session) prompt(request string) string {
+	var b strings.Builder
+	b.WriteString(instructions)
+	b.WriteString("\n\nConversation:\n")
+	for _, t := range s.History {
+		writeTurn(&b, t)
+	}
+	writeTurn(&b, Turn{Role: User, Text: request})
+	b.WriteString("\n" + Orchestrator + ":")
+	return b.String()
+}
+
+func writeTurn(b *strings.Builder, t Turn) {
+	b.WriteString("\n" + t.Role + ": " + t.Text + "\n")
+}
@@ -0,0 +1,77 @@
+package orchestrator
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// proseWriter passes a streaming reply through to the user but for the
+// contents of the files it proposes, which show as one line each; the
+// review that follows shows what changes.
+type proseWriter struct {
+	out io.Writer
+	// pending is the start of a line that may yet turn out to open a file.
+	pending string
+	// midLine is set once the current line is known to be prose.
+	midLine bool
+	inFile  bool
+}
+
+func (w *proseWriter) Write(p []byte) (int, error) {
+	text := w.pending + string(p)
+	w.pending = ""
+	for text != "" {
+		line, rest, complete := strings.Cut(text, "\n")
+		if !complete {
+			w.partial(line)
+			break
+		}
+		text = rest
+		if err := w.line(line); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// partial handles the end of the input, short of a newline.
+func (w *proseWriter) partial(s string) {
+	if w.midLine || (!w.inFile && !strings.HasPrefix(openTag, s) && !strings.HasPrefix(s, openTag)) {
+		w.midLine = true
+		_, _ = io.WriteString(w.out, s)
+		return
+	}
+	w.pending = s
+}
+
+const openTag = `<file path="`
+
+func (w *proseWriter) line(s string) error {
+	var err error
+	switch {
+	case w.midLine:
+		_, err = io.WriteString(w.out, s+"\n")
+		w.midLine = false
+	case w.inFile:
+		w.inFile = strings.TrimRight(s, " \t") != "</file>"
+	case strings.HasPrefix(s, openTag) && strings.HasSuffix(s, `">`):
+		w.inFile = true
+		_, err = fmt.Fprintf(w.out, "  [proposes %s]\n", strings.TrimSuffix(strings.TrimPrefix(s, openTag), `">`))
+	default:
+		_, err = io.WriteString(w.out, s+"\n")
+	}
+	return err
+}
+
+// Flush ends an unterminated last line.
+func (w *proseWriter) Flush() error {
+	rest := w.pending
+	w.pending = ""
+	if w.inFile || (rest == "" && !w.midLine) {
+		return nil
+	}
+	w.midLine = false
+	_, err := io.WriteString(w.out, rest+"\n")
+	return err
+}
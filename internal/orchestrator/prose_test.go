@@ -0,0 +1,44 @@
+package orchestrator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProseWriter(t *testing.T) {
+	reply := "Here is the test.\n<file path=\"a_test.go\">\npackage a\n\nfunc TestA() {}\n</file>\nDone, <file> aside"
+
+	t.Run("hides file contents", func(t *testing.T) {
+		var out strings.Builder
+		w := &proseWriter{out: &out}
+		_, err := w.Write([]byte(reply))
+		require.NoError(t, err)
+		require.NoError(t, w.Flush())
+		assert.Equal(t, "Here is the test.\n  [proposes a_test.go]\nDone, <file> aside\n", out.String())
+	})
+
+	t.Run("copes with any split and shows prose as it comes", func(t *testing.T) {
+		var out strings.Builder
+		w := &proseWriter{out: &out}
+		for _, r := range reply {
+			_, err := w.Write([]byte(string(r)))
+			require.NoError(t, err)
+			if strings.HasSuffix(out.String(), "Here is") {
+				break
+			}
+		}
+		assert.Equal(t, "Here is", out.String(), "prose is not held back for the end of the line")
+
+		out.Reset()
+		w = &proseWriter{out: &out}
+		for _, r := range reply {
+			_, err := w.Write([]byte(string(r)))
+			require.NoError(t, err)
+		}
+		require.NoError(t, w.Flush())
+		assert.Equal(t, "Here is the test.\n  [proposes a_test.go]\nDone, <file> aside\n", out.String())
+	})
+}
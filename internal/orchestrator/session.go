@@ -0,0 +1,393 @@
+// Package orchestrator runs the interactive session: what the user asks goes
+// to a model, its reply streams back, and every change it proposes passes
+// the review agents before it touches the working tree. Passes are silent,
+// warnings are shown, and a blocked change is applied only if the user
+// says so. Lines starting with a slash are commands to the session itself.
+// A transcript of each session is kept for resuming and auditing it.
+package orchestrator
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gauthierbraillon/ensemble/internal/agent"
+	"github.com/gauthierbraillon/ensemble/internal/config"
+	"github.com/gauthierbraillon/ensemble/internal/runner"
+	"github.com/gauthierbraillon/ensemble/internal/terminal"
+)
+
+// Prompt is shown when the session waits for the user.
+const Prompt = "ensemble> "
+
+// Roles of the turns in a conversation.
+const (
+	User         = "user"
+	Orchestrator = "orchestrator"
+	// Ensemble turns tell the orchestrator what became of its proposals.
+	Ensemble = "ensemble"
+)
+
+// Turn is one message of the conversation.
+type Turn struct {
+	Role string
+	Text string
+}
+
+// Outstanding is a finding of the last review the user has not overridden.
+type Outstanding struct {
+	ID int `json:"id"`
+	agent.Finding
+	// Blocking is set when the finding meets the block threshold.
+	Blocking bool `json:"blocking"`
+}
+
+// Session is one conversation between the user and the orchestrator.
+type Session struct {
+	// Config chooses the review agents, the block threshold and the project
+	// root edits are written under. /agents changes it for the session.
+	Config config.Config
+	// Model is the model the orchestrator runs on, and Connect returns a
+	// runner for a model, nil when none is available.
+	Model   string
+	Connect func(model string) (runner.Runner, error)
+	// Runner answers the user; nil when no model is available.
+	Runner runner.Runner
+	// Review runs the review agents cfg enables on a change.
+	Review func(ctx context.Context, cfg config.Config, c agent.Change) ([]agent.Finding, error)
+	// History is the conversation so far, oldest first.
+	History []Turn
+	// Findings are the last review's, less those overridden.
+	Findings []Outstanding
+	// Transcript records the session as it goes; nil records nothing.
+	Transcript *Transcript
+
+	// pending is a blocked proposal the user declined; overriding its
+	// blocking findings applies it.
+	pending *proposal
+	lastID  int
+	lines   lineReader
+	out     io.Writer
+}
+
+// lineReader reads what the user types.
+type lineReader interface {
+	// ReadLine shows prompt and returns the next line, io.EOF at the end of
+	// the input and terminal.ErrInterrupted for a line abandoned.
+	ReadLine(prompt string) (string, error)
+}
+
+// New starts a session reading the user's lines from in and writing to out.
+// A terminal gets line editing and completion of the slash commands.
+func New(in io.Reader, out io.Writer) *Session {
+	s := &Session{out: out}
+	if f, ok := in.(*os.File); ok {
+		if e, ok := terminal.Open(f, out); ok {
+			e.Complete = s.complete
+			s.lines = e
+			return s
+		}
+	}
+	s.lines = plainLines{in: bufio.NewScanner(in), out: out}
+	return s
+}
+
+type plainLines struct {
+	in  *bufio.Scanner
+	out io.Writer
+}
+
+func (p plainLines) ReadLine(prompt string) (string, error) {
+	fmt.Fprint(p.out, prompt)
+	if !p.in.Scan() {
+		fmt.Fprintln(p.out)
+		if err := p.in.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return p.in.Text(), nil
+}
+
+// Run reads the user's requests and commands until the input ends or
+// /quit. Interrupting a reply abandons it and returns to the prompt.
+func (s *Session) Run(ctx context.Context) error {
+	if s.Runner == nil {
+		fmt.Fprintln(s.out, "No model available: set ANTHROPIC_API_KEY to talk to the orchestrator.")
+	}
+	defer func() {
+		if t := s.Transcript; t != nil && t.Recorded {
+			fmt.Fprintf(s.out, "Session %s saved; ensemble --resume %s continues it.\n", t.ID, t.ID)
+		}
+	}()
+	for {
+		line, err := s.lines.ReadLine(Prompt)
+		switch {
+		case errors.Is(err, io.EOF):
+			return nil
+		case errors.Is(err, terminal.ErrInterrupted):
+			continue
+		case err != nil:
+			return err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		turnCtx, stop := signal.NotifyContext(ctx, os.Interrupt)
+		if strings.HasPrefix(line, "/") {
+			s.record(Entry{Kind: KindCommand, Text: line})
+			err = s.Command(turnCtx, line)
+		} else {
+			err = s.Ask(turnCtx, line)
+		}
+		stop()
+		if errors.Is(err, errQuit) {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			fmt.Fprintln(s.out, "error:", err)
+		}
+	}
+}
+
+// Ask sends one request to the orchestrator and reviews the change it
+// proposes, if any.
+func (s *Session) Ask(ctx context.Context, request string) error {
+	if s.Runner == nil {
+		return fmt.Errorf("no model available; set ANTHROPIC_API_KEY")
+	}
+	prompt := s.prompt(request)
+	s.History = append(s.History, Turn{Role: User, Text: request})
+	s.record(Entry{Kind: User, Text: request})
+
+	w := &proseWriter{out: s.out}
+	reply, err := runner.Stream(ctx, s.Runner, prompt, w)
+	if ferr := w.Flush(); err == nil {
+		err = ferr
+	}
+	if err != nil {
+		return err
+	}
+	s.History = append(s.History, Turn{Role: Orchestrator, Text: reply})
+	s.record(Entry{Kind: Orchestrator, Text: reply, Model: s.Model})
+
+	edits := Edits(reply)
+	if len(edits) == 0 {
+		return nil
+	}
+	outcome, err := s.decide(ctx, edits)
+	if err != nil {
+		return err
+	}
+	s.note(outcome)
+	return nil
+}
+
+// note tells the orchestrator, on its next turn, what happened.
+func (s *Session) note(text string) {
+	s.History = append(s.History, Turn{Role: Ensemble, Text: text})
+	s.record(Entry{Kind: Ensemble, Text: text})
+}
+
+// record adds e to the transcript, if there is one. A transcript that
+// cannot be written is reported once and the session carries on.
+func (s *Session) record(e Entry) {
+	t := s.Transcript
+	if t == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	if err := t.Record(e); err != nil {
+		fmt.Fprintln(s.out, "warning: not recording the session:", err)
+		s.Transcript = nil
+	}
+}
+
+// decide reviews the proposed edits and applies them unless a finding
+// blocks and the user declines. It returns what happened, for the
+// orchestrator's next turn.
+func (s *Session) decide(ctx context.Context, edits []Edit) (string, error) {
+	var refused []string
+	var safe []Edit
+	index := map[string]int{}
+	for _, e := range edits {
+		if err := checkPath(s.Config.Root, e.Path); err != nil {
+			fmt.Fprintln(s.out, "Refused:", err)
+			refused = append(refused, err.Error())
+			continue
+		}
+		// From here on the path is the one checkPath judged, so ./a.go and
+		// a//a.go are a.go, and the last of them wins.
+		e.Path = path.Clean(e.Path)
+		if i, ok := index[e.Path]; ok {
+			safe[i] = e
+			continue
+		}
+		index[e.Path] = len(safe)
+		safe = append(safe, e)
+	}
+	p, err := propose(s.Config.Root, safe)
+	if err != nil {
+		return "", err
+	}
+	if len(p.edits) == 0 {
+		return outcome("Nothing to apply.", refused, nil), nil
+	}
+	change, err := p.change()
+	if err != nil {
+		return "", err
+	}
+	blocking, err := s.review(ctx, change, s.checkContent(p)...)
+	if err != nil {
+		return "", err
+	}
+	files := strings.Join(p.paths(), ", ")
+	if len(blocking) > 0 && !s.confirm(fmt.Sprintf("%d blocking finding(s). Apply anyway? [y/N] ", len(blocking))) {
+		s.pending = &p
+		s.record(Entry{Kind: KindDecision, Decision: Declined, Files: p.paths()})
+		fmt.Fprintln(s.out, "Not applied. /override <id> <reason> each blocking finding to apply it after all.")
+		return outcome("The user declined the change to "+files+" because of these findings:", refused, blocking), nil
+	}
+	if err := s.apply(p); err != nil {
+		return "", err
+	}
+	if len(blocking) > 0 {
+		s.record(Entry{Kind: KindDecision, Decision: AppliedAnyway, Files: p.paths()})
+		return outcome("The user applied the change to "+files+" despite these findings:", refused, blocking), nil
+	}
+	s.record(Entry{Kind: KindDecision, Decision: Applied, Files: p.paths()})
+	return outcome("Applied the change to "+files+".", refused, s.findings()), nil
+}
+
+// checkContent makes the checks the PreToolUse hook makes on every write on
+// the files a proposal writes: secrets, and in tests, skips, deleted
+// assertions and lint suppressions. Only the enabled agents' findings count.
+func (s *Session) checkContent(p proposal) []agent.Finding {
+	policy := s.Config.Policy()
+	var findings []agent.Finding
+	for _, e := range p.edits {
+		for _, f := range agent.CheckContent(e.Path, p.before[e.Path], e.Content, policy) {
+			if s.Config.Enabled(f.Agent) {
+				findings = append(findings, f)
+			}
+		}
+	}
+	return findings
+}
+
+// review runs the agents on a change, shows what they found along with the
+// findings already checked, and makes them the outstanding findings,
+// superseding any earlier review. It returns the blocking findings.
+func (s *Session) review(ctx context.Context, change agent.Change, checked ...agent.Finding) ([]agent.Finding, error) {
+	reviewed, err := s.Review(ctx, s.Config, change)
+	if err != nil {
+		return nil, err
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	findings := append(checked, reviewed...)
+	s.pending = nil
+	s.Findings = nil
+	var blocking []agent.Finding
+	for _, f := range findings {
+		if f.Verdict == agent.Pass {
+			continue
+		}
+		s.lastID++
+		o := Outstanding{ID: s.lastID, Finding: f, Blocking: s.Config.Fails(f)}
+		s.Findings = append(s.Findings, o)
+		fmt.Fprintln(s.out, o)
+		if o.Blocking {
+			blocking = append(blocking, f)
+		}
+	}
+	s.record(Entry{Kind: KindReview, Findings: s.Findings})
+	return blocking, nil
+}
+
+// apply writes a proposal unless its files changed since it was made; the
+// proposal is dropped then, and the orchestrator told.
+func (s *Session) apply(p proposal) error {
+	changed, err := p.stale()
+	if err != nil {
+		return err
+	}
+	if len(changed) > 0 {
+		s.pending = nil
+		files := strings.Join(changed, ", ")
+		s.note("The change to " + strings.Join(p.paths(), ", ") + " was not applied: " + files + " changed since it was proposed.")
+		return fmt.Errorf("not applied: %s changed since the change was proposed; ask for it again", files)
+	}
+	if err := p.apply(); err != nil {
+		return err
+	}
+	s.pending = nil
+	fmt.Fprintln(s.out, "Applied:", strings.Join(p.paths(), ", "))
+	return nil
+}
+
+// findings are the outstanding findings, as the agents reported them.
+func (s *Session) findings() []agent.Finding {
+	var fs []agent.Finding
+	for _, o := range s.Findings {
+		fs = append(fs, o.Finding)
+	}
+	return fs
+}
+
+// confirm asks a yes-or-no question; anything but yes, including the end
+// of the input, is no.
+func (s *Session) confirm(question string) bool {
+	answer, err := s.lines.ReadLine(question)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+func outcome(summary string, refused []string, findings []agent.Finding) string {
+	var b strings.Builder
+	b.WriteString(summary)
+	for _, f := range findings {
+		b.WriteString("\n  " + FormatFinding(f))
+	}
+	for _, r := range refused {
+		b.WriteString("\nRefused: " + r)
+	}
+	return b.String()
+}
+
+// String shows the finding with the ID /override takes.
+func (o Outstanding) String() string {
+	return fmt.Sprintf("  #%-3d %s", o.ID, FormatFinding(o.Finding))
+}
+
+// FormatFinding renders a finding on one line for the terminal.
+func FormatFinding(f agent.Finding) string {
+	s := fmt.Sprintf("%-5s %-8s %s: %s", f.Verdict, f.Severity, f.Agent, f.Finding)
+	if f.File != "" {
+		s += " (" + f.File + ")"
+	}
+	if f.Fix != "" {
+		s += " Fix: " + f.Fix
+	}
+	return s
+}
@@ -0,0 +1,182 @@
+package orchestrator_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gauthierbraillon/ensemble/internal/agent"
+	"github.com/gauthierbraillon/ensemble/internal/config"
+	"github.com/gauthierbraillon/ensemble/internal/orchestrator"
+	"github.com/gauthierbraillon/ensemble/internal/runner"
+)
+
+// scripted replies in turn and remembers the prompts it was given.
+type scripted struct {
+	replies []string
+	prompts []string
+}
+
+func (s *scripted) Run(_ context.Context, prompt string) (string, error) {
+	s.prompts = append(s.prompts, prompt)
+	if len(s.replies) == 0 {
+		return "", errors.New("no reply scripted")
+	}
+	reply := s.replies[0]
+	s.replies = s.replies[1:]
+	return reply, nil
+}
+
+const proposal = "Adding bar.\n<file path=\"bar/bar.go\">\npackage bar\n</file>\n"
+
+func session(t *testing.T, input string, r *scripted, findings ...agent.Finding) (*orchestrator.Session, *strings.Builder, *[]agent.Change) {
+	t.Helper()
+	var out strings.Builder
+	var reviewed []agent.Change
+	s := orchestrator.New(strings.NewReader(input), &out)
+	s.Config = config.Default()
+	s.Config.Root = t.TempDir()
+	s.Model = "claude-sonnet-4-6"
+	s.Connect = func(string) (runner.Runner, error) { return r, nil }
+	s.Runner = r
+	s.Review = func(_ context.Context, _ config.Config, c agent.Change) ([]agent.Finding, error) {
+		reviewed = append(reviewed, c)
+		return findings, nil
+	}
+	return s, &out, &reviewed
+}
+
+func TestSessionConverses(t *testing.T) {
+	r := &scripted{replies: []string{"Hello.", "Still here."}}
+	s, out, _ := session(t, "hi\n\nagain\n", r)
+	require.NoError(t, s.Run(context.Background()))
+
+	assert.Equal(t, "ensemble> Hello.\nensemble> ensemble> Still here.\nensemble> \n", out.String())
+	require.Len(t, r.prompts, 2)
+	assert.Contains(t, r.prompts[1], "user: hi\n")
+	assert.Contains(t, r.prompts[1], "orchestrator: Hello.\n")
+	assert.True(t, strings.HasSuffix(r.prompts[1], "user: again\n\norchestrator:"))
+	assert.Len(t, s.History, 4)
+}
+
+func TestSessionAppliesReviewedChange(t *testing.T) {
+	r := &scripted{replies: []string{proposal}}
+	warning := agent.Finding{Agent: "software-engineering", Verdict: agent.Warn, Severity: agent.Low, Finding: "terse name"}
+	s, out, reviewed := session(t, "add bar\n", r, warning, agent.Finding{Agent: "security", Verdict: agent.Pass, Finding: "fine"})
+	require.NoError(t, s.Run(context.Background()))
+
+	require.Len(t, *reviewed, 1)
+	assert.Equal(t, "bar/bar.go", (*reviewed)[0].Patch.Files[0].Path())
+	assert.Contains(t, out.String(), "  [proposes bar/bar.go]")
+	assert.Contains(t, out.String(), "software-engineering: terse name", "warnings are shown")
+	assert.NotContains(t, out.String(), "fine", "passes are silent")
+	assert.Contains(t, out.String(), "Applied: bar/bar.go")
+	data, err := os.ReadFile(filepath.Join(s.Config.Root, "bar", "bar.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "package bar\n", string(data))
+	assert.Equal(t, orchestrator.Ensemble, s.History[len(s.History)-1].Role)
+}
+
+func TestSessionLetsTheUserDecideOnBlocks(t *testing.T) {
+	block := agent.Finding{Agent: "testing-quality", Verdict: agent.Block, Severity: agent.Critical, Finding: "no test", File: "bar/bar.go"}
+
+	t.Run("declined", func(t *testing.T) {
+		r := &scripted{replies: []string{proposal, "I will write the test first."}}
+		s, out, _ := session(t, "add bar\nn\nok\n", r, block)
+		require.NoError(t, s.Run(context.Background()))
+
+		assert.Contains(t, out.String(), "Apply anyway? [y/N] Not applied.")
+		_, err := os.Stat(filepath.Join(s.Config.Root, "bar", "bar.go"))
+		assert.True(t, os.IsNotExist(err))
+		require.Len(t, r.prompts, 2)
+		assert.Contains(t, r.prompts[1], "ensemble: The user declined the change to bar/bar.go")
+		assert.Contains(t, r.prompts[1], "no test (bar/bar.go)", "the orchestrator learns why")
+	})
+
+	t.Run("overridden", func(t *testing.T) {
+		r := &scripted{replies: []string{proposal}}
+		s, out, _ := session(t, "add bar\ny\n", r, block)
+		require.NoError(t, s.Run(context.Background()))
+
+		assert.Contains(t, out.String(), "Applied: bar/bar.go")
+		assert.Contains(t, s.History[len(s.History)-1].Text, "despite these findings")
+	})
+
+	t.Run("input ends at the question", func(t *testing.T) {
+		r := &scripted{replies: []string{proposal}}
+		s, out, _ := session(t, "add bar\n", r, block)
+		require.NoError(t, s.Run(context.Background()))
+		assert.NotContains(t, out.String(), "Applied")
+	})
+}
+
+func TestSessionChecksContentLikeTheHook(t *testing.T) {
+	// Assembled at run time so the test file itself carries no secret.
+	secret := "<file path=\"bar/bar.go\">\npackage bar\n\nconst key = \"AKIA" + "IOSFODNN7EXAMPLE\"\n</file>\n"
+
+	t.Run("a secret blocks whatever the review says", func(t *testing.T) {
+		r := &scripted{replies: []string{secret, "Reading it from the environment instead."}}
+		s, out, _ := session(t, "add bar\nn\nok\n", r)
+		require.NoError(t, s.Run(context.Background()))
+
+		assert.Contains(t, out.String(), "security: ")
+		assert.Contains(t, out.String(), "1 blocking finding(s). Apply anyway? [y/N] Not applied.")
+		_, err := os.Stat(filepath.Join(s.Config.Root, "bar", "bar.go"))
+		assert.True(t, os.IsNotExist(err))
+		require.Len(t, s.Findings, 1)
+		assert.Equal(t, "hardcoded-secret", s.Findings[0].Category)
+		assert.True(t, s.Findings[0].Blocking, "and can be overridden like any other")
+	})
+
+	t.Run("not when its agent is disabled", func(t *testing.T) {
+		r := &scripted{replies: []string{secret}}
+		s, out, _ := session(t, "add bar\n", r)
+		s.Config.Agents = map[string]config.Agent{"security": {Enabled: new(bool)}}
+		require.NoError(t, s.Run(context.Background()))
+		assert.Contains(t, out.String(), "Applied: bar/bar.go")
+	})
+}
+
+func TestSessionCleansProposedPaths(t *testing.T) {
+	r := &scripted{replies: []string{"<file path=\"./bar//bar.go\">\npackage old\n</file>\n<file path=\"bar/bar.go\">\npackage bar\n</file>\n"}}
+	s, out, reviewed := session(t, "add bar\n", r)
+	require.NoError(t, s.Run(context.Background()))
+
+	require.Len(t, *reviewed, 1)
+	require.Len(t, (*reviewed)[0].Patch.Files, 1, "one file, however it is named")
+	assert.Equal(t, "bar/bar.go", (*reviewed)[0].Patch.Files[0].Path())
+	assert.Contains(t, out.String(), "Applied: bar/bar.go\n")
+	data, err := os.ReadFile(filepath.Join(s.Config.Root, "bar", "bar.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "package bar\n", string(data), "the last proposal wins")
+}
+
+func TestSessionRefusesPathsOutsideTheProject(t *testing.T) {
+	r := &scripted{replies: []string{"<file path=\"../escape.go\">\npackage x\n</file>\n"}}
+	s, out, reviewed := session(t, "go\n", r)
+	require.NoError(t, s.Run(context.Background()))
+	assert.Contains(t, out.String(), "Refused:")
+	assert.Empty(t, *reviewed)
+	_, err := os.Stat(filepath.Join(filepath.Dir(s.Config.Root), "escape.go"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestSessionWithoutModel(t *testing.T) {
+	s, out, _ := session(t, "hi\n", nil)
+	s.Runner = nil
+	s.Connect = func(string) (runner.Runner, error) { return nil, nil }
+	require.NoError(t, s.Run(context.Background()))
+	assert.Contains(t, out.String(), "No model available")
+	assert.Contains(t, out.String(), "error: no model available")
+}
+
+func TestFormatFinding(t *testing.T) {
+	f := agent.Finding{Agent: "security", Verdict: agent.Block, Severity: agent.High, Finding: "secret", File: "a.go:3", Fix: "use env"}
+	assert.Equal(t, "block high     security: secret (a.go:3) Fix: use env", orchestrator.FormatFinding(f))
+}
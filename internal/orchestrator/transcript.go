@@ -0,0 +1,228 @@
+package orchestrator
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gauthierbraillon/ensemble/internal/state"
+)
+
+// sessionsDir holds the transcripts, one JSON line per entry, inside the
+// state directory.
+const sessionsDir = "sessions"
+
+// Kinds of transcript entries. The conversation's turns use their role.
+const (
+	// KindReview records the findings of a review, numbered for /override.
+	KindReview = "review"
+	// KindDecision records what became of a proposed change or a finding.
+	KindDecision = "decision"
+	// KindCommand records a slash command as typed.
+	KindCommand = "command"
+	// KindModel records the orchestrator switching to Model.
+	KindModel = "model"
+	// KindAgent records a review agent turned on or off for the session.
+	KindAgent = "agent"
+)
+
+// Decisions about a proposed change or a finding.
+const (
+	// Applied is a change with nothing blocking it, or whose blocking
+	// findings were all overridden.
+	Applied = "applied"
+	// AppliedAnyway is a blocked change the user applied.
+	AppliedAnyway = "applied-anyway"
+	Declined      = "declined"
+	Overridden    = "overridden"
+)
+
+// Entry is one line of a transcript.
+type Entry struct {
+	Time time.Time `json:"time"`
+	Kind string    `json:"kind"`
+	Text string    `json:"text,omitempty"`
+	// Model answered an orchestrator turn.
+	Model    string        `json:"model,omitempty"`
+	Findings []Outstanding `json:"findings,omitempty"`
+	Decision string        `json:"decision,omitempty"`
+	Files    []string      `json:"files,omitempty"`
+	// Finding is the ID of the finding an override is about, and Text the
+	// reason given.
+	Finding int `json:"finding,omitempty"`
+	// Agent was turned on or off, as Enabled says.
+	Agent   string `json:"agent,omitempty"`
+	Enabled *bool  `json:"enabled,omitempty"`
+}
+
+// Transcript records a session under the project root.
+type Transcript struct {
+	Root string
+	ID   string
+	// Recorded is set once anything has been written.
+	Recorded bool
+}
+
+// validTranscriptID keeps transcript IDs from naming files outside the
+// sessions directory.
+var validTranscriptID = regexp.MustCompile(`^[A-Za-z0-9_-]{1,128}$`)
+
+// NewTranscript starts a transcript under root, named for the time it
+// started. Nothing is written until the first entry.
+func NewTranscript(root string, now time.Time) (*Transcript, error) {
+	suffix := make([]byte, 2)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+	return &Transcript{Root: root, ID: now.UTC().Format("20060102-150405") + "-" + hex.EncodeToString(suffix)}, nil
+}
+
+// OpenTranscript reads the transcript with id to carry it on.
+func OpenTranscript(root, id string) (*Transcript, []Entry, error) {
+	if !validTranscriptID.MatchString(id) {
+		return nil, nil, fmt.Errorf("invalid session ID %q", id)
+	}
+	entries, err := readTranscript(transcriptPath(root, id))
+	if os.IsNotExist(err) {
+		return nil, nil, fmt.Errorf("no session %q; ensemble sessions list shows them", id)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return &Transcript{Root: root, ID: id, Recorded: true}, entries, nil
+}
+
+func transcriptPath(root, id string) string {
+	return filepath.Join(root, state.Dir, sessionsDir, id+".jsonl")
+}
+
+// Path is where the transcript is written.
+func (t *Transcript) Path() string {
+	return transcriptPath(t.Root, t.ID)
+}
+
+// Record appends e to the transcript.
+func (t *Transcript) Record(e Entry) error {
+	if err := state.Append(t.Root, sessionsDir+"/"+t.ID+".jsonl", e); err != nil {
+		return err
+	}
+	t.Recorded = true
+	return nil
+}
+
+func readTranscript(path string) ([]Entry, error) {
+	f, err := os.Open(path) // #nosec G304 -- the ID is validated
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// Summary describes a recorded session.
+type Summary struct {
+	ID      string    `json:"id"`
+	Started time.Time `json:"started"`
+	Updated time.Time `json:"updated"`
+	// Prompts counts the user's requests, and First is the first of them.
+	Prompts int    `json:"prompts"`
+	First   string `json:"first,omitempty"`
+	// Overrides counts the findings overridden and the blocked changes
+	// applied anyway.
+	Overrides int `json:"overrides"`
+}
+
+// Transcripts summarises the sessions recorded under root, latest first.
+func Transcripts(root string) ([]Summary, error) {
+	paths, err := filepath.Glob(filepath.Join(root, state.Dir, sessionsDir, "*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	var summaries []Summary
+	for _, path := range paths {
+		entries, err := readTranscript(path)
+		if err != nil {
+			return nil, err
+		}
+		s := Summary{ID: strings.TrimSuffix(filepath.Base(path), ".jsonl")}
+		for i, e := range entries {
+			if i == 0 {
+				s.Started = e.Time
+			}
+			s.Updated = e.Time
+			switch {
+			case e.Kind == User:
+				if s.Prompts == 0 {
+					s.First = e.Text
+				}
+				s.Prompts++
+			case e.Decision == Overridden || e.Decision == AppliedAnyway:
+				s.Overrides++
+			}
+		}
+		summaries = append(summaries, s)
+	}
+	sort.SliceStable(summaries, func(i, j int) bool { return summaries[i].Started.After(summaries[j].Started) })
+	return summaries, nil
+}
+
+// Resume carries on the conversation a transcript recorded: its turns, the
+// outstanding findings of its last review, and the model and agents chosen
+// with /model and /agents. A change declined then is not kept, since the
+// files may have moved on; ask for it again.
+func (s *Session) Resume(entries []Entry) error {
+	model := s.Model
+	for _, e := range entries {
+		switch e.Kind {
+		case User, Orchestrator, Ensemble:
+			s.History = append(s.History, Turn{Role: e.Kind, Text: e.Text})
+		case KindReview:
+			s.Findings = append([]Outstanding(nil), e.Findings...)
+			for _, o := range e.Findings {
+				s.lastID = max(s.lastID, o.ID)
+			}
+		case KindDecision:
+			if e.Decision == Overridden {
+				if i := s.outstanding(e.Finding); i >= 0 {
+					s.Findings = append(s.Findings[:i], s.Findings[i+1:]...)
+				}
+			}
+		case KindModel:
+			model = e.Model
+		case KindAgent:
+			if e.Enabled != nil {
+				s.setAgent(e.Agent, *e.Enabled)
+			}
+		}
+	}
+	if model == s.Model || s.Connect == nil {
+		return nil
+	}
+	r, err := s.Connect(model)
+	if err != nil {
+		return err
+	}
+	s.Model, s.Runner = model, r
+	return nil
+}
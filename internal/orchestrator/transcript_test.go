@@ -0,0 +1,146 @@
+package orchestrator
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gauthierbraillon/ensemble/internal/agent"
+	"github.com/gauthierbraillon/ensemble/internal/config"
+	"github.com/gauthierbraillon/ensemble/internal/runner"
+)
+
+// recordedSession runs input through a session recording to root.
+func recordedSession(t *testing.T, root string, tr *Transcript, entries []Entry, input string, findings ...agent.Finding) (*Session, string) {
+	t.Helper()
+	var out strings.Builder
+	s := New(strings.NewReader(input), &out)
+	s.Config = config.Default()
+	s.Config.Root = root
+	s.Model = "claude-sonnet-4-6"
+	s.Connect = func(string) (runner.Runner, error) {
+		return reply("<file path=\"bar/bar.go\">\npackage bar\n</file>\n"), nil
+	}
+	s.Runner, _ = s.Connect(s.Model)
+	s.Review = func(context.Context, config.Config, agent.Change) ([]agent.Finding, error) { return findings, nil }
+	s.Transcript = tr
+	require.NoError(t, s.Resume(entries))
+	require.NoError(t, s.Run(context.Background()))
+	return s, out.String()
+}
+
+func TestTranscript(t *testing.T) {
+	root := t.TempDir()
+	warn := agent.Finding{Agent: "ux-design", Verdict: agent.Warn, Severity: agent.Low, Finding: "wording"}
+	tr, err := NewTranscript(root, time.Date(2026, 10, 18, 9, 15, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Regexp(t, `^20261018-091500-[0-9a-f]{4}$`, tr.ID)
+
+	_, out := recordedSession(t, root, tr, nil, "add bar\nn\n/override 1 spike, deleted tomorrow\n", noTest, warn)
+	assert.Contains(t, out, "Session "+tr.ID+" saved; ensemble --resume "+tr.ID+" continues it.")
+
+	_, entries, err := OpenTranscript(root, tr.ID)
+	require.NoError(t, err)
+	var kinds []string
+	for _, e := range entries {
+		kinds = append(kinds, e.Kind)
+		assert.False(t, e.Time.IsZero())
+	}
+	assert.Equal(t, []string{User, Orchestrator, KindReview, KindDecision, Ensemble, KindCommand, KindDecision, Ensemble, KindDecision, Ensemble}, kinds)
+	assert.Equal(t, "claude-sonnet-4-6", entries[1].Model)
+	require.Len(t, entries[2].Findings, 2)
+	assert.Equal(t, Outstanding{ID: 1, Finding: noTest, Blocking: true}, entries[2].Findings[0])
+	assert.Equal(t, Entry{Kind: KindDecision, Decision: Declined, Files: []string{"bar/bar.go"}}, withoutTime(entries[3]))
+	assert.Equal(t, Entry{Kind: KindDecision, Decision: Overridden, Finding: 1, Text: "spike, deleted tomorrow"}, withoutTime(entries[6]))
+	assert.Equal(t, Entry{Kind: KindDecision, Decision: Applied, Files: []string{"bar/bar.go"}}, withoutTime(entries[8]))
+
+	t.Run("resumes the conversation and its outstanding findings", func(t *testing.T) {
+		s, _ := recordedSession(t, root, &Transcript{Root: root, ID: tr.ID}, entries, "/findings\n")
+		require.Len(t, s.History, 5)
+		assert.Equal(t, Turn{Role: User, Text: "add bar"}, s.History[0])
+		require.Len(t, s.Findings, 1, "the override still holds")
+		assert.Equal(t, 2, s.Findings[0].ID)
+		assert.Equal(t, 2, s.lastID, "new findings are numbered after the old")
+		assert.Nil(t, s.pending, "a declined change is not carried over")
+
+		_, again, err := OpenTranscript(root, tr.ID)
+		require.NoError(t, err)
+		assert.Len(t, again, len(entries)+1, "the resumed session appends to the same transcript")
+	})
+
+	t.Run("restores the model and agents chosen in the session", func(t *testing.T) {
+		root := t.TempDir()
+		tr := &Transcript{Root: root, ID: "settings"}
+		recordedSession(t, root, tr, nil, "/model haiku\n/agents disable security\n/agents disable ux-design\n/agents enable ux-design\n")
+		_, entries, err := OpenTranscript(root, tr.ID)
+		require.NoError(t, err)
+
+		var connected []string
+		s := New(strings.NewReader(""), &strings.Builder{})
+		s.Config = config.Default()
+		s.Model = "claude-sonnet-4-6"
+		s.Connect = func(m string) (runner.Runner, error) {
+			connected = append(connected, m)
+			return reply(""), nil
+		}
+		require.NoError(t, s.Resume(entries))
+		assert.Equal(t, "claude-haiku-4-5-20251001", s.Model)
+		assert.Equal(t, []string{"claude-haiku-4-5-20251001"}, connected, "the runner follows the model")
+		assert.NotNil(t, s.Runner)
+		assert.False(t, s.Config.Enabled("security"))
+		assert.True(t, s.Config.Enabled("ux-design"), "the last choice holds")
+		assert.True(t, config.Default().Enabled("security"), "the defaults are untouched")
+	})
+
+	t.Run("lists the sessions", func(t *testing.T) {
+		require.NoError(t, (&Transcript{Root: root, ID: "20261019-080000-0000"}).Record(Entry{Time: time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC), Kind: KindCommand, Text: "/cycle"}))
+		summaries, err := Transcripts(root)
+		require.NoError(t, err)
+		require.Len(t, summaries, 2)
+		assert.Equal(t, "20261019-080000-0000", summaries[0].ID, "latest first")
+		assert.Equal(t, 0, summaries[0].Prompts)
+		assert.Equal(t, tr.ID, summaries[1].ID)
+		assert.Equal(t, 1, summaries[1].Prompts)
+		assert.Equal(t, "add bar", summaries[1].First)
+		assert.Equal(t, 1, summaries[1].Overrides)
+	})
+
+	t.Run("records nothing for a session where nothing happened", func(t *testing.T) {
+		empty := t.TempDir()
+		tr, err := NewTranscript(empty, time.Now())
+		require.NoError(t, err)
+		_, out := recordedSession(t, empty, tr, nil, "")
+		assert.NotContains(t, out, "saved")
+		summaries, err := Transcripts(empty)
+		require.NoError(t, err)
+		assert.Empty(t, summaries)
+	})
+}
+
+func TestOpenTranscript(t *testing.T) {
+	root := t.TempDir()
+	_, _, err := OpenTranscript(root, "missing")
+	assert.ErrorContains(t, err, `no session "missing"; ensemble sessions list shows them`)
+	_, _, err = OpenTranscript(root, "../../etc/passwd")
+	assert.ErrorContains(t, err, "invalid session ID")
+
+	tr := &Transcript{Root: root, ID: "broken"}
+	require.NoError(t, tr.Record(Entry{Kind: User, Text: "hi"}))
+	f, err := os.OpenFile(tr.Path(), os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString("{not json\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	_, _, err = OpenTranscript(root, "broken")
+	assert.ErrorContains(t, err, "broken.jsonl:2:")
+}
+
+func withoutTime(e Entry) Entry {
+	e.Time = time.Time{}
+	return e
+}
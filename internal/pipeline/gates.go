@@ -2,6 +2,11 @@
 // Gates run in order. Any failure halts the pipeline (Minimum CD requirement).
 package pipeline
 
+import (
+	"context"
+	"os/exec"
+)
+
 // Gate represents a single quality gate in the CD pipeline.
 type Gate struct {
 	Name        string
@@ -24,3 +29,11 @@ func Gates() []Gate {
 		{"Schema validation", "test-schema", "Migration schema validation"},
 	}
 }
+
+// Run runs the gate's make target in dir and returns its combined output.
+func (g Gate) Run(ctx context.Context, dir string) (string, error) {
+	cmd := exec.CommandContext(ctx, "make", g.MakeTarget) // #nosec G204 -- targets are fixed
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
@@ -1,6 +1,9 @@
 package pipeline_test
 
 import (
+	"context"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -41,3 +44,16 @@ func TestCDPipelineGates(t *testing.T) {
 		assert.Less(t, indexOf("build"), indexOf("vulncheck"), "build must precede vuln check")
 	})
 }
+
+func TestGateRun(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Makefile"), []byte("lint:\n\t@echo linted\ntest:\n\t@echo broken; exit 1\n"), 0600))
+
+	out, err := pipeline.Gate{Name: "Lint", MakeTarget: "lint"}.Run(context.Background(), dir)
+	require.NoError(t, err)
+	assert.Equal(t, "linted\n", out)
+
+	out, err = pipeline.Gate{Name: "Unit tests", MakeTarget: "test"}.Run(context.Background(), dir)
+	assert.Error(t, err)
+	assert.Contains(t, out, "broken")
+}
@@ -0,0 +1,41 @@
+package pipeline
+
+import (
+	"strings"
+)
+
+// Tools returns the programs a Makefile target's recipe runs, in order and
+// without repeats: the first word of each recipe line, past make's @ and -
+// prefixes. ok is false when the Makefile has no such target. Recipes that
+// call other targets are not followed.
+func Tools(makefile, target string) (tools []string, ok bool) {
+	in := false
+	seen := map[string]bool{}
+	for _, line := range strings.Split(makefile, "\n") {
+		if strings.HasPrefix(line, "\t") {
+			if !in {
+				continue
+			}
+			fields := strings.Fields(strings.TrimLeft(strings.TrimSpace(line), "@-+"))
+			if len(fields) > 0 && !seen[fields[0]] {
+				seen[fields[0]] = true
+				tools = append(tools, fields[0])
+			}
+			continue
+		}
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		in = false
+		name, _, isRule := strings.Cut(line, ":")
+		if !isRule || strings.HasPrefix(line[len(name)+1:], "=") {
+			continue
+		}
+		for _, t := range strings.Fields(name) {
+			if t == target {
+				in, ok = true, true
+			}
+		}
+	}
+	return tools, ok
+}
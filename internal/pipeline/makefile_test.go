@@ -0,0 +1,42 @@
+package pipeline_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gauthierbraillon/ensemble/internal/pipeline"
+)
+
+func TestTools(t *testing.T) {
+	makefile := ".PHONY: lint test\nGO := go\n\n# Gate 1\nlint:\n\t@golangci-lint run ./...\n\n\t-gofmt -l .\n\ntypecheck:\n\tgo vet ./...\n\tstaticcheck ./...\n\tgo build ./...\n\nci: lint typecheck\n"
+
+	tools, ok := pipeline.Tools(makefile, "lint")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"golangci-lint", "gofmt"}, tools)
+
+	tools, ok = pipeline.Tools(makefile, "typecheck")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"go", "staticcheck"}, tools)
+
+	tools, ok = pipeline.Tools(makefile, "ci")
+	assert.True(t, ok, "a target of prerequisites only")
+	assert.Empty(t, tools)
+
+	_, ok = pipeline.Tools(makefile, "GO")
+	assert.False(t, ok, "variables are not targets")
+	_, ok = pipeline.Tools(makefile, "vulncheck")
+	assert.False(t, ok)
+}
+
+func TestToolsCoverTheGates(t *testing.T) {
+	data, err := os.ReadFile("../../Makefile")
+	require.NoError(t, err)
+	for _, g := range pipeline.Gates() {
+		tools, ok := pipeline.Tools(string(data), g.MakeTarget)
+		assert.True(t, ok, "the Makefile has the %s gate", g.Name)
+		assert.NotEmpty(t, tools, g.Name)
+	}
+}
@@ -0,0 +1,226 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	DefaultBaseURL   = "https://api.anthropic.com"
+	anthropicVersion = "2023-06-01"
+	maxTokens        = 4096
+)
+
+type APIRunner struct {
+	cfg      Config
+	endpoint string
+	client   *http.Client
+}
+
+type messagesRequest struct {
+	Model     string    `json:"model"`
+	MaxTokens int       `json:"max_tokens"`
+	Messages  []message `json:"messages"`
+	Stream    bool      `json:"stream,omitempty"`
+}
+
+type message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type messagesResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+}
+
+// streamEvent is one server-sent event of a streamed response; only the
+// fields ensemble reads are decoded.
+type streamEvent struct {
+	Type  string `json:"type"`
+	Delta struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"delta"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type apiError struct {
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func NewAPI(cfg Config) (*APIRunner, error) {
+	if cfg.Model == "" {
+		return nil, fmt.Errorf("api runner requires a model")
+	}
+	if !ValidModels[cfg.Model] {
+		return nil, fmt.Errorf("invalid model: %q", cfg.Model)
+	}
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("api runner requires an API key")
+	}
+	base := cfg.BaseURL
+	if base == "" {
+		base = DefaultBaseURL
+	}
+	u, err := url.Parse(base)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid base URL: %q", base)
+	}
+	return &APIRunner{
+		cfg:      cfg,
+		endpoint: strings.TrimSuffix(base, "/") + "/v1/messages",
+		client:   &http.Client{},
+	}, nil
+}
+
+func (r *APIRunner) Run(ctx context.Context, prompt string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.Timeout)
+	defer cancel()
+
+	resp, err := r.post(ctx, prompt, false)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	data, err := r.read(ctx, resp.Body)
+	if err != nil {
+		return "", err
+	}
+	var mr messagesResponse
+	if err := json.Unmarshal(data, &mr); err != nil {
+		return "", fmt.Errorf("runner: decoding response: %w", err)
+	}
+	var out strings.Builder
+	for _, block := range mr.Content {
+		if block.Type == "text" {
+			out.WriteString(block.Text)
+		}
+	}
+	return out.String(), nil
+}
+
+// Stream asks for the response as server-sent events and copies its text to
+// w as each delta arrives.
+func (r *APIRunner) Stream(ctx context.Context, prompt string, w io.Writer) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.Timeout)
+	defer cancel()
+
+	resp, err := r.post(ctx, prompt, true)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var out strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), int(r.cfg.MaxBytes)+64*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		var ev streamEvent
+		if err := json.Unmarshal([]byte(strings.TrimSpace(data)), &ev); err != nil {
+			return "", fmt.Errorf("runner: decoding event: %w", err)
+		}
+		switch ev.Type {
+		case "error":
+			return "", fmt.Errorf("runner: api error: %s: %s", ev.Error.Type, ev.Error.Message)
+		case "content_block_delta":
+			if ev.Delta.Type != "text_delta" {
+				continue
+			}
+			if int64(out.Len()+len(ev.Delta.Text)) > r.cfg.MaxBytes {
+				return "", fmt.Errorf("runner: output exceeded %d bytes", r.cfg.MaxBytes)
+			}
+			out.WriteString(ev.Delta.Text)
+			if _, err := io.WriteString(w, ev.Delta.Text); err != nil {
+				return "", err
+			}
+		case "message_stop":
+			return out.String(), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", r.requestError(ctx, err)
+	}
+	if ctx.Err() != nil {
+		return "", r.requestError(ctx, ctx.Err())
+	}
+	return "", fmt.Errorf("runner: stream ended before the message did")
+}
+
+// post sends prompt to the Messages API and returns the response once it
+// is known to be a success.
+func (r *APIRunner) post(ctx context.Context, prompt string, stream bool) (*http.Response, error) {
+	body, err := json.Marshal(messagesRequest{
+		Model:     r.cfg.Model,
+		MaxTokens: maxTokens,
+		Messages:  []message{{Role: "user", Content: prompt}},
+		Stream:    stream,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("runner: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("runner: %w", err)
+	}
+	req.Header.Set("content-type", "application/json")
+	req.Header.Set("x-api-key", r.cfg.APIKey)
+	req.Header.Set("anthropic-version", anthropicVersion)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, r.requestError(ctx, err)
+	}
+	if resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	data, err := r.read(ctx, resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var e apiError
+	if json.Unmarshal(data, &e) == nil && e.Error.Message != "" {
+		return nil, fmt.Errorf("runner: api returned %d: %s: %s", resp.StatusCode, e.Error.Type, e.Error.Message)
+	}
+	return nil, fmt.Errorf("runner: api returned %d", resp.StatusCode)
+}
+
+// read reads a response body of at most MaxBytes.
+func (r *APIRunner) read(ctx context.Context, body io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(body, r.cfg.MaxBytes+1))
+	if err != nil {
+		return nil, r.requestError(ctx, err)
+	}
+	if int64(len(data)) > r.cfg.MaxBytes {
+		return nil, fmt.Errorf("runner: output exceeded %d bytes", r.cfg.MaxBytes)
+	}
+	return data, nil
+}
+
+func (r *APIRunner) requestError(ctx context.Context, err error) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("runner: timeout after %s", r.cfg.Timeout)
+	}
+	return fmt.Errorf("runner: %w", err)
+}
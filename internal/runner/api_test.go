@@ -0,0 +1,249 @@
+package runner_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gauthierbraillon/ensemble/internal/runner"
+)
+
+func apiCfg(baseURL string) runner.Config {
+	return runner.Config{
+		Backend:  runner.BackendAPI,
+		Model:    "claude-haiku-4-5-20251001",
+		Timeout:  2 * time.Second,
+		MaxBytes: 4096,
+		BaseURL:  baseURL,
+		APIKey:   "test-key",
+	}
+}
+
+func stubMessagesAPI(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func textResponse(w http.ResponseWriter, text string) {
+	w.Header().Set("content-type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"content": []map[string]string{{"type": "text", "text": text}},
+	})
+}
+
+func TestAPIRunnerSendsPromptToMessagesEndpoint(t *testing.T) {
+	var got struct {
+		Model    string `json:"model"`
+		Messages []struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"messages"`
+	}
+	srv := stubMessagesAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/messages", r.URL.Path)
+		assert.Equal(t, "test-key", r.Header.Get("x-api-key"))
+		assert.NotEmpty(t, r.Header.Get("anthropic-version"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		textResponse(w, "[]")
+	})
+
+	r, err := runner.NewAPI(apiCfg(srv.URL + "/"))
+	require.NoError(t, err)
+	out, err := r.Run(context.Background(), "hello")
+	require.NoError(t, err)
+
+	assert.Equal(t, "[]", out)
+	assert.Equal(t, "claude-haiku-4-5-20251001", got.Model)
+	require.Len(t, got.Messages, 1)
+	assert.Equal(t, "user", got.Messages[0].Role)
+	assert.Equal(t, "hello", got.Messages[0].Content)
+}
+
+func TestAPIRunnerConcatenatesTextBlocks(t *testing.T) {
+	srv := stubMessagesAPI(t, func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"content":[{"type":"text","text":"[{"},{"type":"tool_use"},{"type":"text","text":"}]"}]}`))
+	})
+	r, err := runner.NewAPI(apiCfg(srv.URL))
+	require.NoError(t, err)
+	out, err := r.Run(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, "[{}]", out)
+}
+
+func TestAPIRunnerEnforcesTimeout(t *testing.T) {
+	srv := stubMessagesAPI(t, func(_ http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	})
+	c := apiCfg(srv.URL)
+	c.Timeout = 100 * time.Millisecond
+	r, err := runner.NewAPI(c)
+	require.NoError(t, err)
+	_, err = r.Run(context.Background(), "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "timeout")
+}
+
+func TestAPIRunnerBlocksOutputExceedingMaxBytes(t *testing.T) {
+	srv := stubMessagesAPI(t, func(w http.ResponseWriter, _ *http.Request) {
+		textResponse(w, strings.Repeat("x", 200))
+	})
+	c := apiCfg(srv.URL)
+	c.MaxBytes = 100
+	r, err := runner.NewAPI(c)
+	require.NoError(t, err)
+	_, err = r.Run(context.Background(), "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "output exceeded")
+}
+
+func TestAPIRunnerReportsAPIErrors(t *testing.T) {
+	srv := stubMessagesAPI(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`))
+	})
+	r, err := runner.NewAPI(apiCfg(srv.URL))
+	require.NoError(t, err)
+	_, err = r.Run(context.Background(), "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "401")
+	assert.Contains(t, err.Error(), "invalid x-api-key")
+}
+
+func sseResponse(w http.ResponseWriter, events ...string) {
+	w.Header().Set("content-type", "text/event-stream")
+	for _, e := range events {
+		_, _ = w.Write([]byte("event: x\ndata: " + e + "\n\n"))
+	}
+}
+
+func TestAPIRunnerStreams(t *testing.T) {
+	t.Run("copies each text delta as it arrives", func(t *testing.T) {
+		var stream bool
+		srv := stubMessagesAPI(t, func(w http.ResponseWriter, r *http.Request) {
+			var req struct {
+				Stream bool `json:"stream"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			stream = req.Stream
+			sseResponse(w,
+				`{"type":"message_start"}`,
+				`{"type":"content_block_delta","delta":{"type":"text_delta","text":"Hel"}}`,
+				`{"type":"content_block_delta","delta":{"type":"input_json_delta","partial_json":"{}"}}`,
+				`{"type":"content_block_delta","delta":{"type":"text_delta","text":"lo"}}`,
+				`{"type":"message_stop"}`)
+		})
+		r, err := runner.NewAPI(apiCfg(srv.URL))
+		require.NoError(t, err)
+		var w strings.Builder
+		out, err := r.Stream(context.Background(), "hi", &w)
+		require.NoError(t, err)
+		assert.True(t, stream, "asks for a stream")
+		assert.Equal(t, "Hello", out)
+		assert.Equal(t, "Hello", w.String())
+	})
+
+	t.Run("reports an error event", func(t *testing.T) {
+		srv := stubMessagesAPI(t, func(w http.ResponseWriter, _ *http.Request) {
+			sseResponse(w, `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`)
+		})
+		r, err := runner.NewAPI(apiCfg(srv.URL))
+		require.NoError(t, err)
+		_, err = r.Stream(context.Background(), "", io.Discard)
+		assert.ErrorContains(t, err, "Overloaded")
+	})
+
+	t.Run("fails a stream cut short", func(t *testing.T) {
+		srv := stubMessagesAPI(t, func(w http.ResponseWriter, _ *http.Request) {
+			sseResponse(w, `{"type":"content_block_delta","delta":{"type":"text_delta","text":"Hel"}}`)
+		})
+		r, err := runner.NewAPI(apiCfg(srv.URL))
+		require.NoError(t, err)
+		_, err = r.Stream(context.Background(), "", io.Discard)
+		assert.ErrorContains(t, err, "stream ended")
+	})
+
+	t.Run("enforces the output cap", func(t *testing.T) {
+		srv := stubMessagesAPI(t, func(w http.ResponseWriter, _ *http.Request) {
+			sseResponse(w, `{"type":"content_block_delta","delta":{"type":"text_delta","text":"`+strings.Repeat("x", 200)+`"}}`)
+		})
+		c := apiCfg(srv.URL)
+		c.MaxBytes = 100
+		r, err := runner.NewAPI(c)
+		require.NoError(t, err)
+		_, err = r.Stream(context.Background(), "", io.Discard)
+		assert.ErrorContains(t, err, "output exceeded")
+	})
+}
+
+func TestNewAPIValidatesConfig(t *testing.T) {
+	t.Run("rejects a missing model", func(t *testing.T) {
+		c := apiCfg("")
+		c.Model = ""
+		_, err := runner.NewAPI(c)
+		require.Error(t, err)
+	})
+
+	t.Run("rejects an unknown model", func(t *testing.T) {
+		c := apiCfg("")
+		c.Model = "gpt-4"
+		_, err := runner.NewAPI(c)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid model")
+	})
+
+	t.Run("rejects a missing API key", func(t *testing.T) {
+		c := apiCfg("")
+		c.APIKey = ""
+		_, err := runner.NewAPI(c)
+		require.Error(t, err)
+	})
+
+	t.Run("rejects a malformed base URL", func(t *testing.T) {
+		_, err := runner.NewAPI(apiCfg("not a url"))
+		require.Error(t, err)
+	})
+
+	t.Run("defaults to the public API when no base URL is set", func(t *testing.T) {
+		_, err := runner.NewAPI(apiCfg(""))
+		require.NoError(t, err)
+	})
+}
+
+func TestFromConfigSelectsBackend(t *testing.T) {
+	t.Run("api backend returns an HTTP runner", func(t *testing.T) {
+		r, err := runner.FromConfig(apiCfg("http://localhost:1"))
+		require.NoError(t, err)
+		assert.IsType(t, &runner.APIRunner{}, r)
+	})
+
+	t.Run("cli backend is the default", func(t *testing.T) {
+		r, err := runner.FromConfig(runner.Config{Binary: selfBin(t), Timeout: time.Second, MaxBytes: 1024})
+		require.NoError(t, err)
+		assert.IsType(t, &runner.ClaudeRunner{}, r)
+	})
+
+	t.Run("rejects an unknown backend", func(t *testing.T) {
+		_, err := runner.FromConfig(runner.Config{Backend: "carrier-pigeon"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unknown runner backend")
+	})
+
+	t.Run("returns a nil runner on error", func(t *testing.T) {
+		r, err := runner.FromConfig(runner.Config{Binary: "definitely-not-a-binary"})
+		require.Error(t, err)
+		assert.Nil(t, r)
+	})
+}
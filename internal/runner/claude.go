@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"strings"
@@ -28,7 +29,17 @@ func New(cfg Config) (*ClaudeRunner, error) {
 	return &ClaudeRunner{cfg: cfg, binaryPath: resolved}, nil
 }
 
+// Path is the binary the runner resolved on PATH.
+func (r *ClaudeRunner) Path() string {
+	return r.binaryPath
+}
+
 func (r *ClaudeRunner) Run(ctx context.Context, prompt string) (string, error) {
+	return r.Stream(ctx, prompt, io.Discard)
+}
+
+// Stream runs prompt, copying the binary's output to w as it is printed.
+func (r *ClaudeRunner) Stream(ctx context.Context, prompt string, w io.Writer) (string, error) {
 	ctx, cancel := context.WithTimeout(ctx, r.cfg.Timeout)
 	defer cancel()
 
@@ -42,7 +53,7 @@ func (r *ClaudeRunner) Run(ctx context.Context, prompt string) (string, error) {
 	cmd.Env = append(safeEnv(os.Environ()), r.cfg.ExtraEnv...)
 
 	lb := &limitedBuffer{max: r.cfg.MaxBytes}
-	cmd.Stdout = lb
+	cmd.Stdout = io.MultiWriter(lb, w)
 
 	if err := cmd.Run(); err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
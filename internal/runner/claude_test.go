@@ -63,6 +63,28 @@ func TestRunnerPassesPromptViaStdin(t *testing.T) {
 	assert.Equal(t, "hello", out)
 }
 
+func TestRunnerStreamsOutput(t *testing.T) {
+	r, err := runner.New(cfg(t, "echo_stdin", 1024))
+	require.NoError(t, err)
+	var w strings.Builder
+	out, err := r.Stream(context.Background(), "hello", &w)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", out)
+	assert.Equal(t, "hello", w.String())
+}
+
+type plainRunner string
+
+func (p plainRunner) Run(context.Context, string) (string, error) { return string(p), nil }
+
+func TestStreamFallsBackToRun(t *testing.T) {
+	var w strings.Builder
+	out, err := runner.Stream(context.Background(), plainRunner("whole"), "", &w)
+	require.NoError(t, err)
+	assert.Equal(t, "whole", out)
+	assert.Equal(t, "whole", w.String())
+}
+
 func TestRunnerEnforcesTimeout(t *testing.T) {
 	c := cfg(t, "sleep", 1024)
 	c.Timeout = 100 * time.Millisecond
@@ -108,6 +130,7 @@ func TestNewRejectsUnknownModel(t *testing.T) {
 
 func TestNewAcceptsEmptyModelForTesting(t *testing.T) {
 	c := runner.Config{Binary: selfBin(t), Model: "", Timeout: time.Second, MaxBytes: 1024}
-	_, err := runner.New(c)
+	r, err := runner.New(c)
 	require.NoError(t, err)
+	assert.Equal(t, selfBin(t), r.Path())
 }
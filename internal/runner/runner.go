@@ -2,19 +2,50 @@ package runner
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"time"
 )
 
+const (
+	BackendCLI = "cli"
+	BackendAPI = "api"
+)
+
 type Runner interface {
 	Run(ctx context.Context, prompt string) (string, error)
 }
 
+// Streamer is a Runner that can also hand over its output as it arrives.
+type Streamer interface {
+	Runner
+	// Stream runs prompt like Run, copying the output to w as it comes.
+	Stream(ctx context.Context, prompt string, w io.Writer) (string, error)
+}
+
+// Stream runs prompt on r, copying the output to w as it arrives when r is
+// a Streamer and all at once when it is not.
+func Stream(ctx context.Context, r Runner, prompt string, w io.Writer) (string, error) {
+	if s, ok := r.(Streamer); ok {
+		return s.Stream(ctx, prompt, w)
+	}
+	out, err := r.Run(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	_, err = io.WriteString(w, out)
+	return out, err
+}
+
 type Config struct {
+	Backend  string
 	Binary   string
 	Model    string
 	Timeout  time.Duration
 	MaxBytes int64
 	ExtraEnv []string
+	BaseURL  string
+	APIKey   string
 }
 
 var ValidModels = map[string]bool{
@@ -22,3 +53,22 @@ var ValidModels = map[string]bool{
 	"claude-sonnet-4-6":         true,
 	"claude-haiku-4-5-20251001": true,
 }
+
+func FromConfig(cfg Config) (Runner, error) {
+	var (
+		r   Runner
+		err error
+	)
+	switch cfg.Backend {
+	case "", BackendCLI:
+		r, err = New(cfg)
+	case BackendAPI:
+		r, err = NewAPI(cfg)
+	default:
+		return nil, fmt.Errorf("unknown runner backend: %q", cfg.Backend)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
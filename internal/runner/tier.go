@@ -0,0 +1,43 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+)
+
+const DefaultTier = "sonnet"
+
+var Tiers = map[string]string{
+	"opus":   "claude-opus-4-6",
+	"sonnet": "claude-sonnet-4-6",
+	"haiku":  "claude-haiku-4-5-20251001",
+}
+
+func ModelForTier(tier string) (string, error) {
+	model, ok := Tiers[strings.ToLower(strings.TrimSpace(tier))]
+	if !ok {
+		return "", fmt.Errorf("invalid tier: %q (want opus, sonnet or haiku)", tier)
+	}
+	return model, nil
+}
+
+func ResolveModel(agent string, getenv func(string) string) (string, error) {
+	key := TierEnvKey(agent)
+	tier := getenv(key)
+	if tier == "" {
+		key = "ENSEMBLE_TIER"
+		tier = getenv(key)
+	}
+	if tier == "" {
+		return ModelForTier(DefaultTier)
+	}
+	model, err := ModelForTier(tier)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", key, err)
+	}
+	return model, nil
+}
+
+func TierEnvKey(agent string) string {
+	return "ENSEMBLE_TIER_" + strings.ToUpper(strings.ReplaceAll(agent, "-", "_"))
+}
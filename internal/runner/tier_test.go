@@ -0,0 +1,65 @@
+package runner_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gauthierbraillon/ensemble/internal/runner"
+)
+
+func envMap(m map[string]string) func(string) string {
+	return func(k string) string { return m[k] }
+}
+
+func TestTierModelsAreValid(t *testing.T) {
+	for tier, model := range runner.Tiers {
+		assert.True(t, runner.ValidModels[model], "tier %s maps to unknown model %s", tier, model)
+	}
+}
+
+func TestResolveModel(t *testing.T) {
+	t.Run("defaults to the sonnet tier", func(t *testing.T) {
+		model, err := runner.ResolveModel("security", envMap(nil))
+		require.NoError(t, err)
+		assert.Equal(t, runner.Tiers["sonnet"], model)
+	})
+
+	t.Run("honours ENSEMBLE_TIER for every agent", func(t *testing.T) {
+		model, err := runner.ResolveModel("security", envMap(map[string]string{"ENSEMBLE_TIER": "haiku"}))
+		require.NoError(t, err)
+		assert.Equal(t, runner.Tiers["haiku"], model)
+	})
+
+	t.Run("per-agent override wins over the global tier", func(t *testing.T) {
+		env := envMap(map[string]string{
+			"ENSEMBLE_TIER":           "haiku",
+			"ENSEMBLE_TIER_SECURITY":  "opus",
+			"ENSEMBLE_TIER_UX_DESIGN": "haiku",
+		})
+		security, err := runner.ResolveModel("security", env)
+		require.NoError(t, err)
+		ux, err := runner.ResolveModel("ux-design", env)
+		require.NoError(t, err)
+		assert.Equal(t, runner.Tiers["opus"], security)
+		assert.Equal(t, runner.Tiers["haiku"], ux)
+	})
+
+	t.Run("tier names are case-insensitive", func(t *testing.T) {
+		model, err := runner.ResolveModel("security", envMap(map[string]string{"ENSEMBLE_TIER": "Opus"}))
+		require.NoError(t, err)
+		assert.Equal(t, runner.Tiers["opus"], model)
+	})
+
+	t.Run("rejects an unknown tier and names the variable", func(t *testing.T) {
+		_, err := runner.ResolveModel("security", envMap(map[string]string{"ENSEMBLE_TIER_SECURITY": "gpt"}))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "ENSEMBLE_TIER_SECURITY")
+		assert.Contains(t, err.Error(), "invalid tier")
+	})
+}
+
+func TestTierEnvKey(t *testing.T) {
+	assert.Equal(t, "ENSEMBLE_TIER_SOFTWARE_ENGINEERING", runner.TierEnvKey("software-engineering"))
+}
@@ -0,0 +1,259 @@
+// Package sarif converts agent findings into a SARIF 2.1.0 log for
+// code-scanning dashboards and IDE viewers.
+package sarif
+
+import (
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gauthierbraillon/ensemble/internal/agent"
+)
+
+const (
+	Version = "2.1.0"
+	Schema  = "https://json.schemastore.org/sarif-2.1.0.json"
+
+	informationURI = "https://github.com/gauthierbraillon/ensemble"
+	srcRoot        = "%SRCROOT%"
+	defaultRule    = "general"
+)
+
+type Log struct {
+	Schema  string `json:"$schema"`
+	Version string `json:"version"`
+	Runs    []Run  `json:"runs"`
+}
+
+type Run struct {
+	Tool              Tool              `json:"tool"`
+	AutomationDetails AutomationDetails `json:"automationDetails"`
+	Results           []Result          `json:"results"`
+}
+
+type Tool struct {
+	Driver Driver `json:"driver"`
+}
+
+type Driver struct {
+	Name           string `json:"name"`
+	Version        string `json:"version,omitempty"`
+	InformationURI string `json:"informationUri"`
+	Rules          []Rule `json:"rules"`
+}
+
+type AutomationDetails struct {
+	ID string `json:"id"`
+}
+
+type Rule struct {
+	ID                   string            `json:"id"`
+	Name                 string            `json:"name"`
+	ShortDescription     Message           `json:"shortDescription"`
+	DefaultConfiguration Configuration     `json:"defaultConfiguration"`
+	Properties           map[string]string `json:"properties,omitempty"`
+}
+
+type Configuration struct {
+	Level string `json:"level"`
+}
+
+type Result struct {
+	RuleID     string                 `json:"ruleId"`
+	RuleIndex  int                    `json:"ruleIndex"`
+	Level      string                 `json:"level"`
+	Message    Message                `json:"message"`
+	Locations  []Location             `json:"locations,omitempty"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+type Message struct {
+	Text string `json:"text"`
+}
+
+type Location struct {
+	PhysicalLocation PhysicalLocation `json:"physicalLocation"`
+}
+
+type PhysicalLocation struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+	Region           *Region          `json:"region,omitempty"`
+}
+
+type ArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type Region struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+var securitySeverity = map[agent.Severity]string{
+	agent.Low:      "2.0",
+	agent.Medium:   "5.5",
+	agent.High:     "8.0",
+	agent.Critical: "9.5",
+}
+
+var nonSlug = regexp.MustCompile(`[^a-z0-9]+`)
+
+// FromFindings builds one run per agent, in the order agents first appear.
+// Passing findings carry no result and are dropped; every agent still gets a run.
+func FromFindings(findings []agent.Finding, toolVersion string) Log {
+	var runs []*Run
+	byAgent := map[string]*Run{}
+	ruleIndex := map[string]int{}
+	for _, f := range findings {
+		run, ok := byAgent[f.Agent]
+		if !ok {
+			run = newRun(f.Agent, toolVersion)
+			byAgent[f.Agent] = run
+			runs = append(runs, run)
+		}
+		if f.Verdict == agent.Pass {
+			continue
+		}
+		id := RuleID(f)
+		idx, ok := ruleIndex[id]
+		if !ok {
+			idx = len(run.Tool.Driver.Rules)
+			ruleIndex[id] = idx
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, newRule(id, f))
+		}
+		run.Results = append(run.Results, newResult(id, idx, f))
+	}
+	log := Log{Schema: Schema, Version: Version, Runs: []Run{}}
+	for _, r := range runs {
+		log.Runs = append(log.Runs, *r)
+	}
+	return log
+}
+
+// RuleID derives a stable rule identifier from a finding's agent and category.
+func RuleID(f agent.Finding) string {
+	category := strings.Trim(nonSlug.ReplaceAllString(strings.ToLower(f.Category), "-"), "-")
+	if category == "" {
+		category = defaultRule
+	}
+	return f.Agent + "/" + category
+}
+
+// ParseLocation splits a finding's file field ("path", "path:line" or
+// "path:line:column") into its parts. Zero means unknown.
+func ParseLocation(file string) (path string, line, column int) {
+	path = strings.TrimSpace(file)
+	parts := strings.Split(path, ":")
+	var nums []int
+	for len(parts) > 1 && len(nums) < 2 {
+		n, err := strconv.Atoi(leadingDigits(parts[len(parts)-1]))
+		if err != nil || n <= 0 {
+			break
+		}
+		nums = append([]int{n}, nums...)
+		parts = parts[:len(parts)-1]
+	}
+	path = strings.Join(parts, ":")
+	switch len(nums) {
+	case 1:
+		line = nums[0]
+	case 2:
+		line, column = nums[0], nums[1]
+	}
+	return path, line, column
+}
+
+func leadingDigits(s string) string {
+	end := 0
+	for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+		end++
+	}
+	if end == 0 {
+		return s
+	}
+	return s[:end]
+}
+
+func newRun(agentName, toolVersion string) *Run {
+	return &Run{
+		Tool: Tool{Driver: Driver{
+			Name:           "ensemble/" + agentName,
+			Version:        toolVersion,
+			InformationURI: informationURI,
+			Rules:          []Rule{},
+		}},
+		AutomationDetails: AutomationDetails{ID: "ensemble/" + agentName + "/"},
+		Results:           []Result{},
+	}
+}
+
+func newRule(id string, f agent.Finding) Rule {
+	name := id[strings.Index(id, "/")+1:]
+	r := Rule{
+		ID:                   id,
+		Name:                 name,
+		ShortDescription:     Message{Text: f.Agent + ": " + strings.ReplaceAll(name, "-", " ")},
+		DefaultConfiguration: Configuration{Level: level(f.Verdict)},
+	}
+	if f.Agent == "security" {
+		r.Properties = map[string]string{"security-severity": securitySeverity[f.Severity]}
+	}
+	return r
+}
+
+func newResult(id string, idx int, f agent.Finding) Result {
+	r := Result{
+		RuleID:     id,
+		RuleIndex:  idx,
+		Level:      level(f.Verdict),
+		Message:    Message{Text: message(f)},
+		Properties: map[string]interface{}{"severity": string(f.Severity)},
+	}
+	if f.Model != "" {
+		r.Properties["model"] = f.Model
+	}
+	if f.Fix != "" {
+		r.Properties["fix"] = f.Fix
+	}
+	if loc, ok := location(f.File); ok {
+		r.Locations = []Location{loc}
+	}
+	return r
+}
+
+func message(f agent.Finding) string {
+	if f.Fix == "" {
+		return f.Finding
+	}
+	return f.Finding + ". Fix: " + f.Fix
+}
+
+func location(file string) (Location, bool) {
+	path, line, column := ParseLocation(file)
+	if path == "" {
+		return Location{}, false
+	}
+	artifact := ArtifactLocation{URI: filepath.ToSlash(path), URIBaseID: srcRoot}
+	if filepath.IsAbs(path) {
+		artifact = ArtifactLocation{URI: (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()}
+	}
+	loc := Location{PhysicalLocation: PhysicalLocation{ArtifactLocation: artifact}}
+	if line > 0 {
+		loc.PhysicalLocation.Region = &Region{StartLine: line, StartColumn: column}
+	}
+	return loc, true
+}
+
+func level(v agent.Verdict) string {
+	switch v {
+	case agent.Block:
+		return "error"
+	case agent.Warn:
+		return "warning"
+	default:
+		return "note"
+	}
+}
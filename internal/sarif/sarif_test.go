@@ -0,0 +1,121 @@
+package sarif_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gauthierbraillon/ensemble/internal/agent"
+	"github.com/gauthierbraillon/ensemble/internal/sarif"
+)
+
+func sampleFindings() []agent.Finding {
+	return []agent.Finding{
+		{Agent: "testing-quality", Verdict: agent.Block, Severity: agent.Critical, Category: "missing-test", Finding: "implementation without test", File: "internal/bar/bar.go", Fix: "add internal/bar/bar_test.go with a failing test first"},
+		{Agent: "software-engineering", Verdict: agent.Pass, Severity: agent.Low, Finding: "no code quality issues found"},
+		{Agent: "security", Verdict: agent.Warn, Severity: agent.High, Category: "SQL Injection", Finding: "query built by concatenation", File: "db.go:42:7", Model: "claude-opus-4-6"},
+		{Agent: "security", Verdict: agent.Block, Severity: agent.Critical, Category: "sql-injection", Finding: "user input in query", File: "db.go:50"},
+	}
+}
+
+func TestFromFindings(t *testing.T) {
+	log := sarif.FromFindings(sampleFindings(), "1.2.3")
+
+	t.Run("declares SARIF 2.1.0", func(t *testing.T) {
+		assert.Equal(t, "2.1.0", log.Version)
+		assert.NotEmpty(t, log.Schema)
+	})
+
+	t.Run("emits one run per agent in order of appearance", func(t *testing.T) {
+		require.Len(t, log.Runs, 3)
+		assert.Equal(t, "ensemble/testing-quality", log.Runs[0].Tool.Driver.Name)
+		assert.Equal(t, "ensemble/software-engineering", log.Runs[1].Tool.Driver.Name)
+		assert.Equal(t, "ensemble/security", log.Runs[2].Tool.Driver.Name)
+		assert.Equal(t, "1.2.3", log.Runs[0].Tool.Driver.Version)
+	})
+
+	t.Run("drops passing findings but keeps the agent's run", func(t *testing.T) {
+		assert.Empty(t, log.Runs[1].Results)
+	})
+
+	t.Run("maps verdicts to SARIF levels", func(t *testing.T) {
+		assert.Equal(t, "error", log.Runs[0].Results[0].Level)
+		assert.Equal(t, "warning", log.Runs[2].Results[0].Level)
+	})
+
+	t.Run("shares one rule between findings of the same agent and category", func(t *testing.T) {
+		security := log.Runs[2]
+		require.Len(t, security.Tool.Driver.Rules, 1)
+		assert.Equal(t, "security/sql-injection", security.Tool.Driver.Rules[0].ID)
+		for _, r := range security.Results {
+			assert.Equal(t, "security/sql-injection", r.RuleID)
+			assert.Equal(t, 0, r.RuleIndex)
+		}
+		assert.NotEmpty(t, security.Tool.Driver.Rules[0].Properties["security-severity"])
+	})
+
+	t.Run("parses path and line into a physical location", func(t *testing.T) {
+		loc := log.Runs[2].Results[0].Locations[0].PhysicalLocation
+		assert.Equal(t, "db.go", loc.ArtifactLocation.URI)
+		assert.Equal(t, "%SRCROOT%", loc.ArtifactLocation.URIBaseID)
+		require.NotNil(t, loc.Region)
+		assert.Equal(t, 42, loc.Region.StartLine)
+		assert.Equal(t, 7, loc.Region.StartColumn)
+	})
+
+	t.Run("omits the region when the file has no line", func(t *testing.T) {
+		loc := log.Runs[0].Results[0].Locations[0].PhysicalLocation
+		assert.Equal(t, "internal/bar/bar.go", loc.ArtifactLocation.URI)
+		assert.Nil(t, loc.Region)
+	})
+
+	t.Run("carries the fix and model along with the message", func(t *testing.T) {
+		assert.Contains(t, log.Runs[0].Results[0].Message.Text, "add internal/bar/bar_test.go")
+		assert.Equal(t, "claude-opus-4-6", log.Runs[2].Results[0].Properties["model"])
+	})
+
+	t.Run("serialises required SARIF properties", func(t *testing.T) {
+		data, err := json.Marshal(log)
+		require.NoError(t, err)
+		var raw map[string]interface{}
+		require.NoError(t, json.Unmarshal(data, &raw))
+		assert.Contains(t, raw, "$schema")
+		run := raw["runs"].([]interface{})[0].(map[string]interface{})
+		assert.Contains(t, run, "tool")
+		assert.Contains(t, run, "results")
+	})
+}
+
+func TestFromFindingsWithNoFindings(t *testing.T) {
+	data, err := json.Marshal(sarif.FromFindings(nil, ""))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"runs":[]`)
+}
+
+func TestRuleIDDefaultsCategory(t *testing.T) {
+	assert.Equal(t, "ux-design/general", sarif.RuleID(agent.Finding{Agent: "ux-design"}))
+}
+
+func TestParseLocation(t *testing.T) {
+	cases := []struct {
+		in           string
+		path         string
+		line, column int
+	}{
+		{"", "", 0, 0},
+		{"foo.go", "foo.go", 0, 0},
+		{"foo.go:12", "foo.go", 12, 0},
+		{"foo.go:12:3", "foo.go", 12, 3},
+		{"foo.go:12-18", "foo.go", 12, 0},
+		{"C:/src/foo.go:9", "C:/src/foo.go", 9, 0},
+		{"foo.go:bar", "foo.go:bar", 0, 0},
+	}
+	for _, c := range cases {
+		path, line, column := sarif.ParseLocation(c.in)
+		assert.Equal(t, c.path, path, c.in)
+		assert.Equal(t, c.line, line, c.in)
+		assert.Equal(t, c.column, column, c.in)
+	}
+}
@@ -0,0 +1,286 @@
+package shell
+
+import (
+	"path"
+	"strings"
+)
+
+type redir struct {
+	target  string
+	writes  bool
+	heredoc bool
+	body    string
+
+	delim     string
+	stripTabs bool
+}
+
+// command is one simple command: its words after quote removal and its
+// redirections.
+type command struct {
+	words  []string
+	redirs []*redir
+}
+
+// wrappers run the command that follows them.
+var wrappers = map[string]bool{
+	"sudo": true, "env": true, "command": true, "exec": true, "nohup": true, "time": true, "xargs": true,
+}
+
+// keywords may precede a command without being one.
+var keywords = map[string]bool{
+	"!": true, "{": true, "}": true, "if": true, "then": true, "else": true, "elif": true,
+	"while": true, "until": true, "do": true,
+}
+
+// name returns the command being run, without its directory, and its
+// arguments, looking past variable assignments, wrappers and keywords.
+func (c command) name() (string, []string) {
+	words := c.words
+	for len(words) > 0 {
+		w := words[0]
+		switch {
+		case keywords[w] || isAssignment(w):
+			words = words[1:]
+		case wrappers[w]:
+			words = words[1:]
+			for len(words) > 0 && (strings.HasPrefix(words[0], "-") || isAssignment(words[0])) {
+				words = words[1:]
+			}
+		default:
+			return path.Base(w), words[1:]
+		}
+	}
+	return "", nil
+}
+
+func isAssignment(w string) bool {
+	i := strings.IndexByte(w, '=')
+	if i <= 0 {
+		return false
+	}
+	for _, r := range w[:i] {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+type lexer struct {
+	src     string
+	pos     int
+	cmds    []command
+	cur     command
+	pending []*redir
+}
+
+func parse(src string) []command {
+	l := &lexer{src: src}
+	for l.pos < len(l.src) {
+		ch := l.src[l.pos]
+		switch {
+		case ch == '\n':
+			l.pos++
+			l.end()
+			l.heredocBodies()
+		case ch == ' ' || ch == '\t' || ch == '\r':
+			l.pos++
+		case ch == '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+		case ch == '&' && l.peek(1) == '>':
+			l.pos++
+			l.redirect(true)
+		case ch == ';' || ch == '&' || ch == '|' || ch == '(' || ch == ')':
+			l.pos++
+			l.end()
+		case ch == '<' || ch == '>':
+			l.redirect(false)
+		default:
+			start := l.pos
+			w := l.word()
+			if next := l.peek(0); (next == '<' || next == '>') && isDigits(l.src[start:l.pos]) {
+				l.redirect(false)
+				continue
+			}
+			l.cur.words = append(l.cur.words, w)
+		}
+	}
+	l.end()
+	return l.cmds
+}
+
+func (l *lexer) peek(off int) byte {
+	if l.pos+off < len(l.src) {
+		return l.src[l.pos+off]
+	}
+	return 0
+}
+
+func (l *lexer) end() {
+	if len(l.cur.words) > 0 || len(l.cur.redirs) > 0 {
+		l.cmds = append(l.cmds, l.cur)
+	}
+	l.cur = command{}
+}
+
+// redirect reads a redirection operator and its target. all is set when a
+// leading & (as in &>) has already been consumed.
+func (l *lexer) redirect(all bool) {
+	op := ""
+	for l.pos < len(l.src) && strings.IndexByte("<>&|-", l.src[l.pos]) >= 0 && len(op) < 3 {
+		next := op + string(l.src[l.pos])
+		if !validOperator(next) {
+			break
+		}
+		op = next
+		l.pos++
+	}
+	l.skipBlanks()
+	target := l.word()
+	r := &redir{target: target}
+	switch op {
+	case "<<", "<<-":
+		r.heredoc, r.target, r.delim, r.stripTabs = true, "", target, op == "<<-"
+		l.pending = append(l.pending, r)
+	case ">", ">>", ">|", "<>":
+		r.writes = true
+	case ">&":
+		r.writes = all || !(target == "-" || isDigits(target))
+	}
+	if all {
+		r.writes = true
+	}
+	l.cur.redirs = append(l.cur.redirs, r)
+}
+
+func validOperator(op string) bool {
+	switch op {
+	case "<", ">", "<<", ">>", "<<-", "<<<", ">|", "<>", ">&", "<&":
+		return true
+	}
+	return false
+}
+
+func (l *lexer) skipBlanks() {
+	for l.pos < len(l.src) && (l.src[l.pos] == ' ' || l.src[l.pos] == '\t') {
+		l.pos++
+	}
+}
+
+// heredocBodies consumes the here-documents opened on the line just ended.
+func (l *lexer) heredocBodies() {
+	for _, r := range l.pending {
+		var body strings.Builder
+		for l.pos < len(l.src) {
+			end := strings.IndexByte(l.src[l.pos:], '\n')
+			line := l.src[l.pos:]
+			if end >= 0 {
+				line = l.src[l.pos : l.pos+end]
+				l.pos += end + 1
+			} else {
+				l.pos = len(l.src)
+			}
+			if r.stripTabs {
+				line = strings.TrimLeft(line, "\t")
+			}
+			if line == r.delim {
+				break
+			}
+			body.WriteString(line)
+			body.WriteByte('\n')
+		}
+		r.body = body.String()
+	}
+	l.pending = nil
+}
+
+// word reads one word, removing quotes and backslashes. Substitutions are
+// kept verbatim.
+func (l *lexer) word() string {
+	var b strings.Builder
+	for l.pos < len(l.src) {
+		ch := l.src[l.pos]
+		switch {
+		case strings.IndexByte(" \t\r\n;&|()<>", ch) >= 0:
+			return b.String()
+		case ch == '\\':
+			if l.pos+1 < len(l.src) && l.src[l.pos+1] != '\n' {
+				b.WriteByte(l.src[l.pos+1])
+			}
+			l.pos += 2
+		case ch == '\'':
+			end := strings.IndexByte(l.src[l.pos+1:], '\'')
+			if end < 0 {
+				b.WriteString(l.src[l.pos+1:])
+				l.pos = len(l.src)
+				continue
+			}
+			b.WriteString(l.src[l.pos+1 : l.pos+1+end])
+			l.pos += end + 2
+		case ch == '"':
+			l.pos++
+			for l.pos < len(l.src) && l.src[l.pos] != '"' {
+				if l.src[l.pos] == '\\' && l.pos+1 < len(l.src) && strings.IndexByte("\"\\$`", l.src[l.pos+1]) >= 0 {
+					l.pos++
+				}
+				b.WriteByte(l.src[l.pos])
+				l.pos++
+			}
+			l.pos++
+		case ch == '$' && (l.peek(1) == '(' || l.peek(1) == '{'):
+			b.WriteString(l.balanced(l.peek(1)))
+		case ch == '`':
+			stop := len(l.src)
+			if end := strings.IndexByte(l.src[l.pos+1:], '`'); end >= 0 {
+				stop = l.pos + end + 2
+			}
+			b.WriteString(l.src[l.pos:stop])
+			l.pos = stop
+		default:
+			b.WriteByte(ch)
+			l.pos++
+		}
+	}
+	return b.String()
+}
+
+// balanced returns $(...) or ${...} verbatim, nesting included.
+func (l *lexer) balanced(open byte) string {
+	closeCh := byte(')')
+	if open == '{' {
+		closeCh = '}'
+	}
+	start := l.pos
+	l.pos += 2
+	depth := 1
+	for l.pos < len(l.src) && depth > 0 {
+		switch l.src[l.pos] {
+		case open:
+			depth++
+		case closeCh:
+			depth--
+		case '\\':
+			l.pos++
+		}
+		l.pos++
+	}
+	if l.pos > len(l.src) {
+		l.pos = len(l.src)
+	}
+	return l.src[start:l.pos]
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
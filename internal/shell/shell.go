@@ -0,0 +1,185 @@
+// Package shell finds the files a shell command line would write, so the
+// TDD hook can judge Bash tool calls like it judges Write and Edit.
+//
+// It understands enough POSIX shell for commands an agent typically runs:
+// quoting, command lists and pipelines, redirections, here-documents and
+// cd. It does not expand variables, globs or command substitutions; a
+// target that depends on them is reported as written.
+package shell
+
+import (
+	"path"
+	"strings"
+)
+
+// Write is one file a command writes. Content is the text written when the
+// command spells it out, as with a here-document; otherwise it is empty.
+type Write struct {
+	Path    string
+	Content string
+}
+
+// Writes lists the files command writes, in order. Relative paths are
+// resolved against any cd earlier on the line, but stay relative.
+func Writes(command string) []Write {
+	var writes []Write
+	dir := ""
+	for _, c := range parse(command) {
+		name, args := c.name()
+		if name == "cd" {
+			if len(args) > 0 {
+				dir = join(dir, args[0])
+			}
+			continue
+		}
+		var content string
+		for _, r := range c.redirs {
+			if r.heredoc {
+				content = r.body
+			}
+		}
+		for _, r := range c.redirs {
+			if r.writes {
+				writes = appendWrite(writes, dir, r.target, content)
+			}
+		}
+		for _, target := range commandTargets(name, args) {
+			writes = appendWrite(writes, dir, target, "")
+		}
+	}
+	return writes
+}
+
+func appendWrite(writes []Write, dir, target, content string) []Write {
+	if target == "" || strings.HasPrefix(target, "/dev/") {
+		return writes
+	}
+	return append(writes, Write{Path: join(dir, target), Content: content})
+}
+
+func join(dir, p string) string {
+	if dir == "" || path.IsAbs(p) {
+		return p
+	}
+	return path.Join(dir, p)
+}
+
+// commandTargets knows the common commands that write the files named in
+// their arguments.
+func commandTargets(name string, args []string) []string {
+	switch name {
+	case "tee":
+		return positional(args)
+	case "sed", "perl":
+		return inPlaceTargets(args)
+	case "cp", "mv", "install":
+		return copyTargets(args)
+	case "dd":
+		for _, a := range args {
+			if strings.HasPrefix(a, "of=") {
+				return []string{strings.TrimPrefix(a, "of=")}
+			}
+		}
+	}
+	return nil
+}
+
+// positional returns the non-option arguments.
+func positional(args []string) []string {
+	var out []string
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--":
+			return append(out, args[i+1:]...)
+		case strings.HasPrefix(a, "-") && a != "-":
+		default:
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// inPlaceTargets returns the files sed -i or perl -i edits: the positional
+// arguments after the script, which comes from -e/-f or the first positional.
+func inPlaceTargets(args []string) []string {
+	inPlace, scripted := false, false
+	var files []string
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--in-place" || strings.HasPrefix(a, "--in-place="):
+			inPlace = true
+		case a == "-e" || a == "-f" || a == "--expression" || a == "--file":
+			scripted = true
+			i++
+		case strings.HasPrefix(a, "--expression=") || strings.HasPrefix(a, "--file="):
+			scripted = true
+		case strings.HasPrefix(a, "--"):
+		case strings.HasPrefix(a, "-") && len(a) > 1:
+			// A cluster like -ni.bak or -pe: after i comes a backup suffix,
+			// after e or f the script, attached or as the next argument.
+			for j := 1; j < len(a); j++ {
+				switch a[j] {
+				case 'i':
+					inPlace = true
+					j = len(a)
+				case 'e', 'f':
+					scripted = true
+					if j == len(a)-1 {
+						i++
+					}
+					j = len(a)
+				}
+			}
+		default:
+			files = append(files, a)
+		}
+	}
+	if !inPlace {
+		return nil
+	}
+	if !scripted && len(files) > 0 {
+		files = files[1:]
+	}
+	return files
+}
+
+// copyTargets returns where cp, mv or install put their files.
+func copyTargets(args []string) []string {
+	dir := ""
+	var operands []string
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "-t" || a == "--target-directory":
+			if i+1 < len(args) {
+				dir = args[i+1]
+			}
+			i++
+		case strings.HasPrefix(a, "--target-directory="):
+			dir = strings.TrimPrefix(a, "--target-directory=")
+		case a == "-m" || a == "-o" || a == "-g" || a == "-S" || a == "--suffix":
+			i++
+		case strings.HasPrefix(a, "-") && a != "-":
+		default:
+			operands = append(operands, a)
+		}
+	}
+	if dir == "" {
+		if len(operands) < 2 {
+			return nil
+		}
+		dest := operands[len(operands)-1]
+		operands = operands[:len(operands)-1]
+		if !strings.HasSuffix(dest, "/") && len(operands) == 1 {
+			return []string{dest}
+		}
+		dir = dest
+	}
+	var out []string
+	for _, src := range operands {
+		out = append(out, path.Join(dir, path.Base(src)))
+	}
+	return out
+}
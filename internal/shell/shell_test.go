@@ -0,0 +1,84 @@
+package shell_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gauthierbraillon/ensemble/internal/shell"
+)
+
+func paths(ws []shell.Write) []string {
+	var out []string
+	for _, w := range ws {
+		out = append(out, w.Path)
+	}
+	return out
+}
+
+func TestWrites(t *testing.T) {
+	cases := []struct {
+		name    string
+		command string
+		want    []string
+	}{
+		{"plain redirect", "echo hi > foo.go", []string{"foo.go"}},
+		{"append without spaces", "echo hi>>foo.go", []string{"foo.go"}},
+		{"clobber", "echo hi >| foo.go", []string{"foo.go"}},
+		{"stdout and stderr", "go run . &> out.log", []string{"out.log"}},
+		{"numbered descriptor", "go build 2> build.err", []string{"build.err"}},
+		{"descriptor duplication writes nothing", "go test ./... 2>&1 >&2", nil},
+		{"reading is not writing", "wc -l < foo.go", nil},
+		{"here-string is not writing", "cat <<< 'x'", nil},
+		{"device files are ignored", "go test ./... > /dev/null 2>/dev/null", nil},
+		{"quoted target", `echo hi > "my file.go"`, []string{"my file.go"}},
+		{"escaped space", `echo hi > my\ file.go`, []string{"my file.go"}},
+		{"redirect after a pipeline", "cat a.go | grep x > b.go", []string{"b.go"}},
+		{"every command in a list", "echo a > a.go && echo b > b.go; echo c > c.go || true", []string{"a.go", "b.go", "c.go"}},
+		{"operators inside quotes are text", `echo "a > b; c" > real.go`, []string{"real.go"}},
+		{"comment is ignored", "echo hi # > not.go", nil},
+		{"tee", "echo hi | tee -a one.go two.go", []string{"one.go", "two.go"}},
+		{"sudo tee", "echo hi | sudo tee /etc/x.conf > /dev/null", []string{"/etc/x.conf"}},
+		{"sed in place", "sed -i 's/a/b/' foo.go bar.go", []string{"foo.go", "bar.go"}},
+		{"sed in place with suffix and script flag", "sed -i.bak -e 's/a/b/' -e 's/c/d/' foo.go", []string{"foo.go"}},
+		{"sed in place with clustered flags", "sed -Ei 's/a/b/' foo.go", []string{"foo.go"}},
+		{"sed long in place", "sed --in-place=.orig --expression='s/a/b/' foo.go", []string{"foo.go"}},
+		{"sed to stdout writes nothing", "sed 's/a/b/' foo.go", nil},
+		{"perl pie", "perl -pi -e 's/a/b/' foo.go", []string{"foo.go"}},
+		{"cp to a file", "cp -f template.go foo.go", []string{"foo.go"}},
+		{"mv into a directory", "mv a.go b.go pkg/", []string{"pkg/a.go", "pkg/b.go"}},
+		{"cp with target directory", "cp -t pkg a.go", []string{"pkg/a.go"}},
+		{"install with mode", "install -m 0644 a.go dst.go", []string{"dst.go"}},
+		{"dd", "dd if=/dev/zero of=blob.bin bs=1 count=1", []string{"blob.bin"}},
+		{"cd changes where relative targets land", "cd internal/foo && echo x > foo.go", []string{"internal/foo/foo.go"}},
+		{"absolute targets ignore cd", "cd sub && echo x > /tmp/a.go", []string{"/tmp/a.go"}},
+		{"assignments and keywords", "if true; then GOOS=linux sed -i 's/a/b/' x.go; fi", []string{"x.go"}},
+		{"command substitution is kept whole", "echo $(cat a > b) > out.go", []string{"out.go"}},
+		{"nothing written", "go test ./...", nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.want, paths(shell.Writes(c.command)))
+		})
+	}
+}
+
+func TestWritesHereDocuments(t *testing.T) {
+	t.Run("captures the body as content", func(t *testing.T) {
+		ws := shell.Writes("cat > src/lib.rs <<'EOF'\npub fn f() {}\n#[cfg(test)]\nmod tests {}\nEOF\necho done > log.txt\n")
+		assert.Equal(t, []string{"src/lib.rs", "log.txt"}, paths(ws))
+		assert.Equal(t, "pub fn f() {}\n#[cfg(test)]\nmod tests {}\n", ws[0].Content)
+		assert.Empty(t, ws[1].Content)
+	})
+
+	t.Run("redirect after the here-document operator", func(t *testing.T) {
+		ws := shell.Writes("cat <<-END > foo.go\n\tpackage foo\n\tEND\n")
+		assert.Equal(t, []string{"foo.go"}, paths(ws))
+		assert.Equal(t, "package foo\n", ws[0].Content)
+	})
+
+	t.Run("body lines are not parsed as commands", func(t *testing.T) {
+		ws := shell.Writes("cat > a.go <<EOF\necho x > b.go\nEOF")
+		assert.Equal(t, []string{"a.go"}, paths(ws))
+	})
+}
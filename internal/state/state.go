@@ -0,0 +1,115 @@
+// Package state keeps what ensemble remembers between runs in .ensemble/ at
+// the project root. The directory ignores itself, so nothing in it is ever
+// committed or shows up in a review of the working tree.
+package state
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gauthierbraillon/ensemble/internal/agent"
+)
+
+// Dir is the state directory, relative to the project root.
+const Dir = ".ensemble"
+
+const cycleFile = "last-cycle.json"
+
+// Cycle records the outcome of the last ensemble cycle.
+type Cycle struct {
+	Time time.Time `json:"time"`
+	// Blocking are the findings that met the block threshold.
+	Blocking []agent.Finding `json:"blocking"`
+}
+
+// SaveCycle records c as the last cycle run under root.
+func SaveCycle(root string, c Cycle) error {
+	return Save(root, cycleFile, c)
+}
+
+// LoadCycle returns the last cycle run under root. It wraps os.ErrNotExist
+// when no cycle has run yet.
+func LoadCycle(root string) (Cycle, error) {
+	var c Cycle
+	err := Load(root, cycleFile, &c)
+	return c, err
+}
+
+// Save stores v as JSON under name, a slash-separated path inside the state
+// directory, replacing any earlier value atomically.
+func Save(root, name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return write(root, filepath.FromSlash(name), append(data, '\n'))
+}
+
+// Load decodes the JSON stored under name into v. It wraps os.ErrNotExist
+// when nothing is stored there.
+func Load(root, name string, v interface{}) error {
+	data, err := os.ReadFile(filepath.Join(root, Dir, filepath.FromSlash(name))) // #nosec G304
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Append adds v to name as one line of JSON, creating the file on first
+// use. Lines are appended whole, so a reader never sees half of one.
+func Append(root, name string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	name = filepath.FromSlash(name)
+	if err := prepare(root, name); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(root, Dir, name), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600) // #nosec G304
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// write replaces name in the state directory atomically.
+func write(root, name string, data []byte) error {
+	if err := prepare(root, name); err != nil {
+		return err
+	}
+	dir := filepath.Join(root, Dir, filepath.Dir(name))
+	tmp, err := os.CreateTemp(dir, filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(root, Dir, name))
+}
+
+// prepare creates the directories name lives in and, on first use, the
+// .gitignore that keeps the state directory out of git.
+func prepare(root, name string) error {
+	top := filepath.Join(root, Dir)
+	if err := os.MkdirAll(filepath.Join(top, filepath.Dir(name)), 0750); err != nil {
+		return err
+	}
+	ignore := filepath.Join(top, ".gitignore")
+	if _, err := os.Stat(ignore); os.IsNotExist(err) {
+		return os.WriteFile(ignore, []byte("*\n"), 0600)
+	}
+	return nil
+}
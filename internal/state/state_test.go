@@ -0,0 +1,80 @@
+package state_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gauthierbraillon/ensemble/internal/agent"
+	"github.com/gauthierbraillon/ensemble/internal/state"
+)
+
+func TestCycle(t *testing.T) {
+	t.Run("round-trips the last cycle", func(t *testing.T) {
+		root := t.TempDir()
+		saved := state.Cycle{
+			Time:     time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC),
+			Blocking: []agent.Finding{{Agent: "testing-quality", Verdict: agent.Block, Severity: agent.Critical, Finding: "implementation without test", File: "foo.go"}},
+		}
+		require.NoError(t, state.SaveCycle(root, saved))
+
+		got, err := state.LoadCycle(root)
+		require.NoError(t, err)
+		assert.Equal(t, saved, got)
+	})
+
+	t.Run("the state directory ignores itself", func(t *testing.T) {
+		root := t.TempDir()
+		require.NoError(t, state.SaveCycle(root, state.Cycle{}))
+		data, err := os.ReadFile(filepath.Join(root, state.Dir, ".gitignore"))
+		require.NoError(t, err)
+		assert.Equal(t, "*\n", string(data))
+	})
+
+	t.Run("no cycle yet", func(t *testing.T) {
+		_, err := state.LoadCycle(t.TempDir())
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("a later cycle replaces the earlier one", func(t *testing.T) {
+		root := t.TempDir()
+		require.NoError(t, state.SaveCycle(root, state.Cycle{Blocking: []agent.Finding{{Finding: "old"}}}))
+		require.NoError(t, state.SaveCycle(root, state.Cycle{}))
+		got, err := state.LoadCycle(root)
+		require.NoError(t, err)
+		assert.Empty(t, got.Blocking)
+		entries, err := os.ReadDir(filepath.Join(root, state.Dir))
+		require.NoError(t, err)
+		assert.Len(t, entries, 2, "no temporary files left behind")
+	})
+}
+
+func TestSaveLoad(t *testing.T) {
+	root := t.TempDir()
+	type phase struct {
+		Name string `json:"name"`
+	}
+	require.NoError(t, state.Save(root, "tdd/abc.json", phase{Name: "GREEN"}))
+
+	var got phase
+	require.NoError(t, state.Load(root, "tdd/abc.json", &got))
+	assert.Equal(t, "GREEN", got.Name)
+	assert.FileExists(t, filepath.Join(root, state.Dir, ".gitignore"), "nested names share the top-level ignore")
+
+	assert.ErrorIs(t, state.Load(root, "tdd/missing.json", &got), os.ErrNotExist)
+}
+
+func TestAppend(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, state.Append(root, "sessions/a.jsonl", map[string]int{"n": 1}))
+	require.NoError(t, state.Append(root, "sessions/a.jsonl", map[string]int{"n": 2}))
+
+	data, err := os.ReadFile(filepath.Join(root, state.Dir, "sessions", "a.jsonl"))
+	require.NoError(t, err)
+	assert.Equal(t, "{\"n\":1}\n{\"n\":2}\n", string(data))
+	assert.FileExists(t, filepath.Join(root, state.Dir, ".gitignore"))
+}
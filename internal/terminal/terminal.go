@@ -0,0 +1,155 @@
+// Package terminal reads lines from an interactive terminal with the little
+// editing a prompt needs: backspace, clearing the line, and completion on
+// Tab. It talks to the terminal directly, so it runs only where the input
+// is one.
+package terminal
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInterrupted is returned for a line abandoned with Ctrl-C.
+var ErrInterrupted = errors.New("interrupted")
+
+// Editor reads lines typed at a terminal.
+type Editor struct {
+	// Complete lists the lines the one typed so far may become. Nil turns
+	// completion off.
+	Complete func(line string) []string
+
+	in  *bufio.Reader
+	out io.Writer
+	// raw switches the terminal to raw mode for one line; nil when the
+	// input is not a terminal, as in tests.
+	raw func() (func(), error)
+}
+
+// Open returns an editor for f, or false when f is not a terminal.
+func Open(f *os.File, out io.Writer) (*Editor, bool) {
+	if !isTerminal(f.Fd()) {
+		return nil, false
+	}
+	e := newEditor(f, out)
+	e.raw = func() (func(), error) { return makeRaw(f.Fd()) }
+	return e, true
+}
+
+func newEditor(in io.Reader, out io.Writer) *Editor {
+	return &Editor{in: bufio.NewReader(in), out: out}
+}
+
+// ReadLine shows prompt and returns the line typed, without its newline.
+// It returns io.EOF for Ctrl-D on an empty line and ErrInterrupted for
+// Ctrl-C.
+func (e *Editor) ReadLine(prompt string) (string, error) {
+	if e.raw != nil {
+		restore, err := e.raw()
+		if err != nil {
+			return "", err
+		}
+		defer restore()
+	}
+	fmt.Fprint(e.out, prompt)
+	var line []rune
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			if err == io.EOF && len(line) > 0 {
+				fmt.Fprintln(e.out)
+				return string(line), nil
+			}
+			return "", err
+		}
+		switch r {
+		case '\r', '\n':
+			fmt.Fprintln(e.out)
+			return string(line), nil
+		case 0x03: // Ctrl-C
+			fmt.Fprintln(e.out, "^C")
+			return "", ErrInterrupted
+		case 0x04: // Ctrl-D
+			if len(line) == 0 {
+				fmt.Fprintln(e.out)
+				return "", io.EOF
+			}
+		case 0x7f, 0x08: // Backspace
+			if len(line) > 0 {
+				line = line[:len(line)-1]
+				fmt.Fprint(e.out, "\b \b")
+			}
+		case 0x15: // Ctrl-U
+			line = nil
+			e.redraw(prompt, line)
+		case '\t':
+			line = e.complete(prompt, line)
+		case 0x1b:
+			e.skipEscape()
+		default:
+			if r >= ' ' {
+				line = append(line, r)
+				fmt.Fprint(e.out, string(r))
+			}
+		}
+	}
+}
+
+// complete extends line as far as every candidate agrees, and lists the
+// candidates when that adds nothing.
+func (e *Editor) complete(prompt string, line []rune) []rune {
+	if e.Complete == nil {
+		return line
+	}
+	candidates := e.Complete(string(line))
+	if len(candidates) == 0 {
+		fmt.Fprint(e.out, "\a")
+		return line
+	}
+	common := commonPrefix(candidates)
+	if len(common) > len(string(line)) {
+		line = []rune(common)
+		e.redraw(prompt, line)
+		return line
+	}
+	sorted := append([]string(nil), candidates...)
+	sort.Strings(sorted)
+	fmt.Fprintf(e.out, "\n%s\n", strings.Join(sorted, "  "))
+	fmt.Fprint(e.out, prompt+string(line))
+	return line
+}
+
+// redraw rewrites the current line in place.
+func (e *Editor) redraw(prompt string, line []rune) {
+	fmt.Fprint(e.out, "\r\x1b[K"+prompt+string(line))
+}
+
+// skipEscape discards the rest of an escape sequence, such as an arrow key.
+func (e *Editor) skipEscape() {
+	b, err := e.in.ReadByte()
+	if err != nil || (b != '[' && b != 'O') {
+		return
+	}
+	for {
+		b, err := e.in.ReadByte()
+		if err != nil || (b >= 0x40 && b <= 0x7e) {
+			return
+		}
+	}
+}
+
+func commonPrefix(ss []string) string {
+	prefix := ss[0]
+	for _, s := range ss[1:] {
+		for !strings.HasPrefix(s, prefix) {
+			_, size := utf8.DecodeLastRuneInString(prefix)
+			prefix = prefix[:len(prefix)-size]
+		}
+	}
+	return prefix
+}
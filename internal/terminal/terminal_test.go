@@ -0,0 +1,91 @@
+package terminal
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func editor(input string) (*Editor, *strings.Builder) {
+	var out strings.Builder
+	return newEditor(strings.NewReader(input), &out), &out
+}
+
+func TestReadLine(t *testing.T) {
+	t.Run("edits the line", func(t *testing.T) {
+		e, out := editor("helo\x7flo\x1b[Dwörld\r")
+		line, err := e.ReadLine("> ")
+		require.NoError(t, err)
+		assert.Equal(t, "hellowörld", line)
+		assert.True(t, strings.HasPrefix(out.String(), "> helo\b \blo"))
+	})
+
+	t.Run("clears the line with Ctrl-U", func(t *testing.T) {
+		e, _ := editor("oops\x15fine\n")
+		line, err := e.ReadLine("> ")
+		require.NoError(t, err)
+		assert.Equal(t, "fine", line)
+	})
+
+	t.Run("Ctrl-C abandons the line and Ctrl-D on an empty one ends input", func(t *testing.T) {
+		e, _ := editor("half\x03\x04")
+		_, err := e.ReadLine("> ")
+		assert.ErrorIs(t, err, ErrInterrupted)
+		_, err = e.ReadLine("> ")
+		assert.ErrorIs(t, err, io.EOF)
+	})
+
+	t.Run("returns an unterminated last line", func(t *testing.T) {
+		e, _ := editor("last")
+		line, err := e.ReadLine("> ")
+		require.NoError(t, err)
+		assert.Equal(t, "last", line)
+	})
+}
+
+func TestComplete(t *testing.T) {
+	words := []string{"/model ", "/help", "/history"}
+	complete := func(line string) []string {
+		var out []string
+		for _, w := range words {
+			if strings.HasPrefix(w, line) {
+				out = append(out, w)
+			}
+		}
+		return out
+	}
+
+	t.Run("completes a single candidate", func(t *testing.T) {
+		e, _ := editor("/m\topus\r")
+		e.Complete = complete
+		line, err := e.ReadLine("> ")
+		require.NoError(t, err)
+		assert.Equal(t, "/model opus", line)
+	})
+
+	t.Run("extends to the common prefix, then lists", func(t *testing.T) {
+		e, out := editor("/\t\t\r")
+		e.Complete = complete
+		line, err := e.ReadLine("> ")
+		require.NoError(t, err)
+		assert.Equal(t, "/", line)
+
+		e, out = editor("/h\t\t\r")
+		e.Complete = complete
+		line, err = e.ReadLine("> ")
+		require.NoError(t, err)
+		assert.Equal(t, "/h", line)
+		assert.Contains(t, out.String(), "\n/help  /history\n> /h")
+	})
+
+	t.Run("rings the bell without candidates", func(t *testing.T) {
+		e, out := editor("x\t\r")
+		e.Complete = complete
+		_, err := e.ReadLine("> ")
+		require.NoError(t, err)
+		assert.Contains(t, out.String(), "\a")
+	})
+}
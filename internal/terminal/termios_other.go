@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+
+package terminal
+
+import "errors"
+
+func makeRaw(uintptr) (func(), error) {
+	return nil, errors.New("line editing is not supported on this platform")
+}
+
+func isTerminal(uintptr) bool {
+	return false
+}
@@ -0,0 +1,38 @@
+//go:build linux || darwin
+
+package terminal
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// makeRaw turns off line buffering, echo and signals on the terminal fd,
+// leaving output processing alone, and returns how to restore it.
+func makeRaw(fd uintptr) (func(), error) {
+	var old syscall.Termios
+	if err := ioctl(fd, getTermios, &old); err != nil {
+		return nil, err
+	}
+	raw := old
+	raw.Lflag &^= syscall.ICANON | syscall.ECHO | syscall.ISIG | syscall.IEXTEN
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if err := ioctl(fd, setTermios, &raw); err != nil {
+		return nil, err
+	}
+	return func() { _ = ioctl(fd, setTermios, &old) }, nil
+}
+
+func isTerminal(fd uintptr) bool {
+	var t syscall.Termios
+	return ioctl(fd, getTermios, &t) == nil
+}
+
+func ioctl(fd uintptr, req uint, t *syscall.Termios) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, uintptr(req), uintptr(unsafe.Pointer(t))) // #nosec G103 -- termios ioctl
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package workflow
+
+import (
+	"errors"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/gauthierbraillon/ensemble/internal/agent"
+	"github.com/gauthierbraillon/ensemble/internal/state"
+)
+
+// Session follows one Claude Code session through the TDD loop, across the
+// separate hook processes that see its writes and test runs.
+//
+// A session starts in RED. Writing implementation moves it to GREEN, and a
+// passing test run in GREEN moves it to REFACTOR. Writing a test in GREEN or
+// REFACTOR starts the next loop in RED.
+type Session struct {
+	ID    string `json:"session_id"`
+	Phase Phase  `json:"phase"`
+	// Tested is set once the session's tests have run; Green records whether
+	// that last run passed, and Failing the package that failed it.
+	Tested  bool      `json:"tested"`
+	Green   bool      `json:"green"`
+	Failing string    `json:"failing,omitempty"`
+	Updated time.Time `json:"updated"`
+}
+
+// validID keeps session ids from naming files outside the state directory.
+var validID = regexp.MustCompile(`^[A-Za-z0-9_-]{1,128}$`)
+
+func sessionFile(id string) string {
+	return "tdd/" + id + ".json"
+}
+
+// LoadSession returns the session's recorded state under root, or a fresh
+// session in RED with a zero Updated. ok is false when id cannot key a
+// session, as when the payload carries none.
+func LoadSession(root, id string) (s Session, ok bool, err error) {
+	if !validID.MatchString(id) {
+		return Session{}, false, nil
+	}
+	s = Session{ID: id, Phase: Red}
+	if err := state.Load(root, sessionFile(id), &s); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return Session{}, true, err
+	}
+	return s, true, nil
+}
+
+// Save records the session under root.
+func (s Session) Save(root string) error {
+	s.Updated = time.Now().UTC()
+	return state.Save(root, sessionFile(s.ID), s)
+}
+
+// Wrote moves the session on after a write to path has landed.
+func (s *Session) Wrote(path string, p agent.Policy) {
+	switch {
+	case p.IsTest(path) && s.Phase != Red:
+		s.Phase = Red
+	case p.IsImpl(path) && s.Phase == Red:
+		s.Phase = Green
+	}
+}
+
+// Ran moves the session on after its tests ran; failing is the package
+// directory that failed, if any.
+func (s *Session) Ran(passed bool, failing string) {
+	s.Tested, s.Green, s.Failing = true, passed, failing
+	if passed && s.Phase == Green {
+		s.Phase = Refactor
+	}
+}
+
+// Check enforces the phase on a proposed write: during REFACTOR,
+// implementation may only change while the tests are green, so a step that
+// breaks them is undone rather than built upon.
+func (s Session) Check(path string, p agent.Policy) agent.Finding {
+	if s.Phase == Refactor && s.Tested && !s.Green && p.IsImpl(path) {
+		return agent.Finding{
+			Agent:    "testing-quality",
+			Verdict:  agent.Block,
+			Severity: agent.High,
+			Category: "red-refactor",
+			Finding:  "implementation changed during REFACTOR while the tests are red",
+			File:     path,
+			Fix:      "undo the refactoring step that broke the tests (git checkout -- <file>), or write a new failing test to start the next loop",
+		}
+	}
+	return agent.Finding{Agent: "testing-quality", Verdict: agent.Pass, Severity: agent.Low, Finding: "write fits the " + string(s.Phase) + " phase"}
+}
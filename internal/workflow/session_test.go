@@ -0,0 +1,112 @@
+package workflow_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gauthierbraillon/ensemble/internal/agent"
+	"github.com/gauthierbraillon/ensemble/internal/workflow"
+)
+
+func TestSession(t *testing.T) {
+	p := agent.DefaultPolicy()
+
+	t.Run("walks the loop", func(t *testing.T) {
+		s := workflow.Session{Phase: workflow.Red}
+		s.Wrote("foo_test.go", p)
+		assert.Equal(t, workflow.Red, s.Phase, "a failing test keeps RED until code is written")
+		s.Wrote("foo.go", p)
+		assert.Equal(t, workflow.Green, s.Phase)
+		s.Ran(false, "")
+		assert.Equal(t, workflow.Green, s.Phase, "red tests keep GREEN")
+		s.Ran(true, "")
+		assert.Equal(t, workflow.Refactor, s.Phase)
+		s.Wrote("foo.go", p)
+		assert.Equal(t, workflow.Refactor, s.Phase)
+		s.Wrote("foo_test.go", p)
+		assert.Equal(t, workflow.Red, s.Phase, "a new test starts the next loop")
+	})
+
+	t.Run("other files leave the phase alone", func(t *testing.T) {
+		s := workflow.Session{Phase: workflow.Green}
+		s.Wrote("README.md", p)
+		assert.Equal(t, workflow.Green, s.Phase)
+	})
+
+	t.Run("records the failing package", func(t *testing.T) {
+		s := workflow.Session{Phase: workflow.Refactor}
+		s.Ran(false, "foo")
+		assert.True(t, s.Tested)
+		assert.False(t, s.Green)
+		assert.Equal(t, "foo", s.Failing)
+		s.Ran(true, "")
+		assert.Empty(t, s.Failing)
+	})
+}
+
+func TestSessionCheck(t *testing.T) {
+	p := agent.DefaultPolicy()
+	red := workflow.Session{Phase: workflow.Refactor}
+	red.Ran(false, "foo")
+
+	t.Run("no implementation while refactoring on red tests", func(t *testing.T) {
+		f := red.Check("foo.go", p)
+		assert.Equal(t, agent.Block, f.Verdict)
+		assert.Equal(t, "red-refactor", f.Category)
+		assert.Equal(t, "foo.go", f.File)
+	})
+
+	t.Run("a new test is always allowed", func(t *testing.T) {
+		assert.Equal(t, agent.Pass, red.Check("foo_test.go", p).Verdict)
+	})
+
+	t.Run("refactoring on green tests is allowed", func(t *testing.T) {
+		green := workflow.Session{Phase: workflow.Refactor}
+		green.Ran(true, "")
+		assert.Equal(t, agent.Pass, green.Check("foo.go", p).Verdict)
+	})
+
+	t.Run("red tests in GREEN are the point", func(t *testing.T) {
+		s := workflow.Session{Phase: workflow.Green}
+		s.Ran(false, "foo")
+		assert.Equal(t, agent.Pass, s.Check("foo.go", p).Verdict)
+	})
+}
+
+func TestLoadSession(t *testing.T) {
+	t.Run("a new session starts in RED", func(t *testing.T) {
+		s, ok, err := workflow.LoadSession(t.TempDir(), "abc-123")
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, workflow.Red, s.Phase)
+		assert.True(t, s.Updated.IsZero())
+	})
+
+	t.Run("round-trips the session", func(t *testing.T) {
+		root := t.TempDir()
+		saved := workflow.Session{ID: "abc-123", Phase: workflow.Refactor}
+		saved.Ran(false, "foo")
+		require.NoError(t, saved.Save(root))
+
+		got, ok, err := workflow.LoadSession(root, "abc-123")
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, workflow.Refactor, got.Phase)
+		assert.Equal(t, "foo", got.Failing)
+		assert.False(t, got.Updated.IsZero())
+
+		other, _, err := workflow.LoadSession(root, "def-456")
+		require.NoError(t, err)
+		assert.Equal(t, workflow.Red, other.Phase, "sessions are kept apart")
+	})
+
+	for _, id := range []string{"", "../escape", "a/b"} {
+		t.Run("no session for id "+id, func(t *testing.T) {
+			_, ok, err := workflow.LoadSession(t.TempDir(), id)
+			require.NoError(t, err)
+			assert.False(t, ok)
+		})
+	}
+}
@@ -0,0 +1,85 @@
+// Package workflow tracks where a change stands in the RED → GREEN →
+// REFACTOR loop, so the model can be told what the next step is.
+package workflow
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gauthierbraillon/ensemble/internal/agent"
+	"github.com/gauthierbraillon/ensemble/internal/diff"
+	"github.com/gauthierbraillon/ensemble/internal/pipeline"
+	"github.com/gauthierbraillon/ensemble/internal/state"
+)
+
+// Phase is the TDD step to take next.
+type Phase string
+
+const (
+	Red      Phase = "RED"
+	Green    Phase = "GREEN"
+	Refactor Phase = "REFACTOR"
+)
+
+// Infer reads the phase off the uncommitted change. With nothing changed, or
+// implementation changed without a test, the next step is a failing test;
+// with only tests changed it is the code that makes them pass; once both
+// have changed it is tidying up under green tests before committing.
+func Infer(p diff.Patch, policy agent.Policy) Phase {
+	tests, impl := false, false
+	for _, f := range p.Files {
+		if f.Status == diff.Deleted || len(f.Hunks) == 0 {
+			continue
+		}
+		switch {
+		case policy.IsTest(f.Path()):
+			tests = true
+		case policy.IsImpl(f.Path()):
+			impl = true
+		}
+	}
+	switch {
+	case tests && impl:
+		return Refactor
+	case tests:
+		return Green
+	}
+	return Red
+}
+
+// Guidance is the instruction that goes with a phase.
+func (p Phase) Guidance() string {
+	switch p {
+	case Green:
+		return "a new test is in place; write the least code that makes it pass, nothing more"
+	case Refactor:
+		return "tests and code have changed; with the tests green, remove duplication and tidy names, then commit"
+	}
+	return "write a failing test for the next behaviour before any implementation"
+}
+
+// Agreement is the working agreement restated to the model at each prompt:
+// the phase, what the last cycle left blocking, and the quality gates every
+// commit must pass. last is nil when no cycle has run.
+func Agreement(phase Phase, last *state.Cycle, gates []pipeline.Gate) string {
+	var b strings.Builder
+	b.WriteString("ensemble working agreement\n")
+	fmt.Fprintf(&b, "TDD phase: %s. Next: %s.\n", phase, phase.Guidance())
+	if last != nil && len(last.Blocking) > 0 {
+		fmt.Fprintf(&b, "Blocking findings from the last cycle (%s):\n", last.Time.Local().Format("2006-01-02 15:04"))
+		for _, f := range last.Blocking {
+			line := "ensemble " + f.Agent + ": " + f.Finding
+			if f.File != "" && !strings.Contains(line, f.File) {
+				line += " (" + f.File + ")"
+			}
+			b.WriteString("- " + line + "\n")
+		}
+	}
+	if len(gates) > 0 {
+		b.WriteString("Quality gates every commit must pass, in order:\n")
+		for i, g := range gates {
+			fmt.Fprintf(&b, "%d. %s (make %s): %s\n", i+1, g.Name, g.MakeTarget, g.Description)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,79 @@
+package workflow_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gauthierbraillon/ensemble/internal/agent"
+	"github.com/gauthierbraillon/ensemble/internal/diff"
+	"github.com/gauthierbraillon/ensemble/internal/pipeline"
+	"github.com/gauthierbraillon/ensemble/internal/state"
+	"github.com/gauthierbraillon/ensemble/internal/workflow"
+)
+
+func adding(t *testing.T, paths ...string) diff.Patch {
+	t.Helper()
+	raw := ""
+	for _, p := range paths {
+		raw += "--- /dev/null\n+++ b/" + p + "\n@@ -0,0 +1 @@\n+x\n"
+	}
+	patch, err := diff.Parse(raw)
+	require.NoError(t, err)
+	return patch
+}
+
+func TestInfer(t *testing.T) {
+	p := agent.DefaultPolicy()
+	cases := []struct {
+		name  string
+		paths []string
+		want  workflow.Phase
+	}{
+		{"nothing changed", nil, workflow.Red},
+		{"only docs changed", []string{"README.md"}, workflow.Red},
+		{"implementation without a test", []string{"foo.go"}, workflow.Red},
+		{"a test and no implementation", []string{"foo_test.go"}, workflow.Green},
+		{"test and implementation", []string{"foo_test.go", "foo.go"}, workflow.Refactor},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.want, workflow.Infer(adding(t, c.paths...), p))
+		})
+	}
+}
+
+func TestGuidance(t *testing.T) {
+	for _, phase := range []workflow.Phase{workflow.Red, workflow.Green, workflow.Refactor} {
+		assert.NotEmpty(t, phase.Guidance(), phase)
+	}
+	assert.Contains(t, workflow.Red.Guidance(), "failing test")
+}
+
+func TestAgreement(t *testing.T) {
+	gates := []pipeline.Gate{{Name: "Lint", MakeTarget: "lint", Description: "Formatting"}, {Name: "Build", MakeTarget: "build", Description: "Compilation"}}
+
+	t.Run("phase, blocking findings and gates", func(t *testing.T) {
+		last := &state.Cycle{
+			Time:     time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC),
+			Blocking: []agent.Finding{{Agent: "testing-quality", Finding: "implementation without test", File: "foo.go"}},
+		}
+		text := workflow.Agreement(workflow.Green, last, gates)
+		assert.Contains(t, text, "TDD phase: GREEN")
+		assert.Contains(t, text, "- ensemble testing-quality: implementation without test (foo.go)")
+		assert.Contains(t, text, "1. Lint (make lint): Formatting\n2. Build (make build): Compilation")
+	})
+
+	t.Run("a clean last cycle adds nothing", func(t *testing.T) {
+		text := workflow.Agreement(workflow.Red, &state.Cycle{}, gates)
+		assert.NotContains(t, text, "last cycle")
+	})
+
+	t.Run("no cycle yet", func(t *testing.T) {
+		text := workflow.Agreement(workflow.Red, nil, nil)
+		assert.Contains(t, text, "TDD phase: RED")
+		assert.NotContains(t, text, "Quality gates")
+	})
+}
@@ -0,0 +1,79 @@
+package acceptance
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func projectWithConfig(t *testing.T, config string) string {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, ".git"), 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".ensemble.yaml"), []byte(config), 0600))
+	return dir
+}
+
+func TestProjectConfig(t *testing.T) {
+	t.Run("disabled agents are left out of the cycle", func(t *testing.T) {
+		dir := projectWithConfig(t, "agents:\n  ux-design:\n    enabled: false\n  security:\n    enabled: false\n")
+		cmd := exec.Command(ensembleBinAbs(t), "cycle")
+		cmd.Dir = dir
+		cmd.Stdin = strings.NewReader(diffWithExportedFunc())
+		cmd.Env = envWithout(os.Environ(), "ANTHROPIC_API_KEY")
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, "expected exit 0: %s", out)
+
+		for _, f := range parseFindings(t, out) {
+			assert.NotEqual(t, "ux-design", f["agent"])
+			assert.NotEqual(t, "security", f["agent"])
+		}
+	})
+
+	t.Run("excluded paths need no tests", func(t *testing.T) {
+		dir := projectWithConfig(t, "paths:\n  exclude: [\"internal/bar/**\"]\n")
+		cmd := exec.Command(ensembleBinAbs(t), "cycle")
+		cmd.Dir = dir
+		cmd.Stdin = strings.NewReader(diffWithoutTest())
+		cmd.Env = envWithout(os.Environ(), "ANTHROPIC_API_KEY")
+		out, err := cmd.CombinedOutput()
+		assert.NoError(t, err, "unexpected block: %s", out)
+	})
+
+	t.Run("severity threshold decides what fails the cycle", func(t *testing.T) {
+		dir := projectWithConfig(t, "block:\n  verdict: warn\n")
+		cmd := exec.Command(ensembleBinAbs(t), "cycle")
+		cmd.Dir = dir
+		cmd.Stdin = strings.NewReader(diffWithTest())
+		cmd.Env = envWithout(os.Environ(), "ANTHROPIC_API_KEY")
+		out, _ := cmd.CombinedOutput()
+		assert.Equal(t, 1, cmd.ProcessState.ExitCode(), "offline warnings should fail at a warn threshold: %s", out)
+	})
+
+	t.Run("hook honours the configured test suffix", func(t *testing.T) {
+		dir := projectWithConfig(t, "tests:\n  suffix: _spec.go\n")
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "foo_spec.go"), []byte("package foo"), 0600))
+		cmd := exec.Command(ensembleBinAbs(t), "hook")
+		cmd.Dir = dir
+		cmd.Stdin = strings.NewReader(hookEvent("Write", filepath.Join(dir, "foo.go")))
+		out, err := cmd.CombinedOutput()
+		assert.NoError(t, err, "unexpected block: %s", out)
+		assert.Empty(t, out, "unexpected decision")
+	})
+
+	t.Run("malformed config fails with the file name and reason", func(t *testing.T) {
+		dir := projectWithConfig(t, "runner:\n  timout: 10s\n")
+		cmd := exec.Command(ensembleBinAbs(t), "cycle")
+		cmd.Dir = dir
+		cmd.Stdin = strings.NewReader(diffWithTest())
+		out, err := cmd.CombinedOutput()
+		require.Error(t, err)
+		assert.Contains(t, string(out), ".ensemble.yaml")
+		assert.Contains(t, string(out), "timout")
+	})
+}
@@ -0,0 +1,92 @@
+package acceptance
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func stubMessagesAPI(t *testing.T, reply string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		_, _ = w.Write([]byte(`{"content":[{"type":"text","text":` + reply + `}]}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func apiCycle(t *testing.T, baseURL string, env ...string) *exec.Cmd {
+	t.Helper()
+	cmd := exec.Command(ensembleBin(t), "cycle")
+	cmd.Stdin = strings.NewReader(diffWithTest())
+	cmd.Env = append(envWithout(os.Environ(), "ANTHROPIC_API_KEY"),
+		"ANTHROPIC_API_KEY=test-key",
+		"ENSEMBLE_RUNNER=api",
+		"ANTHROPIC_BASE_URL="+baseURL,
+	)
+	cmd.Env = append(cmd.Env, env...)
+	return cmd
+}
+
+func TestCycleAPIRunner(t *testing.T) {
+	t.Run("reviews through the Messages API without the claude binary", func(t *testing.T) {
+		srv := stubMessagesAPI(t, `"[{\"verdict\":\"block\",\"severity\":\"high\",\"finding\":\"stub objection\",\"file\":\"internal/foo/foo.go:2\",\"fix\":\"\"}]"`)
+		cmd := apiCycle(t, srv.URL)
+		cmd.Env = append(envWithout(cmd.Env, "PATH"), "PATH=/nonexistent")
+		out, _ := cmd.CombinedOutput()
+
+		assert.Equal(t, 1, cmd.ProcessState.ExitCode(), "expected exit 1: %s", out)
+		var objections []string
+		for _, f := range parseFindings(t, out) {
+			if f["finding"] == "stub objection" {
+				objections = append(objections, f["agent"].(string))
+			}
+		}
+		require.NotEmpty(t, objections)
+		assert.Contains(t, objections, "software-engineering")
+	})
+	t.Run("records the tier-selected model on each agent's findings", func(t *testing.T) {
+		srv := stubMessagesAPI(t, `"[]"`)
+		cmd := apiCycle(t, srv.URL, "ENSEMBLE_TIER=haiku", "ENSEMBLE_TIER_SECURITY=opus")
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, "expected exit 0: %s", out)
+
+		models := map[string]interface{}{}
+		for _, f := range parseFindings(t, out) {
+			models[f["agent"].(string)] = f["model"]
+		}
+		assert.Equal(t, "claude-opus-4-6", models["security"])
+		assert.Equal(t, "claude-haiku-4-5-20251001", models["software-engineering"])
+		assert.Equal(t, "claude-haiku-4-5-20251001", models["ux-design"])
+		assert.Nil(t, models["testing-quality"], "deterministic agent uses no model")
+	})
+
+	t.Run("rejects an unknown tier", func(t *testing.T) {
+		srv := stubMessagesAPI(t, `"[]"`)
+		cmd := apiCycle(t, srv.URL, "ENSEMBLE_TIER=turbo")
+		out, err := cmd.CombinedOutput()
+		require.Error(t, err)
+		assert.Contains(t, string(out), "invalid tier")
+	})
+
+	t.Run("fails on a runner set up wrong rather than skipping the reviews", func(t *testing.T) {
+		srv := stubMessagesAPI(t, `"[]"`)
+		for env, want := range map[string]string{
+			"ENSEMBLE_RUNNER=ap":           `ENSEMBLE_RUNNER: must be "cli" or "api", got "ap"`,
+			"ANTHROPIC_BASE_URL=not a url": "invalid base URL",
+		} {
+			cmd := apiCycle(t, srv.URL, env)
+			out, err := cmd.CombinedOutput()
+			require.Error(t, err, env)
+			assert.Contains(t, string(out), want, env)
+			assert.NotContains(t, string(out), "skipped", env)
+		}
+	})
+}
@@ -0,0 +1,180 @@
+package acceptance
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gitIn(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "commit.gpgsign=false"}, args...)...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, "git %v: %s", args, out)
+}
+
+func writeRepoFile(t *testing.T, dir, path, content string) {
+	t.Helper()
+	full := filepath.Join(dir, path)
+	require.NoError(t, os.MkdirAll(filepath.Dir(full), 0750))
+	require.NoError(t, os.WriteFile(full, []byte(content), 0600))
+}
+
+// gitProject is a repository on main whose first commit holds a tested package.
+func gitProject(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	gitIn(t, dir, "init", "-q", "-b", "main")
+	writeRepoFile(t, dir, "go.mod", "module example.com/p\n")
+	writeRepoFile(t, dir, "foo/foo.go", "package foo\n\nfunc Add(a, b int) int { return a + b }\n")
+	writeRepoFile(t, dir, "foo/foo_test.go", "package foo\n")
+	gitIn(t, dir, "add", "-A")
+	gitIn(t, dir, "commit", "-q", "-m", "initial")
+	return dir
+}
+
+func gitCycle(t *testing.T, dir string, args ...string) *exec.Cmd {
+	t.Helper()
+	cmd := exec.Command(ensembleBinAbs(t), append([]string{"cycle"}, args...)...)
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader("")
+	cmd.Env = envWithout(os.Environ(), "ANTHROPIC_API_KEY")
+	return cmd
+}
+
+func blockedFiles(t *testing.T, out []byte) []string {
+	t.Helper()
+	var files []string
+	for _, f := range parseFindings(t, out) {
+		if f["verdict"] == "block" {
+			files = append(files, f["file"].(string))
+		}
+	}
+	return files
+}
+
+func TestCycleFromGit(t *testing.T) {
+	t.Run("--worktree reviews untracked files", func(t *testing.T) {
+		dir := gitProject(t)
+		writeRepoFile(t, dir, "bar/bar.go", "package bar\n")
+		cmd := gitCycle(t, dir, "--worktree")
+		out, _ := cmd.CombinedOutput()
+		assert.Equal(t, 1, cmd.ProcessState.ExitCode(), "expected exit 1: %s", out)
+		assert.Equal(t, []string{"bar/bar.go"}, blockedFiles(t, out))
+	})
+
+	t.Run("--staged ignores unstaged changes", func(t *testing.T) {
+		dir := gitProject(t)
+		writeRepoFile(t, dir, "bar/bar.go", "package bar\n")
+		writeRepoFile(t, dir, "bar/bar_test.go", "package bar\n")
+		gitIn(t, dir, "add", "bar")
+		writeRepoFile(t, dir, "baz/baz.go", "package baz\n")
+		out, err := gitCycle(t, dir, "--staged").CombinedOutput()
+		assert.NoError(t, err, "unexpected block: %s", out)
+	})
+
+	t.Run("reviews a revision range", func(t *testing.T) {
+		dir := gitProject(t)
+		writeRepoFile(t, dir, "bar/bar.go", "package bar\n")
+		gitIn(t, dir, "add", "-A")
+		gitIn(t, dir, "commit", "-q", "-m", "untested")
+		out, _ := gitCycle(t, dir, "HEAD~1..HEAD").CombinedOutput()
+		assert.Equal(t, []string{"bar/bar.go"}, blockedFiles(t, out))
+
+		out, err := gitCycle(t, dir, "HEAD~1..HEAD~1").CombinedOutput()
+		assert.NoError(t, err, "an empty range has nothing to block: %s", out)
+	})
+
+	t.Run("--base reviews only the branch's own commits", func(t *testing.T) {
+		dir := gitProject(t)
+		gitIn(t, dir, "checkout", "-q", "-b", "feature")
+		writeRepoFile(t, dir, "feature/feature.go", "package feature\n")
+		gitIn(t, dir, "add", "-A")
+		gitIn(t, dir, "commit", "-q", "-m", "feature")
+		gitIn(t, dir, "checkout", "-q", "main")
+		writeRepoFile(t, dir, "onmain/onmain.go", "package onmain\n")
+		gitIn(t, dir, "add", "-A")
+		gitIn(t, dir, "commit", "-q", "-m", "main")
+		gitIn(t, dir, "checkout", "-q", "feature")
+
+		out, _ := gitCycle(t, dir, "--base", "main").CombinedOutput()
+		assert.Equal(t, []string{"feature/feature.go"}, blockedFiles(t, out))
+	})
+
+	t.Run("modifying a file with an existing test only warns", func(t *testing.T) {
+		dir := gitProject(t)
+		writeRepoFile(t, dir, "foo/foo.go", "package foo\n\nfunc Add(a, b int) int { return b + a }\n")
+		out, err := gitCycle(t, dir, "--worktree").CombinedOutput()
+		require.NoError(t, err, "expected exit 0: %s", out)
+		warned := false
+		for _, f := range parseFindings(t, out) {
+			if f["agent"] == "testing-quality" && f["verdict"] == "warn" && f["severity"] == "medium" {
+				warned = true
+			}
+		}
+		assert.True(t, warned, "expected an untouched-test warning: %s", out)
+	})
+
+	t.Run("deleting an implementation file needs no test", func(t *testing.T) {
+		dir := gitProject(t)
+		gitIn(t, dir, "rm", "-q", "foo/foo.go")
+		out, err := gitCycle(t, dir, "--staged").CombinedOutput()
+		assert.NoError(t, err, "unexpected block: %s", out)
+	})
+
+	t.Run("rejects more than one source", func(t *testing.T) {
+		dir := gitProject(t)
+		out, err := gitCycle(t, dir, "--staged", "HEAD~1..HEAD").CombinedOutput()
+		require.Error(t, err)
+		assert.Contains(t, string(out), "choose one")
+	})
+
+	t.Run("reports git errors", func(t *testing.T) {
+		dir := gitProject(t)
+		out, err := gitCycle(t, dir, "--base", "no-such-branch").CombinedOutput()
+		require.Error(t, err)
+		assert.Contains(t, string(out), "git:")
+	})
+
+	t.Run("model agents see the full file beyond the hunk", func(t *testing.T) {
+		dir := gitProject(t)
+		far := "func FarFromTheHunk() {}\n"
+		writeRepoFile(t, dir, "foo/foo.go", "package foo\n\n"+far+strings.Repeat("\n", 20)+"func Add(a, b int) int { return a + b }\n")
+		gitIn(t, dir, "commit", "-qam", "far")
+		writeRepoFile(t, dir, "foo/foo.go", "package foo\n\n"+far+strings.Repeat("\n", 20)+"func Add(a, b int) int { return b + a }\n")
+
+		var mu sync.Mutex
+		var prompts []string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			mu.Lock()
+			prompts = append(prompts, string(body))
+			mu.Unlock()
+			w.Header().Set("content-type", "application/json")
+			_, _ = w.Write([]byte(`{"content":[{"type":"text","text":"[]"}]}`))
+		}))
+		t.Cleanup(srv.Close)
+
+		cmd := gitCycle(t, dir, "--worktree")
+		cmd.Env = append(cmd.Env, "ANTHROPIC_API_KEY=test-key", "ENSEMBLE_RUNNER=api", "ANTHROPIC_BASE_URL="+srv.URL)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, "expected exit 0: %s", out)
+
+		mu.Lock()
+		defer mu.Unlock()
+		require.NotEmpty(t, prompts, "no model calls: %s", out)
+		for _, p := range prompts {
+			assert.Contains(t, p, "FarFromTheHunk")
+		}
+	})
+}
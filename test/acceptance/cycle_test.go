@@ -155,4 +155,103 @@ func TestCycleCommand(t *testing.T) {
 		assert.True(t, agents["software-engineering"], "missing software-engineering agent")
 		assert.True(t, agents["security"], "missing security agent")
 	})
+	t.Run("prints findings in a stable agent order across runs", func(t *testing.T) {
+		agentOrder := func() []string {
+			cmd := exec.Command(ensembleBin(t), "cycle")
+			cmd.Stdin = strings.NewReader(diffWithTest())
+			cmd.Env = envWithout(os.Environ(), "ANTHROPIC_API_KEY")
+			out, err := cmd.CombinedOutput()
+			require.NoError(t, err, "expected exit 0: %s", out)
+			var order []string
+			for _, f := range parseFindings(t, out) {
+				order = append(order, f["agent"].(string))
+			}
+			return order
+		}
+		first := agentOrder()
+		assert.Equal(t, []string{"testing-quality", "software-engineering", "security", "ux-design"}, first)
+		assert.Equal(t, first, agentOrder())
+	})
+
+	t.Run("reads --no-prefix diffs", func(t *testing.T) {
+		cmd := exec.Command(ensembleBin(t), "cycle")
+		cmd.Stdin = strings.NewReader(strings.ReplaceAll(strings.ReplaceAll(diffWithoutTest(), " a/", " "), " b/", " "))
+		cmd.Env = envWithout(os.Environ(), "ANTHROPIC_API_KEY")
+		out, _ := cmd.CombinedOutput()
+		assert.Equal(t, 1, cmd.ProcessState.ExitCode(), "expected exit 1: %s", out)
+		assert.Equal(t, "internal/bar/bar.go", parseFindings(t, out)[0]["file"])
+	})
+
+	t.Run("a pure rename needs no new test", func(t *testing.T) {
+		cmd := exec.Command(ensembleBin(t), "cycle")
+		cmd.Stdin = strings.NewReader("diff --git a/old.go b/new.go\nsimilarity index 100%\nrename from old.go\nrename to new.go\n")
+		cmd.Env = envWithout(os.Environ(), "ANTHROPIC_API_KEY")
+		out, err := cmd.CombinedOutput()
+		assert.NoError(t, err, "unexpected block: %s", out)
+	})
+
+	t.Run("rejects a malformed hunk header", func(t *testing.T) {
+		cmd := exec.Command(ensembleBin(t), "cycle")
+		cmd.Stdin = strings.NewReader("--- a/x.go\n+++ b/x.go\n@@ broken @@\n")
+		out, err := cmd.CombinedOutput()
+		require.Error(t, err)
+		assert.Contains(t, string(out), "malformed hunk header")
+	})
+
+	t.Run("rejects a concurrency limit below one", func(t *testing.T) {
+		cmd := exec.Command(ensembleBin(t), "cycle", "--concurrency", "0")
+		cmd.Stdin = strings.NewReader(diffWithTest())
+		out, err := cmd.CombinedOutput()
+		require.Error(t, err)
+		assert.Contains(t, string(out), "--concurrency")
+	})
+}
+
+func TestCycleSARIF(t *testing.T) {
+	t.Run("emits a SARIF 2.1.0 log with one run per agent", func(t *testing.T) {
+		cmd := exec.Command(ensembleBin(t), "cycle", "--format", "sarif")
+		cmd.Stdin = strings.NewReader(diffWithoutTest())
+		cmd.Env = envWithout(os.Environ(), "ANTHROPIC_API_KEY")
+		out, _ := cmd.Output()
+
+		assert.Equal(t, 1, cmd.ProcessState.ExitCode(), "blocking findings still fail the cycle")
+		var log struct {
+			Version string `json:"version"`
+			Runs    []struct {
+				Tool struct {
+					Driver struct {
+						Name string `json:"name"`
+					} `json:"driver"`
+				} `json:"tool"`
+				Results []struct {
+					RuleID    string `json:"ruleId"`
+					Level     string `json:"level"`
+					Locations []struct {
+						PhysicalLocation struct {
+							ArtifactLocation struct {
+								URI string `json:"uri"`
+							} `json:"artifactLocation"`
+						} `json:"physicalLocation"`
+					} `json:"locations"`
+				} `json:"results"`
+			} `json:"runs"`
+		}
+		require.NoError(t, json.Unmarshal(out, &log), "not a SARIF document: %s", out)
+		assert.Equal(t, "2.1.0", log.Version)
+		require.NotEmpty(t, log.Runs)
+		tdd := log.Runs[0]
+		assert.Equal(t, "ensemble/testing-quality", tdd.Tool.Driver.Name)
+		require.Len(t, tdd.Results, 1)
+		assert.Equal(t, "error", tdd.Results[0].Level)
+		assert.Equal(t, "testing-quality/missing-test", tdd.Results[0].RuleID)
+		assert.Equal(t, "internal/bar/bar.go", tdd.Results[0].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	})
+
+	t.Run("rejects unknown formats", func(t *testing.T) {
+		cmd := exec.Command(ensembleBin(t), "cycle", "--format", "xml")
+		cmd.Stdin = strings.NewReader(diffWithTest())
+		out, err := cmd.CombinedOutput()
+		require.Error(t, err)
+		assert.Contains(t, string(out), "--format")
+	})
 }
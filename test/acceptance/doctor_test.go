@@ -0,0 +1,65 @@
+package acceptance
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoctor(t *testing.T) {
+	doctor := func(t *testing.T, dir string, args ...string) ([]byte, int) {
+		t.Helper()
+		cmd := exec.Command(ensembleBinAbs(t), append([]string{"doctor"}, args...)...)
+		cmd.Dir = dir
+		cmd.Env = append(envWithout(os.Environ(), "HOME"), "HOME="+t.TempDir())
+		out, err := cmd.Output()
+		if _, ok := err.(*exec.ExitError); !ok {
+			require.NoError(t, err)
+		}
+		return out, cmd.ProcessState.ExitCode()
+	}
+
+	t.Run("prints a table and fails without hooks", func(t *testing.T) {
+		out, code := doctor(t, t.TempDir())
+		assert.Equal(t, 1, code)
+		assert.Contains(t, string(out), "CHECK")
+		assert.Contains(t, string(out), "run ensemble init")
+	})
+
+	t.Run("prints one JSON object per check", func(t *testing.T) {
+		dir := t.TempDir()
+		setup := exec.Command(ensembleBinAbs(t), "init")
+		setup.Dir = dir
+		setup.Env = append(envWithout(os.Environ(), "HOME"), "HOME="+t.TempDir())
+		require.NoError(t, setup.Run())
+
+		out, _ := doctor(t, dir, "--format", "json")
+		statuses := map[string]string{}
+		scanner := bufio.NewScanner(bytes.NewReader(out))
+		for scanner.Scan() {
+			var c struct {
+				Check  string `json:"check"`
+				Status string `json:"status"`
+				Detail string `json:"detail"`
+			}
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &c), "not a check: %s", scanner.Text())
+			statuses[c.Check] = c.Status
+		}
+		assert.Equal(t, "pass", statuses["hooks (project)"])
+		assert.Equal(t, "pass", statuses["hooks"])
+		assert.Equal(t, "pass", statuses["config"])
+	})
+
+	t.Run("rejects an unknown format", func(t *testing.T) {
+		cmd := exec.Command(ensembleBinAbs(t), "doctor", "--format", "xml")
+		out, err := cmd.CombinedOutput()
+		assert.Error(t, err)
+		assert.Contains(t, string(out), "--format must be table or json")
+	})
+}
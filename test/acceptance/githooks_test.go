@@ -0,0 +1,125 @@
+package acceptance
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hookEnv runs git hooks against the freshly built ensemble, without a key so
+// the model agents stay out of the pre-push review.
+func hookEnv(t *testing.T) []string {
+	t.Helper()
+	env := envWithout(envWithout(os.Environ(), "ANTHROPIC_API_KEY"), "PATH")
+	return append(env, "PATH="+filepath.Dir(ensembleBinAbs(t))+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func initGitHooks(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command(ensembleBinAbs(t), append([]string{"init", "--git-hooks"}, args...)...)
+	cmd.Dir = dir
+	cmd.Env = hookEnv(t)
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, "init --git-hooks: %s", out)
+	return string(out)
+}
+
+func gitHooked(t *testing.T, dir string, args ...string) (string, error) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "commit.gpgsign=false"}, args...)...)
+	cmd.Dir = dir
+	cmd.Env = hookEnv(t)
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+func TestEnsembleInitGitHooks(t *testing.T) {
+	t.Run("pre-commit rejects untested implementation", func(t *testing.T) {
+		dir := gitProject(t)
+		out := initGitHooks(t, dir)
+		assert.Contains(t, out, "Installed pre-commit hook")
+
+		writeRepoFile(t, dir, "bar/bar.go", "package bar\n")
+		gitIn(t, dir, "add", "bar")
+		out, err := gitHooked(t, dir, "commit", "-q", "-m", "bar")
+		require.Error(t, err, "commit should be rejected: %s", out)
+		assert.Contains(t, out, "bar/bar.go")
+
+		writeRepoFile(t, dir, "bar/bar_test.go", "package bar\n")
+		gitIn(t, dir, "add", "bar")
+		out, err = gitHooked(t, dir, "commit", "-q", "-m", "bar")
+		assert.NoError(t, err, "tested commit should pass: %s", out)
+	})
+
+	t.Run("runs the hook that was there first and restores it on uninstall", func(t *testing.T) {
+		dir := gitProject(t)
+		mine := "#!/bin/sh\necho existing hook ran\n"
+		writeRepoFile(t, dir, ".git/hooks/pre-commit", mine)
+		require.NoError(t, os.Chmod(filepath.Join(dir, ".git/hooks/pre-commit"), 0700))
+		assert.Contains(t, initGitHooks(t, dir), "pre-commit.pre-ensemble")
+
+		writeRepoFile(t, dir, "foo/foo_test.go", "package foo\n\n// more\n")
+		gitIn(t, dir, "add", "foo")
+		out, err := gitHooked(t, dir, "commit", "-m", "tests")
+		require.NoError(t, err, "%s", out)
+		assert.Contains(t, out, "existing hook ran")
+
+		cmd := exec.Command(ensembleBinAbs(t), "uninstall", "--git-hooks")
+		cmd.Dir = dir
+		cmd.Env = append(hookEnv(t), "HOME="+t.TempDir())
+		removed, err := cmd.CombinedOutput()
+		require.NoError(t, err, "%s", removed)
+		data, err := os.ReadFile(filepath.Join(dir, ".git/hooks/pre-commit"))
+		require.NoError(t, err)
+		assert.Equal(t, mine, string(data))
+	})
+
+	t.Run("honours core.hooksPath", func(t *testing.T) {
+		dir := gitProject(t)
+		gitIn(t, dir, "config", "core.hooksPath", "githooks")
+		initGitHooks(t, dir)
+		_, err := os.Stat(filepath.Join(dir, "githooks", "pre-commit"))
+		assert.NoError(t, err)
+		_, err = os.Stat(filepath.Join(dir, ".git", "hooks", "pre-commit"))
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("--dry-run writes no hook", func(t *testing.T) {
+		dir := gitProject(t)
+		out := initGitHooks(t, dir, "--dry-run")
+		assert.Contains(t, out, "Would have installed pre-commit hook")
+		_, err := os.Stat(filepath.Join(dir, ".git", "hooks", "pre-commit"))
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("pre-push reviews the commits being pushed", func(t *testing.T) {
+		remote := t.TempDir()
+		gitIn(t, remote, "init", "-q", "--bare")
+		dir := gitProject(t)
+		gitIn(t, dir, "remote", "add", "origin", remote)
+		initGitHooks(t, dir)
+
+		// Committed past the pre-commit hook, as --no-verify would.
+		writeRepoFile(t, dir, "bar/bar.go", "package bar\n")
+		gitIn(t, dir, "add", "bar")
+		gitIn(t, dir, "commit", "-q", "--no-verify", "-m", "bar")
+		out, err := gitHooked(t, dir, "push", "-q", "origin", "main")
+		require.Error(t, err, "push should be rejected: %s", out)
+		assert.Contains(t, out, "bar/bar.go")
+
+		writeRepoFile(t, dir, "bar/bar_test.go", "package bar\n")
+		gitIn(t, dir, "add", "bar")
+		gitIn(t, dir, "commit", "-q", "--no-verify", "-m", "bar test")
+		gitIn(t, dir, "push", "-q", "--no-verify", "origin", "main")
+
+		writeRepoFile(t, dir, "foo/foo_test.go", "package foo\n\n// more\n")
+		gitIn(t, dir, "add", "foo")
+		gitIn(t, dir, "commit", "-q", "--no-verify", "-m", "foo test")
+		out, err = gitHooked(t, dir, "push", "-q", "origin", "main")
+		assert.NoError(t, err, "only the new commit is reviewed: %s", out)
+	})
+}
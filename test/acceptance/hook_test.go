@@ -1,6 +1,7 @@
 package acceptance
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -17,12 +18,32 @@ func hookEvent(toolName, filePath string) string {
 	return fmt.Sprintf(`{"tool_name":%q,"tool_input":{"file_path":%q}}`, toolName, filePath)
 }
 
+// hookDecision parses the structured PreToolUse decision a hook printed,
+// returning an empty decision when it printed nothing.
+func hookDecision(t *testing.T, out []byte) (decision, reason string) {
+	t.Helper()
+	if len(bytes.TrimSpace(out)) == 0 {
+		return "", ""
+	}
+	var o struct {
+		HookSpecificOutput struct {
+			HookEventName            string `json:"hookEventName"`
+			PermissionDecision       string `json:"permissionDecision"`
+			PermissionDecisionReason string `json:"permissionDecisionReason"`
+		} `json:"hookSpecificOutput"`
+	}
+	require.NoError(t, json.Unmarshal(out, &o), "not a hook decision: %s", out)
+	assert.Equal(t, "PreToolUse", o.HookSpecificOutput.HookEventName)
+	return o.HookSpecificOutput.PermissionDecision, o.HookSpecificOutput.PermissionDecisionReason
+}
+
 func TestHookEnforcesTDD(t *testing.T) {
 	t.Run("ignores non-Go files", func(t *testing.T) {
 		cmd := exec.Command(ensembleBin(t), "hook")
 		cmd.Stdin = strings.NewReader(hookEvent("Write", "/project/README.md"))
 		out, err := cmd.CombinedOutput()
 		assert.NoError(t, err, "unexpected block: %s", out)
+		assert.Empty(t, out, "passes are silent")
 	})
 
 	t.Run("allows writing test files directly", func(t *testing.T) {
@@ -30,6 +51,7 @@ func TestHookEnforcesTDD(t *testing.T) {
 		cmd.Stdin = strings.NewReader(hookEvent("Write", "/project/internal/foo/foo_test.go"))
 		out, err := cmd.CombinedOutput()
 		assert.NoError(t, err, "unexpected block: %s", out)
+		assert.Empty(t, out, "passes are silent")
 	})
 
 	t.Run("allows writing implementation when test already exists on disk", func(t *testing.T) {
@@ -40,12 +62,25 @@ func TestHookEnforcesTDD(t *testing.T) {
 		cmd.Stdin = strings.NewReader(hookEvent("Write", filepath.Join(dir, "foo.go")))
 		out, err := cmd.CombinedOutput()
 		assert.NoError(t, err, "unexpected block: %s", out)
+		assert.Empty(t, out, "passes are silent")
 	})
 
-	t.Run("blocks Claude Code from writing implementation before a test exists", func(t *testing.T) {
+	t.Run("denies Claude Code writing implementation before a test exists", func(t *testing.T) {
 		dir := t.TempDir()
 		cmd := exec.Command(ensembleBin(t), "hook")
 		cmd.Stdin = strings.NewReader(hookEvent("Write", filepath.Join(dir, "bar.go")))
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, "structured decisions exit 0: %s", out)
+
+		decision, reason := hookDecision(t, out)
+		assert.Equal(t, "deny", decision)
+		assert.Contains(t, reason, "bar_test.go", "the fix reaches the model")
+	})
+
+	t.Run("--exit-code blocks by exiting 2 with the finding", func(t *testing.T) {
+		dir := t.TempDir()
+		cmd := exec.Command(ensembleBin(t), "hook", "--exit-code")
+		cmd.Stdin = strings.NewReader(hookEvent("Write", filepath.Join(dir, "bar.go")))
 		out, _ := cmd.CombinedOutput()
 
 		assert.Equal(t, 2, cmd.ProcessState.ExitCode(), "hook must exit 2 to block Claude Code: %s", out)
@@ -55,12 +90,353 @@ func TestHookEnforcesTDD(t *testing.T) {
 		assert.Equal(t, "block", f["verdict"])
 	})
 
+	t.Run("applies each language's test convention", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "app.test.ts"), nil, 0600))
+
+		cmd := exec.Command(ensembleBin(t), "hook")
+		cmd.Stdin = strings.NewReader(hookEvent("Write", filepath.Join(dir, "app.ts")))
+		out, err := cmd.CombinedOutput()
+		assert.NoError(t, err, "unexpected block: %s", out)
+		assert.Empty(t, out, "passes are silent")
+
+		cmd = exec.Command(ensembleBin(t), "hook")
+		cmd.Stdin = strings.NewReader(hookEvent("Write", filepath.Join(dir, "handler.py")))
+		out, _ = cmd.CombinedOutput()
+		decision, reason := hookDecision(t, out)
+		assert.Equal(t, "deny", decision)
+		assert.Contains(t, reason, "test_handler.py")
+	})
+
 	t.Run("enforces TDD on edits not just new files", func(t *testing.T) {
 		dir := t.TempDir()
 		cmd := exec.Command(ensembleBin(t), "hook")
 		cmd.Stdin = strings.NewReader(hookEvent("Edit", filepath.Join(dir, "bar.go")))
 		out, _ := cmd.CombinedOutput()
 
-		assert.Equal(t, 2, cmd.ProcessState.ExitCode(), "hook must exit 2 for Edit without test: %s", out)
+		decision, _ := hookDecision(t, out)
+		assert.Equal(t, "deny", decision, "hook must deny Edit without test: %s", out)
+	})
+
+	t.Run("denies a Bash command that writes an untested implementation", func(t *testing.T) {
+		dir := t.TempDir()
+		event, _ := json.Marshal(map[string]interface{}{
+			"tool_name":  "Bash",
+			"cwd":        dir,
+			"tool_input": map[string]string{"command": "cat > foo.go <<'EOF'\npackage foo\nEOF"},
+		})
+		cmd := exec.Command(ensembleBin(t), "hook")
+		cmd.Stdin = bytes.NewReader(event)
+		out, _ := cmd.CombinedOutput()
+
+		decision, reason := hookDecision(t, out)
+		assert.Equal(t, "deny", decision, "hook must deny Bash writes without test: %s", out)
+		assert.Contains(t, reason, "foo_test.go")
+	})
+
+	t.Run("stays silent on Bash commands that write nothing", func(t *testing.T) {
+		cmd := exec.Command(ensembleBin(t), "hook")
+		cmd.Stdin = strings.NewReader(`{"tool_name":"Bash","tool_input":{"command":"go test ./... 2>&1 > /dev/null"}}`)
+		out, err := cmd.CombinedOutput()
+		assert.NoError(t, err, "unexpected block: %s", out)
+		assert.Empty(t, out, "passes are silent")
+	})
+
+	t.Run("denies a MultiEdit of an untested implementation", func(t *testing.T) {
+		dir := t.TempDir()
+		event, _ := json.Marshal(map[string]interface{}{
+			"tool_name": "MultiEdit",
+			"tool_input": map[string]interface{}{
+				"file_path": filepath.Join(dir, "bar.go"),
+				"edits":     []map[string]string{{"old_string": "a", "new_string": "b"}},
+			},
+		})
+		cmd := exec.Command(ensembleBin(t), "hook")
+		cmd.Stdin = bytes.NewReader(event)
+		out, _ := cmd.CombinedOutput()
+
+		decision, _ := hookDecision(t, out)
+		assert.Equal(t, "deny", decision, "hook must deny MultiEdit without test: %s", out)
+	})
+
+	t.Run("denies disabling a test with t.Skip", func(t *testing.T) {
+		dir := t.TempDir()
+		event, _ := json.Marshal(map[string]interface{}{
+			"tool_name": "Edit",
+			"tool_input": map[string]string{
+				"file_path":  filepath.Join(dir, "foo_test.go"),
+				"old_string": "func TestFoo(t *testing.T) {\n",
+				"new_string": "func TestFoo(t *testing.T) {\n\tt.Skip(\"later\")\n",
+			},
+		})
+		cmd := exec.Command(ensembleBin(t), "hook")
+		cmd.Stdin = bytes.NewReader(event)
+		out, _ := cmd.CombinedOutput()
+
+		decision, reason := hookDecision(t, out)
+		assert.Equal(t, "deny", decision, "hook must deny a skipped test: %s", out)
+		assert.Contains(t, reason, "t.Skip")
+	})
+
+	t.Run("denies writing a hard-coded secret", func(t *testing.T) {
+		dir := t.TempDir()
+		event, _ := json.Marshal(map[string]interface{}{
+			"tool_name":  "Write",
+			"tool_input": map[string]string{"file_path": filepath.Join(dir, "deploy.yaml"), "content": "aws_key: AKIA" + "IOSFODNN7EXAMPLE\n"},
+		})
+		cmd := exec.Command(ensembleBin(t), "hook")
+		cmd.Stdin = bytes.NewReader(event)
+		out, _ := cmd.CombinedOutput()
+
+		decision, reason := hookDecision(t, out)
+		assert.Equal(t, "deny", decision, "hook must deny secrets: %s", out)
+		assert.Contains(t, reason, "ensemble security")
+	})
+
+	t.Run("asks before deleting an assertion", func(t *testing.T) {
+		dir := t.TempDir()
+		event, _ := json.Marshal(map[string]interface{}{
+			"tool_name": "Edit",
+			"tool_input": map[string]string{
+				"file_path":  filepath.Join(dir, "foo_test.go"),
+				"old_string": "\tassert.Equal(t, 1, Foo())\n}\n",
+				"new_string": "}\n",
+			},
+		})
+		cmd := exec.Command(ensembleBin(t), "hook")
+		cmd.Stdin = bytes.NewReader(event)
+		out, _ := cmd.CombinedOutput()
+
+		decision, _ := hookDecision(t, out)
+		assert.Equal(t, "ask", decision, "deleted assertions need approval: %s", out)
 	})
+
+	t.Run("with verify_red, denies implementation whose new test already passes", func(t *testing.T) {
+		dir := gitProject(t)
+		writeRepoFile(t, dir, ".ensemble.yaml", "tests:\n  verify_red: true\n")
+		writeRepoFile(t, dir, "foo/foo_test.go", "package foo\n\nimport \"testing\"\n\nfunc TestAdd(t *testing.T) {\n\tif Add(1, 2) != 3 {\n\t\tt.Fatal(\"sum\")\n\t}\n}\n")
+		hookIn := func() (string, string) {
+			cmd := exec.Command(ensembleBinAbs(t), "hook")
+			cmd.Dir = dir
+			cmd.Stdin = strings.NewReader(hookEvent("Edit", filepath.Join(dir, "foo", "foo.go")))
+			out, _ := cmd.CombinedOutput()
+			return hookDecision(t, out)
+		}
+
+		decision, reason := hookIn()
+		assert.Equal(t, "deny", decision)
+		assert.Contains(t, reason, "TestAdd")
+
+		writeRepoFile(t, dir, "foo/foo_test.go", "package foo\n\nimport \"testing\"\n\nfunc TestSub(t *testing.T) {\n\tif Sub(3, 2) != 1 {\n\t\tt.Fatal(\"diff\")\n\t}\n}\n")
+		decision, _ = hookIn()
+		assert.Empty(t, decision, "a failing test lets the implementation through")
+	})
+}
+
+func TestHookPostPhase(t *testing.T) {
+	postHook := func(t *testing.T, dir, path string) []byte {
+		t.Helper()
+		cmd := exec.Command(ensembleBinAbs(t), "hook", "--phase", "post")
+		cmd.Dir = dir
+		cmd.Stdin = strings.NewReader(hookEvent("Edit", path))
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, "post hooks exit 0: %s", out)
+		return out
+	}
+
+	t.Run("hands a failing test back to the model", func(t *testing.T) {
+		dir := gitProject(t)
+		writeRepoFile(t, dir, "foo/foo_test.go", "package foo\n\nimport \"testing\"\n\nfunc TestAdd(t *testing.T) {\n\tif Add(1, 2) != 4 {\n\t\tt.Fatal(\"wrong sum\")\n\t}\n}\n")
+
+		out := postHook(t, dir, filepath.Join(dir, "foo", "foo.go"))
+		var o struct {
+			Decision string `json:"decision"`
+			Reason   string `json:"reason"`
+		}
+		require.NoError(t, json.Unmarshal(out, &o), "not a hook answer: %s", out)
+		assert.Equal(t, "block", o.Decision)
+		assert.Contains(t, o.Reason, "wrong sum")
+	})
+
+	t.Run("stays silent when the package's tests pass", func(t *testing.T) {
+		dir := gitProject(t)
+		assert.Empty(t, postHook(t, dir, filepath.Join(dir, "foo", "foo.go")))
+	})
+
+	t.Run("stays silent for files outside a Go package", func(t *testing.T) {
+		dir := gitProject(t)
+		assert.Empty(t, postHook(t, dir, filepath.Join(dir, "README.md")))
+	})
+
+	t.Run("rejects an unknown phase", func(t *testing.T) {
+		cmd := exec.Command(ensembleBin(t), "hook", "--phase", "later")
+		cmd.Stdin = strings.NewReader(hookEvent("Edit", "foo.go"))
+		out, err := cmd.CombinedOutput()
+		assert.Error(t, err)
+		assert.Contains(t, string(out), "unknown phase")
+	})
+}
+
+func TestHookStopPhase(t *testing.T) {
+	stopHook := func(t *testing.T, dir string) []byte {
+		t.Helper()
+		cmd := exec.Command(ensembleBinAbs(t), "hook", "--phase", "stop")
+		cmd.Dir = dir
+		cmd.Env = envWithout(os.Environ(), "ANTHROPIC_API_KEY")
+		cmd.Stdin = strings.NewReader(`{"hook_event_name":"Stop","stop_hook_active":false}`)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, "stop hooks exit 0: %s", out)
+		return out
+	}
+	continueReason := func(t *testing.T, out []byte) string {
+		t.Helper()
+		var o struct {
+			Decision string `json:"decision"`
+			Reason   string `json:"reason"`
+		}
+		require.NoError(t, json.Unmarshal(out, &o), "not a hook answer: %s", out)
+		assert.Equal(t, "block", o.Decision)
+		return o.Reason
+	}
+
+	t.Run("keeps the model working on an untested change", func(t *testing.T) {
+		dir := gitProject(t)
+		writeRepoFile(t, dir, "bar/bar.go", "package bar\n")
+		reason := continueReason(t, stopHook(t, dir))
+		assert.Contains(t, reason, "bar/bar.go")
+	})
+
+	t.Run("keeps the model working on a failing test", func(t *testing.T) {
+		dir := gitProject(t)
+		writeRepoFile(t, dir, "foo/foo_test.go", "package foo\n\nimport \"testing\"\n\nfunc TestAdd(t *testing.T) {\n\tif Add(1, 2) != 4 {\n\t\tt.Fatal(\"wrong sum\")\n\t}\n}\n")
+		reason := continueReason(t, stopHook(t, dir))
+		assert.Contains(t, reason, "wrong sum")
+	})
+
+	t.Run("lets the model stop when the changes are tested and green", func(t *testing.T) {
+		dir := gitProject(t)
+		writeRepoFile(t, dir, "foo/foo.go", "package foo\n\n// Add sums a and b.\nfunc Add(a, b int) int { return a + b }\n")
+		assert.Empty(t, stopHook(t, dir))
+	})
+
+	t.Run("lets the model stop with nothing uncommitted", func(t *testing.T) {
+		assert.Empty(t, stopHook(t, gitProject(t)))
+	})
+
+	t.Run("sends the model back once for a model objection, then lets it stop", func(t *testing.T) {
+		dir := gitProject(t)
+		writeRepoFile(t, dir, "foo/foo.go", "package foo\n\n// Add sums a and b.\nfunc Add(a, b int) int { return a + b }\n")
+		srv := stubMessagesAPI(t, `"[{\"verdict\":\"block\",\"severity\":\"high\",\"finding\":\"stub objection\",\"file\":\"foo/foo.go\",\"fix\":\"\"}]"`)
+		stop := func(active bool) []byte {
+			cmd := exec.Command(ensembleBinAbs(t), "hook", "--phase", "stop")
+			cmd.Dir = dir
+			cmd.Env = append(envWithout(os.Environ(), "ANTHROPIC_API_KEY"), "ANTHROPIC_API_KEY=test-key", "ENSEMBLE_RUNNER=api", "ANTHROPIC_BASE_URL="+srv.URL)
+			cmd.Stdin = strings.NewReader(fmt.Sprintf(`{"hook_event_name":"Stop","stop_hook_active":%t}`, active))
+			out, err := cmd.CombinedOutput()
+			require.NoError(t, err, "stop hooks exit 0: %s", out)
+			return out
+		}
+		assert.Contains(t, continueReason(t, stop(false)), "stub objection")
+		assert.Empty(t, stop(true))
+	})
+
+	t.Run("still holds the model back for a failing test once sent back", func(t *testing.T) {
+		dir := gitProject(t)
+		writeRepoFile(t, dir, "foo/foo_test.go", "package foo\n\nimport \"testing\"\n\nfunc TestAdd(t *testing.T) {\n\tif Add(1, 2) != 4 {\n\t\tt.Fatal(\"wrong sum\")\n\t}\n}\n")
+		cmd := exec.Command(ensembleBinAbs(t), "hook", "--phase", "stop")
+		cmd.Dir = dir
+		cmd.Env = envWithout(os.Environ(), "ANTHROPIC_API_KEY")
+		cmd.Stdin = strings.NewReader(`{"hook_event_name":"Stop","stop_hook_active":true}`)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, "%s", out)
+		assert.Contains(t, continueReason(t, out), "wrong sum")
+	})
+}
+
+func TestHookPromptPhase(t *testing.T) {
+	promptHook := func(t *testing.T, dir string) string {
+		t.Helper()
+		cmd := exec.Command(ensembleBinAbs(t), "hook", "--phase", "prompt")
+		cmd.Dir = dir
+		cmd.Stdin = strings.NewReader(`{"hook_event_name":"UserPromptSubmit","prompt":"add a Sub function"}`)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, "prompt hooks exit 0: %s", out)
+		var o struct {
+			HookSpecificOutput struct {
+				HookEventName     string `json:"hookEventName"`
+				AdditionalContext string `json:"additionalContext"`
+			} `json:"hookSpecificOutput"`
+		}
+		require.NoError(t, json.Unmarshal(out, &o), "not a hook answer: %s", out)
+		assert.Equal(t, "UserPromptSubmit", o.HookSpecificOutput.HookEventName)
+		return o.HookSpecificOutput.AdditionalContext
+	}
+
+	t.Run("states the phase and the quality gates", func(t *testing.T) {
+		dir := gitProject(t)
+		writeRepoFile(t, dir, "foo/foo_test.go", "package foo\n\nfunc TestSub() {}\n")
+		text := promptHook(t, dir)
+		assert.Contains(t, text, "TDD phase: GREEN")
+		assert.Contains(t, text, "Unit tests (make test)")
+		assert.NotContains(t, text, "last cycle")
+	})
+
+	t.Run("carries the blocking findings of the last cycle", func(t *testing.T) {
+		dir := gitProject(t)
+		writeRepoFile(t, dir, "bar/bar.go", "package bar\n")
+		cycle := gitCycle(t, dir, "--worktree")
+		_, _ = cycle.CombinedOutput()
+		require.Equal(t, 1, cycle.ProcessState.ExitCode())
+
+		text := promptHook(t, dir)
+		assert.Contains(t, text, "TDD phase: RED")
+		assert.Contains(t, text, "Blocking findings from the last cycle")
+		assert.Contains(t, text, "bar/bar.go")
+
+		status := exec.Command("git", "status", "--porcelain")
+		status.Dir = dir
+		out, err := status.Output()
+		require.NoError(t, err)
+		assert.NotContains(t, string(out), ".ensemble", "ensemble's state never shows up as a change")
+	})
+}
+
+func TestHookTracksSession(t *testing.T) {
+	runHook := func(t *testing.T, dir, phase, payload string) []byte {
+		t.Helper()
+		cmd := exec.Command(ensembleBinAbs(t), "hook", "--phase", phase)
+		cmd.Dir = dir
+		cmd.Env = envWithout(os.Environ(), "ANTHROPIC_API_KEY")
+		cmd.Stdin = strings.NewReader(payload)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, "hooks exit 0: %s", out)
+		return out
+	}
+	edit := func(path string) string {
+		return fmt.Sprintf(`{"session_id":"s1","tool_name":"Edit","tool_input":{"file_path":%q}}`, path)
+	}
+	prompt := `{"session_id":"s1","hook_event_name":"UserPromptSubmit","prompt":"tidy up"}`
+
+	dir := gitProject(t)
+	impl := filepath.Join(dir, "foo", "foo.go")
+
+	runHook(t, dir, "post", edit(impl))
+	assert.Contains(t, string(runHook(t, dir, "prompt", prompt)), "TDD phase: REFACTOR", "passing tests after implementation move to REFACTOR")
+
+	writeRepoFile(t, dir, "foo/foo.go", "package foo\n\nfunc Add(a, b int) int { return a - b }\n")
+	writeRepoFile(t, dir, "foo/foo_test.go", "package foo\n\nimport \"testing\"\n\nfunc TestAdd(t *testing.T) {\n\tif Add(1, 2) != 3 {\n\t\tt.Fatal(\"wrong sum\")\n\t}\n}\n")
+	gitIn(t, dir, "add", "foo/foo_test.go")
+	runHook(t, dir, "post", edit(impl))
+
+	decision, reason := hookDecision(t, runHook(t, dir, "pre", edit(impl)))
+	assert.Equal(t, "deny", decision, "no more implementation while refactoring on red tests")
+	assert.Contains(t, reason, "REFACTOR")
+
+	gitIn(t, dir, "checkout", "--", "foo/foo.go")
+	runHook(t, dir, "post", `{"session_id":"s1","tool_name":"Bash","tool_input":{"command":"git checkout -- foo/foo.go"}}`)
+	decision, _ = hookDecision(t, runHook(t, dir, "pre", edit(impl)))
+	assert.Empty(t, decision, "undoing the step turns the tests green again")
+
+	decision, _ = hookDecision(t, runHook(t, dir, "pre", hookEvent("Edit", impl)))
+	assert.Empty(t, decision, "payloads without a session are not tracked")
 }
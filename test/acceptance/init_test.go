@@ -4,6 +4,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -93,3 +94,132 @@ func TestEnsembleInit(t *testing.T) {
 		assert.Contains(t, combined, "not found on PATH")
 	})
 }
+
+func TestEnsembleUninstall(t *testing.T) {
+	run := func(t *testing.T, dir string, args ...string) string {
+		t.Helper()
+		cmd := exec.Command(ensembleBinAbs(t), args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, "unexpected error: %s", out)
+		return string(out)
+	}
+
+	for _, args := range [][]string{{"uninstall"}, {"init", "--remove"}} {
+		t.Run(strings.Join(args, " ")+" backs init out", func(t *testing.T) {
+			dir := t.TempDir()
+			claudeDir := filepath.Join(dir, ".claude")
+			require.NoError(t, os.MkdirAll(claudeDir, 0755))
+			require.NoError(t, os.WriteFile(filepath.Join(claudeDir, "settings.json"), []byte(`{"someOtherKey": true}`), 0644))
+			run(t, dir, "init")
+
+			out := run(t, dir, args...)
+			assert.Contains(t, out, "Removed")
+			assert.Contains(t, out, "PreToolUse: ensemble hook")
+			data, err := os.ReadFile(filepath.Join(claudeDir, "settings.json"))
+			require.NoError(t, err)
+			assert.Contains(t, string(data), "someOtherKey")
+			assert.NotContains(t, string(data), "ensemble")
+			assert.NotContains(t, string(data), "hooks")
+		})
+	}
+
+	t.Run("reports nothing to remove", func(t *testing.T) {
+		assert.Contains(t, run(t, t.TempDir(), "uninstall"), "nothing changed")
+	})
+}
+
+func TestEnsembleInitSafety(t *testing.T) {
+	settingsDir := func(t *testing.T, content string) (dir, path string) {
+		t.Helper()
+		dir = t.TempDir()
+		path = filepath.Join(dir, ".claude", "settings.json")
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		return dir, path
+	}
+
+	t.Run("aborts on invalid JSON and points at it", func(t *testing.T) {
+		original := "{\n  \"model\": \"opus\"\n  \"env\": {}\n}\n"
+		dir, path := settingsDir(t, original)
+		cmd := exec.Command(ensembleBinAbs(t), "init")
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		assert.Error(t, err)
+		assert.Contains(t, string(out), "line 3, column 3")
+		assert.Contains(t, string(out), `> 3 |   "env": {}`)
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, original, string(data), "settings are left untouched")
+	})
+
+	t.Run("--dry-run prints the change without writing it", func(t *testing.T) {
+		original := "{\n  \"model\": \"opus\"\n}\n"
+		dir, path := settingsDir(t, original)
+		cmd := exec.Command(ensembleBinAbs(t), "init", "--dry-run")
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, "unexpected error: %s", out)
+		assert.Contains(t, string(out), "--- a/.claude/settings.json\n+++ b/.claude/settings.json\n")
+		assert.Contains(t, string(out), "-  \"model\": \"opus\"\n+  \"model\": \"opus\",\n+  \"hooks\": {")
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, original, string(data))
+	})
+
+	t.Run("uninstall --dry-run prints the removal", func(t *testing.T) {
+		dir, path := settingsDir(t, `{"hooks": {"Stop": [{"hooks": [{"type": "command", "command": "ensemble hook --phase stop"}]}]}}`)
+		cmd := exec.Command(ensembleBinAbs(t), "uninstall", "--dry-run")
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, "unexpected error: %s", out)
+		assert.Contains(t, string(out), "+{}")
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "ensemble hook")
+	})
+}
+
+func TestEnsembleInitScopes(t *testing.T) {
+	run := func(t *testing.T, dir, home string, args ...string) string {
+		t.Helper()
+		cmd := exec.Command(ensembleBinAbs(t), args...)
+		cmd.Dir = dir
+		cmd.Env = append(envWithout(os.Environ(), "HOME"), "HOME="+home)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, "unexpected error: %s", out)
+		return string(out)
+	}
+
+	t.Run("local scope stays out of the committed settings", func(t *testing.T) {
+		dir, home := t.TempDir(), t.TempDir()
+		out := run(t, dir, home, "init", "--scope", "local")
+		assert.Contains(t, out, "Initialised .claude/settings.local.json")
+		assert.FileExists(t, filepath.Join(dir, ".claude", "settings.local.json"))
+		assert.NoFileExists(t, filepath.Join(dir, ".claude", "settings.json"))
+	})
+
+	t.Run("user scope covers every project without registering twice", func(t *testing.T) {
+		dir, home := t.TempDir(), t.TempDir()
+		run(t, dir, home, "init", "--scope", "user")
+		data, err := os.ReadFile(filepath.Join(home, ".claude", "settings.json"))
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "ensemble hook")
+
+		out := run(t, dir, home, "init")
+		assert.Contains(t, out, "PreToolUse hook already registered in ~/.claude/settings.json")
+		assert.Contains(t, out, "nothing changed")
+		assert.NoFileExists(t, filepath.Join(dir, ".claude", "settings.json"))
+
+		out = run(t, dir, home, "uninstall", "--scope", "user")
+		assert.Contains(t, out, "Removed from ~/.claude/settings.json")
+	})
+
+	t.Run("rejects an unknown scope", func(t *testing.T) {
+		cmd := exec.Command(ensembleBinAbs(t), "init", "--scope", "global")
+		cmd.Dir = t.TempDir()
+		out, err := cmd.CombinedOutput()
+		assert.Error(t, err)
+		assert.Contains(t, string(out), "unknown scope")
+	})
+}
@@ -1,14 +1,65 @@
 package acceptance
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
+// stubOrchestrator streams the replies in turn to the session's requests
+// and finds nothing wrong in the model reviews.
+func stubOrchestrator(t *testing.T, replies ...string) *httptest.Server {
+	t.Helper()
+	var mu sync.Mutex
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Stream bool `json:"stream"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if !req.Stream {
+			w.Header().Set("content-type", "application/json")
+			_, _ = w.Write([]byte(`{"content":[{"type":"text","text":"[]"}]}`))
+			return
+		}
+		mu.Lock()
+		reply := "(no reply left)"
+		if len(replies) > 0 {
+			reply, replies = replies[0], replies[1:]
+		}
+		mu.Unlock()
+		delta, _ := json.Marshal(map[string]interface{}{"type": "content_block_delta", "delta": map[string]string{"type": "text_delta", "text": reply}})
+		w.Header().Set("content-type", "text/event-stream")
+		_, _ = w.Write([]byte("data: " + string(delta) + "\n\ndata: {\"type\":\"message_stop\"}\n\n"))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func sessionIn(t *testing.T, dir, baseURL, input string) string {
+	t.Helper()
+	cmd := exec.Command(ensembleBinAbs(t))
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(input)
+	cmd.Env = append(envWithout(os.Environ(), "ANTHROPIC_API_KEY"),
+		"ANTHROPIC_API_KEY=test-key",
+		"ENSEMBLE_RUNNER=api",
+		"ANTHROPIC_BASE_URL="+baseURL,
+	)
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, "session failed: %s", out)
+	return string(out)
+}
+
 func TestInteractiveSession(t *testing.T) {
 	t.Run("opens interactive prompt when run with no arguments", func(t *testing.T) {
 		cmd := exec.Command(ensembleBin(t))
@@ -18,4 +69,94 @@ func TestInteractiveSession(t *testing.T) {
 		assert.Contains(t, string(out), "ensemble> ")
 		assert.NotContains(t, string(out), "Available Commands:")
 	})
+
+	t.Run("streams the orchestrator's reply", func(t *testing.T) {
+		srv := stubOrchestrator(t, "Hello from the orchestrator.")
+		out := sessionIn(t, gitProject(t), srv.URL, "hi\n")
+		assert.Contains(t, out, "ensemble> Hello from the orchestrator.\n")
+	})
+
+	t.Run("blocks untested code until the user decides", func(t *testing.T) {
+		dir := gitProject(t)
+		impl := "Adding bar.\n<file path=\"bar/bar.go\">\npackage bar\n</file>\n"
+		srv := stubOrchestrator(t, impl, impl)
+
+		out := sessionIn(t, dir, srv.URL, "add bar\nn\nadd bar anyway\ny\n")
+		assert.Contains(t, out, "[proposes bar/bar.go]")
+		assert.Contains(t, out, "testing-quality")
+		assert.Contains(t, out, "Apply anyway? [y/N] Not applied.")
+		assert.Contains(t, out, "Applied: bar/bar.go")
+		_, err := os.Stat(filepath.Join(dir, "bar", "bar.go"))
+		assert.NoError(t, err)
+	})
+
+	t.Run("applies a tested change without asking", func(t *testing.T) {
+		dir := gitProject(t)
+		srv := stubOrchestrator(t, "<file path=\"bar/bar_test.go\">\npackage bar\n</file>\n<file path=\"bar/bar.go\">\npackage bar\n</file>\n")
+		out := sessionIn(t, dir, srv.URL, "add bar with a test\n")
+		assert.NotContains(t, out, "Apply anyway")
+		assert.Contains(t, out, "Applied: bar/bar_test.go, bar/bar.go")
+	})
+
+	t.Run("answers slash commands without a model", func(t *testing.T) {
+		cmd := exec.Command(ensembleBinAbs(t))
+		cmd.Dir = gitProject(t)
+		cmd.Stdin = strings.NewReader("/help\n/agents\n/cycle\n/quit\nnot read\n")
+		cmd.Env = envWithout(os.Environ(), "ANTHROPIC_API_KEY")
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, "%s", out)
+		assert.Contains(t, string(out), "/override <id> <reason>")
+		assert.Contains(t, string(out), "testing-quality")
+		assert.Contains(t, string(out), "No uncommitted changes.")
+		assert.NotContains(t, string(out), "error:")
+	})
+
+	t.Run("records the session to resume or inspect it", func(t *testing.T) {
+		dir := gitProject(t)
+		impl := "Adding bar.\n<file path=\"bar/bar.go\">\npackage bar\n</file>\n"
+		srv := stubOrchestrator(t, impl, "You said to add bar.")
+
+		out := sessionIn(t, dir, srv.URL, "add bar\nn\n")
+		m := regexp.MustCompile(`Session (\S+) saved; ensemble --resume (\S+) continues it\.`).FindStringSubmatch(out)
+		require.NotNil(t, m, out)
+		id := m[1]
+		assert.FileExists(t, filepath.Join(dir, ".ensemble", "sessions", id+".jsonl"))
+
+		list := exec.Command(ensembleBinAbs(t), "sessions", "list")
+		list.Dir = dir
+		listed, err := list.CombinedOutput()
+		require.NoError(t, err, "%s", listed)
+		assert.Regexp(t, id+` .* 1 +0 +add bar`, string(listed))
+
+		show := exec.Command(ensembleBinAbs(t), "sessions", "show", id)
+		show.Dir = dir
+		shown, err := show.CombinedOutput()
+		require.NoError(t, err, "%s", shown)
+		assert.Contains(t, string(shown), "user: add bar")
+		assert.Contains(t, string(shown), "decision: declined bar/bar.go")
+
+		resumed := exec.Command(ensembleBinAbs(t), "--resume", id)
+		resumed.Dir = dir
+		resumed.Stdin = strings.NewReader("/findings\nwhat did I ask?\n/override 1 spike\n")
+		resumed.Env = append(envWithout(os.Environ(), "ANTHROPIC_API_KEY"), "ANTHROPIC_API_KEY=test-key", "ENSEMBLE_RUNNER=api", "ANTHROPIC_BASE_URL="+srv.URL)
+		again, err := resumed.CombinedOutput()
+		require.NoError(t, err, "%s", again)
+		assert.Contains(t, string(again), "Resuming session "+id)
+		assert.Contains(t, string(again), "testing-quality", "the outstanding findings carry over")
+		assert.Contains(t, string(again), "You said to add bar.")
+
+		show = exec.Command(ensembleBinAbs(t), "sessions", "show", id)
+		show.Dir = dir
+		shown, err = show.CombinedOutput()
+		require.NoError(t, err, "%s", shown)
+		assert.Contains(t, string(shown), "decision: overridden #1 because: spike")
+	})
+
+	t.Run("refuses to resume an unknown session", func(t *testing.T) {
+		cmd := exec.Command(ensembleBinAbs(t), "--resume", "nope")
+		cmd.Dir = gitProject(t)
+		out, err := cmd.CombinedOutput()
+		assert.Error(t, err)
+		assert.Contains(t, string(out), `no session "nope"`)
+	})
 }